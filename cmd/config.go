@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"cinexus/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "检查和查看配置",
+	// 只读取配置，不校验、不初始化日志，校验结果由子命令输出
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		godotenv.Load()
+		return config.Init(configFile)
+	},
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "校验配置，列出所有错误",
+	Run: func(cmd *cobra.Command, args []string) {
+		source := config.File()
+		if source == "" {
+			source = "默认配置和环境变量"
+		}
+
+		if err := config.Get().Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, source)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(source)
+		fmt.Println("配置校验通过")
+	},
+}
+
+var (
+	printRedacted bool
+	printFormat   string
+)
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "输出合并默认值、配置文件和环境变量后的最终配置",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := config.Get().Map(printRedacted)
+
+		var (
+			out []byte
+			err error
+		)
+		switch printFormat {
+		case "toml":
+			out, err = toml.Marshal(m)
+		case "yaml":
+			out, err = yaml.Marshal(m)
+		case "json":
+			out, err = json.MarshalIndent(m, "", "  ")
+			out = append(out, '\n')
+		default:
+			return fmt.Errorf("不支持的格式: %s", printFormat)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+func init() {
+	configPrintCmd.Flags().BoolVar(&printRedacted, "redacted", false, "隐藏密码、令牌等敏感配置")
+	configPrintCmd.Flags().StringVar(&printFormat, "format", "toml", "输出格式：toml、yaml、json")
+
+	configCmd.AddCommand(configCheckCmd, configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"cinexus/internal/database"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "数据库迁移",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "执行所有未执行的迁移，并同步模型中新增的表和字段",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := database.Init(); err != nil {
+			return err
+		}
+		defer database.Close()
+
+		applied, err := database.Migrate()
+		for _, m := range applied {
+			fmt.Printf("已执行 %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
+		return nil
+	},
+}
+
+var migrateSteps int
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "回滚最近执行的迁移",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if migrateSteps < 1 {
+			return errors.New("--steps 至少为 1")
+		}
+		if err := database.Init(); err != nil {
+			return err
+		}
+		defer database.Close()
+
+		rolledBack, err := database.Rollback(migrateSteps)
+		for _, m := range rolledBack {
+			fmt.Printf("已回滚 %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+		return nil
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "列出所有迁移版本及执行状态",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := database.Init(); err != nil {
+			return err
+		}
+		defer database.Close()
+
+		statuses, err := database.MigrationStatuses()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tSTATUS")
+		for _, s := range statuses {
+			appliedAt, state := "-", "未执行"
+			if s.AppliedAt != nil {
+				appliedAt, state = s.AppliedAt.Local().Format("2006-01-02 15:04:05"), "已执行"
+			}
+			if s.Unknown {
+				state = "当前程序中不存在"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, state)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		missing, err := database.SchemaDrift()
+		if err != nil {
+			return err
+		}
+		for _, m := range missing {
+			fmt.Printf("未同步的%s\n", m)
+		}
+		return nil
+	},
+}
+
+func init() {
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "回滚的迁移数量")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
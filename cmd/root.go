@@ -2,64 +2,177 @@ package cmd
 
 import (
 	"cinexus/config"
+	"cinexus/internal/cd2"
 	"cinexus/internal/database"
 	"cinexus/internal/middleware"
-	"cinexus/internal/model"
 	"cinexus/internal/router"
+	"cinexus/internal/service"
 	"cinexus/pkg/logger"
+	"cinexus/pkg/tracing"
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
 )
 
+// configFile 通过 --config 指定的配置文件
+var configFile string
+
 var rootCmd = &cobra.Command{
 	Use:   "cinexus",
 	Short: "cinexus",
 	Long:  `Film Fusion`,
-	Run: func(cmd *cobra.Command, args []string) {
+	// 出错时只在 Execute 中输出错误信息，不输出用法
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// .env 中的变量可以覆盖配置，需在读取配置前加载
 		godotenv.Load()
 
+		// 初始化配置
+		if err := config.Init(configFile); err != nil {
+			return fmt.Errorf("配置初始化失败: %w", err)
+		}
+		if err := config.Get().Validate(); err != nil {
+			return err
+		}
+
+		// 初始化日志
+		if err := logger.Init(); err != nil {
+			return fmt.Errorf("日志初始化失败: %w", err)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if file := config.File(); file != "" {
+			logger.Info("已加载配置文件", zap.String("file", file))
+		} else {
+			logger.Info("未找到配置文件，使用默认配置和环境变量")
+		}
+
+		// 未配置 jwt.secret 时使用数据目录中的密钥，首次启动时生成
+		if err := config.EnsureJWTSecret(); err != nil {
+			logger.Error("JWT密钥初始化失败", zap.Error(err))
+			return
+		}
+
 		// 设置运行模式
-		if config.Conf.Server.RunMode == "debug" {
+		if config.Get().Server.RunMode == "debug" {
 			gin.SetMode(gin.DebugMode)
 		} else {
 			gin.SetMode(gin.ReleaseMode)
 		}
 
+		// 初始化链路追踪，需在数据库和CD2客户端之前完成
+		shutdownTracing, err := tracing.Init(context.Background())
+		if err != nil {
+			logger.Error("链路追踪初始化失败", zap.Error(err))
+			return
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				logger.Warn("上报剩余链路数据失败", zap.Error(err))
+			}
+		}()
+
 		// 初始化数据库
 		if err := initDB(); err != nil {
 			logger.Error("数据库初始化失败", zap.Error(err))
 			return
 		}
 
+		// 应用数据库中的配置项，需在创建CD2客户端等依赖配置的组件之前完成
+		if err := service.LoadSettings(); err != nil {
+			logger.Error("加载数据库中的配置项失败", zap.Error(err))
+		}
+
+		// 初始化CD2客户端
+		if err := cd2.Init(service.AuditUnaryInterceptor); err != nil {
+			logger.Error("CD2客户端初始化失败", zap.Error(err))
+			return
+		}
+		defer cd2.Close()
+
+		// 启动后台任务：CD2推送监听、挂载点调整、迁移任务、备份策略、文件索引、回收站清理、挂载点健康检查
+		bgCtx, stopBackground := context.WithCancel(context.Background())
+		defer stopBackground()
+		service.StartMountReconciler(bgCtx)
+		service.StartMigrations(bgCtx)
+		service.StartDedupe(bgCtx)
+		service.StartFileIndex(bgCtx)
+		service.StartScheduler(bgCtx)
+		cd2.StartPushListener(bgCtx)
+
 		// 创建gin引擎
 		r := gin.New()
-		r.Use(middleware.Logger(), middleware.Recovery())
+		if tracing.Enabled() {
+			// 放在最外层，请求ID和请求日志中才能关联到span
+			r.Use(otelgin.Middleware(tracing.ServiceName(), otelgin.WithFilter(middleware.TraceFilter)))
+		}
+		r.Use(middleware.RequestID(), middleware.Logger(service.SensitiveRequests...), middleware.Recovery())
 
 		// 注册路由
 		router.RegisterRoutes(r)
 
 		// 创建HTTP服务器
-		srv := &http.Server{
-			Addr:    ":" + config.Conf.Server.Port,
-			Handler: r,
+		server, err := listen(r, config.Get().Server.Port)
+		if err != nil {
+			logger.Fatal("监听失败", zap.Error(err))
 		}
+		var serverMu sync.Mutex
 
-		// 启动服务器
-		go func() {
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				logger.Fatal("监听失败", zap.Error(err))
+		// 修改端口后在新端口上监听，成功后关闭原来的监听
+		config.OnChange("server", func(old, new *config.Config) {
+			if new.Server.RunMode != old.Server.RunMode {
+				logger.Warn("运行模式修改后需要重启才能生效")
+			}
+			if new.Server.Port == old.Server.Port {
+				return
 			}
-		}()
+
+			next, err := listen(r, new.Server.Port)
+			if err != nil {
+				logger.Error("切换监听端口失败，继续使用原端口", zap.String("port", new.Server.Port), zap.Error(err))
+				return
+			}
+			serverMu.Lock()
+			prev := server
+			server = next
+			serverMu.Unlock()
+
+			logger.Info("HTTP服务已切换监听端口", zap.String("port", new.Server.Port))
+			go shutdown(prev)
+		})
+
+		// 监控配置文件变化，校验通过后生效
+		config.Watch(func(changed []string, err error) {
+			if err != nil {
+				logger.Error("配置文件有误，继续使用当前配置", zap.Error(err))
+				return
+			}
+			if len(changed) == 0 {
+				return
+			}
+			logger.Info("配置已重新加载", zap.Strings("sections", changed))
+			if sections := config.RestartRequired(changed); len(sections) > 0 {
+				logger.Warn("以下配置修改后需要重启才能生效", zap.Strings("sections", sections))
+			}
+		})
 
 		// 等待中断信号优雅地关闭服务器
 		quit := make(chan os.Signal, 1)
@@ -67,37 +180,90 @@ var rootCmd = &cobra.Command{
 		<-quit
 		logger.Info("关闭服务器...")
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := srv.Shutdown(ctx); err != nil {
-			logger.Fatal("服务器强制关闭", zap.Error(err))
+		serverMu.Lock()
+		current := server
+		serverMu.Unlock()
+		if err := shutdown(current); err != nil {
+			// 不退出进程，继续关闭CD2连接和后台任务并上报剩余的span
+			logger.Error("服务器强制关闭", zap.Error(err))
 		}
 
 		logger.Info("服务器已退出")
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "配置文件路径，支持 toml、yaml、json，默认查找 config/config.* 和 config.*")
+}
+
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	logger.Sync()
+	if err != nil {
 		log.Println("Run Server Error: ", err)
 		os.Exit(1)
 	}
 }
 
+// listen 在指定端口上监听并在后台处理请求，端口被占用等错误直接返回
+func listen(handler http.Handler, port string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+
+	// 请求上下文派生自 baseCtx，关闭服务器时取消，日志推送、扫码登录等长连接随之结束，不必等到超时
+	baseCtx, cancel := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	srv.RegisterOnShutdown(cancel)
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTP服务异常退出", zap.String("port", port), zap.Error(err))
+		}
+	}()
+	return srv, nil
+}
+
+// shutdown 等待正在处理的请求完成后关闭服务器，最多等待5秒
+func shutdown(srv *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
 func initDB() error {
 	// 初始化数据库连接
 	if err := database.Init(); err != nil {
 		return err
 	}
 
-	// 自动迁移数据库表结构
-	err := database.DB.AutoMigrate(
-		&model.User{},
-		// 添加其他模型...
-	)
-
-	if err != nil {
-		return err
+	// 执行数据库迁移，关闭自动迁移时只检查是否有未执行的迁移和模型中尚未同步的表和字段
+	if config.Get().Database.AutoMigrate {
+		applied, err := database.Migrate()
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			logger.Info("已执行数据库迁移", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		}
+	} else {
+		pending, err := database.PendingMigrations()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("有 %d 个数据库迁移未执行，请先执行 cinexus migrate up", len(pending))
+		}
+		missing, err := database.SchemaDrift()
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("数据库缺少模型中的%s，请先执行 cinexus migrate up", strings.Join(missing, "、"))
+		}
 	}
 
 	logger.Info("数据库初始化成功")
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/spf13/viper"
 )
@@ -14,6 +19,17 @@ type Config struct {
 	Database DatabaseConfig `mapstructure:"database"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	Log      LogConfig      `mapstructure:"log"`
+	Register RegisterConfig `mapstructure:"register"`
+	SMTP     SMTPConfig     `mapstructure:"smtp"`
+	CD2      []CD2Config    `mapstructure:"cd2"`
+	Watchdog WatchdogConfig `mapstructure:"watchdog"`
+	Notify   NotifyConfig   `mapstructure:"notify"`
+	Backup   BackupConfig   `mapstructure:"backup"`
+	Quota    QuotaConfig    `mapstructure:"quota"`
+	Index    IndexConfig    `mapstructure:"index"`
+	Trash    TrashConfig    `mapstructure:"trash"`
+	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Tracing  TracingConfig  `mapstructure:"tracing"`
 }
 
 // ServerConfig 服务器配置
@@ -22,6 +38,7 @@ type ServerConfig struct {
 	Port         string `mapstructure:"port"`
 	ReadTimeout  int    `mapstructure:"read_timeout"`
 	WriteTimeout int    `mapstructure:"write_timeout"`
+	DataDir      string `mapstructure:"data_dir"` // 运行数据目录，保存自动生成的JWT密钥等文件
 }
 
 // DatabaseConfig 数据库配置
@@ -30,17 +47,18 @@ type DatabaseConfig struct {
 	Host         string `mapstructure:"host"`
 	Port         string `mapstructure:"port"`
 	User         string `mapstructure:"user"`
-	Password     string `mapstructure:"password"`
+	Password     string `mapstructure:"password" redact:"true"`
 	Name         string `mapstructure:"name"`
 	TablePrefix  string `mapstructure:"table_prefix"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
 	MaxOpenConns int    `mapstructure:"max_open_conns"`
 	SQLitePath   string `mapstructure:"sqlite_path"`
+	AutoMigrate  bool   `mapstructure:"auto_migrate"` // 启动时执行未执行的数据库迁移，关闭后需先执行 cinexus migrate up
 }
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret     string `mapstructure:"secret"`
+	Secret     string `mapstructure:"secret" redact:"true"`
 	Issuer     string `mapstructure:"issuer"`
 	ExpireTime int    `mapstructure:"expire_time"` // 过期时间（小时）
 }
@@ -53,35 +71,259 @@ type LogConfig struct {
 	MaxBackups int    `mapstructure:"max_backups"` // 保留的旧日志文件最大数量
 	MaxAge     int    `mapstructure:"max_age"`     // 保留的旧日志文件最大天数
 	Compress   bool   `mapstructure:"compress"`    // 是否压缩
+
+	Access AccessLogConfig `mapstructure:"access"`
 }
 
-// Conf 全局配置变量
-var Conf = &Config{}
+// AccessLogConfig HTTP请求日志配置
+type AccessLogConfig struct {
+	SkipPaths        []string `mapstructure:"skip_paths"`         // 成功时不记录的路径，以 * 结尾表示前缀匹配
+	BodyMaxSize      int      `mapstructure:"body_max_size"`      // 记录请求体和响应体的最大字节数，超出部分截断，0 表示不记录
+	BodyContentTypes []string `mapstructure:"body_content_types"` // 记录请求体和响应体的内容类型
+	RedactFields     []string `mapstructure:"redact_fields"`      // 字段名包含这些关键字（不区分大小写）时脱敏
+}
 
-// Init 初始化配置
-func Init() error {
-	workDir, err := os.Getwd()
-	if err != nil {
+// RegisterConfig 注册配置
+type RegisterConfig struct {
+	Mode              string `mapstructure:"mode"`               // 注册模式：open, invite, closed
+	EmailVerification bool   `mapstructure:"email_verification"` // 是否需要邮箱验证
+	VerifyURL         string `mapstructure:"verify_url"`         // 验证链接地址，令牌以 token 参数附加
+	VerifyExpire      int    `mapstructure:"verify_expire"`      // 验证链接有效期（小时）
+}
+
+// SMTPConfig 邮件服务配置
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password" redact:"true"`
+	From     string `mapstructure:"from"`
+	SSL      bool   `mapstructure:"ssl"` // 是否使用隐式TLS（通常为465端口）
+}
+
+// CD2Config CloudDrive2 实例配置
+type CD2Config struct {
+	Name     string `mapstructure:"name"`                    // 实例名称，接口中通过 instance 参数引用
+	Address  string `mapstructure:"address"`                 // gRPC 地址，如 127.0.0.1:19798
+	APIToken string `mapstructure:"api_token" redact:"true"` // API令牌，优先于用户名密码使用
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password" redact:"true"`
+	Timeout  int    `mapstructure:"timeout"` // 单次调用超时时间（秒）
+
+	Mounts []MountConfig `mapstructure:"mounts"` // 期望的挂载点，启动时和挂载状态变化时自动调整
+}
+
+// MountConfig 挂载点配置
+type MountConfig struct {
+	MountPoint  string `mapstructure:"mount_point"` // 本地挂载路径
+	SourceDir   string `mapstructure:"source_dir"`  // CD2中的源目录
+	Name        string `mapstructure:"name"`
+	LocalMount  bool   `mapstructure:"local_mount"`
+	ReadOnly    bool   `mapstructure:"read_only"`
+	AutoMount   bool   `mapstructure:"auto_mount"`
+	UID         uint32 `mapstructure:"uid"`
+	GID         uint32 `mapstructure:"gid"`
+	Permissions string `mapstructure:"permissions"` // 八进制权限，如 0755
+	ProbePath   string `mapstructure:"probe_path"`  // Cinexus 访问该挂载点的本地路径，CD2与Cinexus不在同一主机或容器时配置
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token" redact:"true"` // 访问 /metrics 需要的 Bearer 令牌，为空表示不校验
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"`     // OTLP gRPC 地址，如 localhost:4317
+	Insecure    bool    `mapstructure:"insecure"`     // 不使用TLS连接 Endpoint
+	ServiceName string  `mapstructure:"service_name"` // 上报的服务名，默认 cinexus
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 采样比例，(0,1]，未设置时为 1，上游已采样的请求始终记录
+}
+
+// WatchdogConfig 挂载点健康检查配置
+type WatchdogConfig struct {
+	Enabled            bool `mapstructure:"enabled"`
+	Interval           int  `mapstructure:"interval"`             // 检查间隔（秒）
+	ProbeTimeout       int  `mapstructure:"probe_timeout"`        // 本地目录探测超时时间（秒）
+	AutoRemount        bool `mapstructure:"auto_remount"`         // 检查失败时是否自动重新挂载
+	MaxRemountAttempts int  `mapstructure:"max_remount_attempts"` // 连续重新挂载失败多少次后停止尝试，0 表示不限制
+	AllowEmpty         bool `mapstructure:"allow_empty"`          // 是否允许挂载目录为空，默认空目录视为挂载失效
+}
+
+// BackupConfig 备份策略配置
+type BackupConfig struct {
+	MonitorInterval int `mapstructure:"monitor_interval"` // 采集CD2备份状态的间隔（秒），0 表示不采集
+	HistoryDays     int `mapstructure:"history_days"`     // 备份状态历史保留天数
+}
+
+// QuotaConfig 云盘空间监控配置
+type QuotaConfig struct {
+	Interval       int     `mapstructure:"interval"`         // 采集云盘空间的间隔（秒），0 表示不采集
+	HistoryDays    int     `mapstructure:"history_days"`     // 空间采样保留天数
+	MinFreePercent float64 `mapstructure:"min_free_percent"` // 剩余空间低于总空间的百分比时告警，0 表示不检查
+	MinFreeGB      float64 `mapstructure:"min_free_gb"`      // 剩余空间低于该值（GB）时告警，0 表示不检查
+	ForecastDays   int     `mapstructure:"forecast_days"`    // 按增长速度预计该天数内用尽时告警，0 表示不预测
+	ForecastWindow int     `mapstructure:"forecast_window"`  // 计算增长速度使用的采样天数
+}
+
+// IndexConfig 本地文件索引配置
+type IndexConfig struct {
+	Enabled  bool              `mapstructure:"enabled"`
+	Interval int               `mapstructure:"interval"` // 全量重新扫描的间隔（秒），0 表示只在没有索引时扫描
+	Roots    []IndexRootConfig `mapstructure:"roots"`
+}
+
+// IndexRootConfig 建立索引的目录
+type IndexRootConfig struct {
+	Instance string `mapstructure:"instance"` // 为空表示默认实例
+	Path     string `mapstructure:"path"`
+}
+
+// TrashConfig Cinexus 回收站配置
+type TrashConfig struct {
+	Enabled       bool `mapstructure:"enabled"`        // 删除时移入云盘根目录下的 .cinexus-trash，关闭时使用云盘自带的回收站
+	RetentionDays int  `mapstructure:"retention_days"` // 保留天数，超过后永久删除，0 表示不自动清理
+	PurgeInterval int  `mapstructure:"purge_interval"` // 检查过期文件的间隔（秒）
+}
+
+// NotifyConfig 通知配置
+type NotifyConfig struct {
+	Webhook string   `mapstructure:"webhook" redact:"true"` // 以JSON POST通知内容的地址
+	Emails  []string `mapstructure:"emails"`                // 接收通知的邮箱，使用 smtp 配置发送
+}
+
+// current 当前生效的配置快照，重新加载时整体替换，不会修改已发布的快照
+var current atomic.Pointer[Config]
+
+// Get 获取当前生效的配置，返回的快照只读；同一流程中需要一致的配置时应只调用一次
+func Get() *Config {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	return &Config{}
+}
+
+// Init 初始化配置，path 为空时依次查找 CINEXUS_CONFIG 和工作目录下的 config/config.* 与 config.*
+// 支持 TOML、YAML 和 JSON，找不到配置文件时只使用默认值和环境变量
+func Init(path string) error {
+	setDefaults()
+	if err := bindEnv(); err != nil {
 		return err
 	}
 
-	// 设置配置文件路径
-	viper.AddConfigPath(filepath.Join(workDir, "config"))
-	viper.SetConfigName("config")
-	viper.SetConfigType("toml")
+	if path == "" {
+		path = os.Getenv(envPrefix + "_CONFIG")
+	}
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		viper.AddConfigPath(filepath.Join(workDir, "config"))
+		viper.AddConfigPath(workDir)
+		viper.SetConfigName("config")
+	}
 
-	// 读取配置文件
+	// 读取配置文件，未指定路径且找不到时使用默认配置
 	if err := viper.ReadInConfig(); err != nil {
-		return fmt.Errorf("读取配置文件失败: %w", err)
+		var notFound viper.ConfigFileNotFoundError
+		if path != "" || !errors.As(err, &notFound) {
+			return fmt.Errorf("读取配置文件失败: %w", err)
+		}
 	}
 
 	// 解析到结构体
-	if err := viper.Unmarshal(Conf); err != nil {
-		return fmt.Errorf("解析配置文件失败: %w", err)
+	conf, err := load()
+	if err != nil {
+		return err
 	}
+	current.Store(conf)
 
-	// 监控配置文件变化并热加载
-	viper.WatchConfig()
+	return nil
+}
+
+// File 返回使用的配置文件路径，没有配置文件时为空
+func File() string {
+	return viper.ConfigFileUsed()
+}
+
+// load 将 viper 中的配置与数据库中的配置项合并，解析为新的快照
+func load() (*Config, error) {
+	return loadWith(overrides)
+}
+
+// loadWith 将 viper 中的配置解析为新的快照，values 中的配置项优先
+func loadWith(values map[string]any) (*Config, error) {
+	settings := viper.AllSettings()
+	for key, value := range values {
+		setPath(settings, key, value)
+	}
+
+	conf := &Config{}
+	if err := decode(settings, conf, false); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if conf.JWT.Secret == "" {
+		conf.JWT.Secret = generatedSecret
+		if conf.JWT.Secret == "" {
+			conf.JWT.Secret = readJWTSecret(conf.Server.DataDir)
+		}
+	}
+	return conf, nil
+}
+
+// generatedSecret 启动服务时生成或读取的JWT密钥，之后重新加载配置时沿用，只在持有 reloadMu 时修改
+var generatedSecret string
+
+// jwtSecretFile 自动生成的JWT密钥文件
+func jwtSecretFile(dataDir string) string {
+	return filepath.Join(dataDir, "jwt_secret")
+}
+
+// readJWTSecret 读取数据目录中已生成的JWT密钥，不存在时返回空
+func readJWTSecret(dataDir string) string {
+	data, err := os.ReadFile(jwtSecretFile(dataDir))
+	if err != nil {
+		return ""
+	}
+	return string(bytes.TrimSpace(data))
+}
+
+// EnsureJWTSecret 未配置 jwt.secret 时使用数据目录中的随机密钥，没有时生成并保存，
+// 之后重新加载配置时沿用该密钥；只在启动服务时调用，检查和输出配置等命令不会写入文件
+func EnsureJWTSecret() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	conf := Get()
+	if secret := readJWTSecret(conf.Server.DataDir); secret != "" && secret == conf.JWT.Secret {
+		generatedSecret = secret
+		return nil
+	}
+	if conf.JWT.Secret != "" {
+		return nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	secret := hex.EncodeToString(b)
+	file := jwtSecretFile(conf.Server.DataDir)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	if err := os.WriteFile(file, []byte(secret+"\n"), 0600); err != nil {
+		return fmt.Errorf("保存JWT密钥失败: %w", err)
+	}
 
+	next := *conf
+	next.JWT.Secret = secret
+	generatedSecret = secret
+	current.Store(&next)
 	return nil
 }
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureJWTSecret(t *testing.T) {
+	dir := t.TempDir()
+	prev := current.Load()
+	t.Cleanup(func() {
+		current.Store(prev)
+		generatedSecret = ""
+	})
+
+	c := &Config{}
+	c.Server.DataDir = filepath.Join(dir, "data")
+	current.Store(c)
+
+	// 读取配置时不生成密钥文件
+	if secret := readJWTSecret(c.Server.DataDir); secret != "" {
+		t.Fatalf("密钥文件不应存在: %q", secret)
+	}
+	if _, err := os.Stat(c.Server.DataDir); !os.IsNotExist(err) {
+		t.Fatalf("读取配置时不应创建数据目录: %v", err)
+	}
+
+	if err := EnsureJWTSecret(); err != nil {
+		t.Fatalf("EnsureJWTSecret: %v", err)
+	}
+	secret := Get().JWT.Secret
+	if len(secret) < minSecretLength {
+		t.Fatalf("生成的密钥过短: %q", secret)
+	}
+	if got := readJWTSecret(c.Server.DataDir); got != secret {
+		t.Errorf("保存的密钥 = %q，应为 %q", got, secret)
+	}
+	info, err := os.Stat(jwtSecretFile(c.Server.DataDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("密钥文件权限 = %o，应为 600", perm)
+	}
+
+	// 再次调用沿用已有的密钥
+	if err := EnsureJWTSecret(); err != nil {
+		t.Fatalf("EnsureJWTSecret: %v", err)
+	}
+	if Get().JWT.Secret != secret {
+		t.Errorf("再次调用后密钥变化: %q", Get().JWT.Secret)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import "github.com/spf13/viper"
+
+// setDefaults 设置默认值，没有配置文件时使用SQLite直接启动
+func setDefaults() {
+	defaults := map[string]any{
+		"server.run_mode":      "release",
+		"server.port":          "9000",
+		"server.read_timeout":  60,
+		"server.write_timeout": 60,
+		"server.data_dir":      "./data",
+
+		"database.type":           "sqlite",
+		"database.host":           "127.0.0.1",
+		"database.port":           "3306",
+		"database.user":           "root",
+		"database.name":           "cinexus",
+		"database.table_prefix":   "cx_",
+		"database.max_idle_conns": 10,
+		"database.max_open_conns": 100,
+		"database.sqlite_path":    "./data/cinexus.db",
+		"database.auto_migrate":   true,
+
+		"jwt.issuer":      "cinexus",
+		"jwt.expire_time": 24,
+
+		"log.level":                     "info",
+		"log.filename":                  "logs/cinexus.log",
+		"log.max_size":                  100,
+		"log.max_backups":               10,
+		"log.max_age":                   30,
+		"log.compress":                  true,
+		"log.access.skip_paths":         []string{"/health/*", "/metrics"},
+		"log.access.body_content_types": []string{"application/json", "application/x-www-form-urlencoded"},
+		"log.access.redact_fields":      []string{"password", "token", "secret", "authorization", "cookie", "api_key"},
+
+		"register.mode":          "open",
+		"register.verify_expire": 24,
+
+		"smtp.port": 465,
+		"smtp.ssl":  true,
+
+		"watchdog.interval":             60,
+		"watchdog.probe_timeout":        10,
+		"watchdog.auto_remount":         true,
+		"watchdog.max_remount_attempts": 5,
+
+		"backup.monitor_interval": 60,
+		"backup.history_days":     30,
+
+		"quota.interval":         3600,
+		"quota.history_days":     90,
+		"quota.min_free_percent": 5,
+		"quota.min_free_gb":      50,
+		"quota.forecast_days":    7,
+		"quota.forecast_window":  7,
+
+		"index.interval": 86400,
+
+		"trash.enabled":        true,
+		"trash.retention_days": 30,
+		"trash.purge_interval": 3600,
+
+		"tracing.endpoint":     "localhost:4317",
+		"tracing.insecure":     true,
+		"tracing.service_name": "cinexus",
+		"tracing.sample_ratio": 1.0,
+	}
+	for key, value := range defaults {
+		viper.SetDefault(key, value)
+	}
+}
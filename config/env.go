@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// 环境变量前缀，如 CINEXUS_DATABASE_TYPE 对应 database.type
+const envPrefix = "CINEXUS"
+
+// 以JSON数组配置的列表，如 CINEXUS_CD2='[{"name":"default","address":"cd2:19798"}]'
+var jsonEnvKeys = []string{"cd2", "index.roots"}
+
+// bindEnv 为每个配置项绑定环境变量，并读取 <变量名>_FILE 指向的文件内容（用于 Docker secrets）
+func bindEnv() error {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		if err := viper.BindEnv(key); err != nil {
+			return err
+		}
+		name := envName(key) + "_FILE"
+		file := os.Getenv(name)
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", name, err)
+		}
+		viper.Set(key, strings.TrimRight(string(data), "\r\n"))
+	}
+
+	for _, key := range jsonEnvKeys {
+		raw := os.Getenv(envName(key))
+		if raw == "" {
+			continue
+		}
+		var value []map[string]any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return fmt.Errorf("解析 %s 失败，应为JSON数组: %w", envName(key), err)
+		}
+		viper.Set(key, value)
+	}
+	return nil
+}
+
+// envName 配置项对应的环境变量名
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys 按 mapstructure 标签列出所有可以用环境变量设置的配置项，结构体列表除外
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" {
+			continue
+		}
+		key := prefix + tag
+
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			keys = append(keys, configKeys(f.Type, key+".")...)
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct:
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// overrides 数据库中保存的配置项，键为配置项名称，如 notify.webhook，优先级高于配置文件和环境变量
+// 只在持有 reloadMu 时修改
+var overrides map[string]any
+
+// SetOverrides 替换数据库中的配置项并重新生成配置快照，返回发生变化的配置节
+// 合并后的配置校验失败时保留原有配置项和快照
+func SetOverrides(values map[string]any) ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	prev := overrides
+	overrides = values
+	changed, err := reload()
+	if err != nil {
+		overrides = prev
+		return nil, err
+	}
+	return changed, nil
+}
+
+// Base 返回不包含数据库配置项的配置，即默认值、配置文件和环境变量合并后的结果
+func Base() (*Config, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	return loadWith(nil)
+}
+
+// CheckValue 检查配置项是否存在，以及值能否按该配置项的类型解析，不允许类型转换和未知字段
+func CheckValue(key string, value any) error {
+	f, ok := lookup(key)
+	if !ok {
+		return fmt.Errorf("配置项不存在: %s", key)
+	}
+	if err := decode(value, reflect.New(f.Type).Interface(), true); err != nil {
+		return fmt.Errorf("%s 的值有误: %w", key, err)
+	}
+	return nil
+}
+
+// Value 获取配置项的值，结构体和结构体列表转换为map，redacted 为 true 时隐藏敏感字段
+func (c *Config) Value(key string, redacted bool) any {
+	var v any = c.Map(redacted)
+	for _, name := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// Redact 按配置项的类型隐藏值中的敏感字段，用于展示数据库中保存的配置项
+func Redact(key string, value any) any {
+	f, ok := lookup(key)
+	if !ok || value == nil {
+		return value
+	}
+
+	v := reflect.New(f.Type)
+	if err := decode(value, v.Interface(), false); err != nil {
+		return nil
+	}
+	if f.Tag.Get("redact") == "true" {
+		if v.Elem().IsZero() {
+			return ""
+		}
+		return redactedValue
+	}
+	return plainValue(v.Elem(), true)
+}
+
+// RestoreRedacted 将值中原样提交的脱敏占位内容替换为当前生效的值，列表按 name 字段对应，没有 name 时按位置对应
+// 管理界面读取到脱敏后的配置，只修改其他字段后提交时不会覆盖密码和令牌
+func RestoreRedacted(key string, value any) any {
+	return restoreRedacted(value, Get().Value(key, false))
+}
+
+func restoreRedacted(value, current any) any {
+	switch v := value.(type) {
+	case string:
+		if v == redactedValue && current != nil {
+			return current
+		}
+	case map[string]any:
+		cur, _ := current.(map[string]any)
+		for k, item := range v {
+			v[k] = restoreRedacted(item, cur[k])
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = restoreRedacted(item, matchItem(current, item, i))
+		}
+	}
+	return value
+}
+
+// matchItem 在当前列表中查找与提交的元素对应的元素
+func matchItem(current, item any, index int) any {
+	list := reflect.ValueOf(current)
+	if list.Kind() != reflect.Slice {
+		return nil
+	}
+
+	m, ok := item.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if name, ok := m["name"]; ok {
+		for i := 0; i < list.Len(); i++ {
+			if cur, ok := list.Index(i).Interface().(map[string]any); ok && cur["name"] == name {
+				return cur
+			}
+		}
+		return nil
+	}
+	if index < list.Len() {
+		return list.Index(index).Interface()
+	}
+	return nil
+}
+
+// lookup 按配置项名称查找 Config 中对应的字段
+func lookup(key string) (reflect.StructField, bool) {
+	var field reflect.StructField
+	t := reflect.TypeOf(Config{})
+	for _, name := range strings.Split(key, ".") {
+		if t.Kind() != reflect.Struct {
+			return field, false
+		}
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("mapstructure") == name {
+				field, found = t.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return field, false
+		}
+		t = field.Type
+	}
+	return field, true
+}
+
+// setPath 按 a.b.c 形式的名称设置嵌套map中的值，中间层不存在时创建
+func setPath(m map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	for _, name := range parts[:len(parts)-1] {
+		next, ok := m[name].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[name] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = value
+}
+
+// decode 与 viper.Unmarshal 相同的规则解析配置，strict 为 true 时不做类型转换且不允许未知字段
+func decode(input, output any, strict bool) error {
+	conf := &mapstructure.DecoderConfig{
+		Result:           output,
+		WeaklyTypedInput: !strict,
+		ErrorUnused:      strict,
+	}
+	if !strict {
+		conf.DecodeHook = mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		)
+	}
+
+	dec, err := mapstructure.NewDecoder(conf)
+	if err != nil {
+		return err
+	}
+	return dec.Decode(input)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+)
+
+// 脱敏后的占位内容
+const redactedValue = "******"
+
+// Map 将配置转换为以配置项名称为键的map，redacted 为 true 时隐藏密码、令牌等标记了 redact 的字段
+func (c *Config) Map(redacted bool) map[string]any {
+	return structMap(reflect.ValueOf(c).Elem(), redacted)
+}
+
+func structMap(v reflect.Value, redacted bool) map[string]any {
+	t := v.Type()
+	m := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+		if redacted && f.Tag.Get("redact") == "true" {
+			if !v.Field(i).IsZero() {
+				m[key] = redactedValue
+			} else {
+				m[key] = ""
+			}
+			continue
+		}
+		m[key] = plainValue(v.Field(i), redacted)
+	}
+	return m
+}
+
+func plainValue(v reflect.Value, redacted bool) any {
+	switch v.Kind() {
+	case reflect.Struct:
+		return structMap(v, redacted)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return v.Interface()
+		}
+		list := make([]map[string]any, v.Len())
+		for i := range list {
+			list[i] = structMap(v.Index(i), redacted)
+		}
+		return list
+	default:
+		return v.Interface()
+	}
+}
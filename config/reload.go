@@ -0,0 +1,118 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Subscriber 配置变化时的回调，old 和 new 为只读快照
+type Subscriber func(old, new *Config)
+
+// ReloadReporter 接收每次重新加载的结果，changed 为发生变化的配置节，err 不为空时新配置未生效
+type ReloadReporter func(changed []string, err error)
+
+// 修改后需要重启才能生效的配置节
+var restartSections = map[string]bool{
+	"metrics": true,
+	"tracing": true,
+	"index":   true,
+}
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   = make(map[string][]Subscriber)
+
+	// reloadMu 保证同一时间只有一次重新加载，避免连续保存文件时新旧快照交错
+	reloadMu sync.Mutex
+	watching bool
+)
+
+// OnChange 订阅配置节的变化，section 为配置文件中的节名，如 log、database、cd2
+// 回调在配置快照替换之后按订阅顺序同步调用
+func OnChange(section string, fn Subscriber) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	subscribers[section] = append(subscribers[section], fn)
+}
+
+// Watch 监控配置文件变化并重新加载，没有使用配置文件时不监控
+func Watch(report ReloadReporter) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if watching || File() == "" {
+		return
+	}
+	watching = true
+
+	viper.OnConfigChange(func(fsnotify.Event) {
+		changed, err := Reload()
+		report(changed, err)
+	})
+	viper.WatchConfig()
+}
+
+// Reload 解析并校验 viper 中的最新配置，通过后替换当前快照并通知变化的配置节
+// 校验失败时保留当前配置
+func Reload() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	return reload()
+}
+
+// reload 重新加载配置，调用方需持有 reloadMu
+func reload() ([]string, error) {
+	next, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	prev := Get()
+	changed := changedSections(prev, next)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	current.Store(next)
+
+	for _, section := range changed {
+		subscribersMu.RLock()
+		fns := append([]Subscriber(nil), subscribers[section]...)
+		subscribersMu.RUnlock()
+
+		for _, fn := range fns {
+			fn(prev, next)
+		}
+	}
+	return changed, nil
+}
+
+// RestartRequired 返回修改后需要重启才能生效的配置节
+func RestartRequired(changed []string) []string {
+	var result []string
+	for _, section := range changed {
+		if restartSections[section] {
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+// changedSections 按配置节比较两份配置，返回发生变化的节名
+func changedSections(old, new *Config) []string {
+	var changed []string
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, t.Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return changed
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JWT密钥的最小长度
+const minSecretLength = 32
+
+// 示例配置中的占位密钥，不能直接使用
+var placeholderSecrets = map[string]bool{
+	"your-secret-key-here": true,
+	"secret":               true,
+	"changeme":             true,
+}
+
+// ValidationError 配置校验失败，包含所有不合法的配置项
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "配置校验失败:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator 收集校验错误
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(field, format string, args ...any) {
+	v.problems = append(v.problems, field+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(field, "%q 不支持，可选值: %s", value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.addf(field, "不能小于 0")
+	}
+}
+
+func (v *validator) port(field, value string) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 65535 {
+		v.addf(field, "%q 不是合法的端口（1-65535）", value)
+	}
+}
+
+func (v *validator) address(field, value string) {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" {
+		v.addf(field, "%q 应为 host:port 格式", value)
+		return
+	}
+	v.port(field, port)
+}
+
+// writableDir 检查目录可写，目录不存在时检查最近的已存在的上级目录，不会创建任何目录
+func (v *validator) writableDir(field, dir string) {
+	dir = filepath.Clean(dir)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				v.addf(field, "%s 不是目录", dir)
+				return
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			v.addf(field, "无法访问 %s: %v", dir, err)
+			return
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".cinexus-check-*")
+	if err != nil {
+		v.addf(field, "目录 %s 不可写: %v", dir, err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+var (
+	permissionsRe = regexp.MustCompile(`^[0-7]{3,4}$`)
+	driveLetterRe = regexp.MustCompile(`^[A-Za-z]:\\?$`)
+)
+
+// 不允许作为挂载点的系统目录
+var forbiddenMountPoints = map[string]bool{
+	"/": true, "/bin": true, "/boot": true, "/dev": true, "/etc": true, "/lib": true, "/lib64": true,
+	"/proc": true, "/root": true, "/sbin": true, "/sys": true, "/usr": true, "/var": true, "/tmp": true,
+}
+
+// Validate 校验挂载选项，配置文件中声明的挂载点和接口提交的挂载选项共用
+func (m MountConfig) Validate() error {
+	mp := strings.TrimSpace(m.MountPoint)
+	switch {
+	case driveLetterRe.MatchString(mp):
+	case strings.HasPrefix(mp, "/"):
+		if forbiddenMountPoints[path.Clean(mp)] {
+			return fmt.Errorf("不允许挂载到系统目录: %s", mp)
+		}
+	default:
+		return errors.New("挂载点必须是绝对路径或盘符")
+	}
+
+	if !m.LocalMount && !strings.HasPrefix(m.SourceDir, "/") {
+		return errors.New("源目录必须是以 / 开头的CD2路径")
+	}
+
+	if m.Permissions != "" && !permissionsRe.MatchString(m.Permissions) {
+		return errors.New("权限必须是3到4位八进制数，如 0755")
+	}
+
+	// 只读挂载时写权限没有意义，直接拒绝以免误解
+	if m.ReadOnly && m.Permissions != "" && strings.ContainsAny(m.Permissions[len(m.Permissions)-3:], "2367") {
+		return errors.New("只读挂载不能设置写权限，如 0555")
+	}
+
+	return nil
+}
+
+// Validate 校验配置，一次返回所有错误
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	// 服务器
+	v.oneOf("server.run_mode", c.Server.RunMode, "debug", "release", "test")
+	v.port("server.port", c.Server.Port)
+	v.nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	v.nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	if c.Server.DataDir == "" {
+		v.addf("server.data_dir", "不能为空")
+	}
+
+	// 数据库
+	db := c.Database
+	v.oneOf("database.type", db.Type, "mysql", "sqlite")
+	switch db.Type {
+	case "mysql":
+		if db.Host == "" {
+			v.addf("database.host", "不能为空")
+		}
+		v.port("database.port", db.Port)
+		if db.User == "" {
+			v.addf("database.user", "不能为空")
+		}
+		if db.Name == "" {
+			v.addf("database.name", "不能为空")
+		}
+	case "sqlite":
+		if db.SQLitePath == "" {
+			v.addf("database.sqlite_path", "不能为空")
+		} else {
+			v.writableDir("database.sqlite_path", filepath.Dir(db.SQLitePath))
+		}
+	}
+	v.nonNegative("database.max_idle_conns", db.MaxIdleConns)
+	v.nonNegative("database.max_open_conns", db.MaxOpenConns)
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		v.addf("database.max_idle_conns", "不能大于 max_open_conns")
+	}
+
+	// JWT
+	switch {
+	case c.JWT.Secret == "":
+		// 启动服务时自动生成并保存在数据目录中
+		if c.Server.DataDir != "" {
+			v.writableDir("server.data_dir", c.Server.DataDir)
+		}
+	case placeholderSecrets[strings.ToLower(c.JWT.Secret)]:
+		v.addf("jwt.secret", "不能使用示例配置中的密钥")
+	case len(c.JWT.Secret) < minSecretLength:
+		v.addf("jwt.secret", "长度不能少于 %d 个字符", minSecretLength)
+	}
+	if c.JWT.ExpireTime <= 0 {
+		v.addf("jwt.expire_time", "必须大于 0")
+	}
+
+	// 日志
+	v.oneOf("log.level", strings.ToLower(c.Log.Level), "debug", "info", "warn", "error")
+	if c.Log.Filename == "" {
+		v.addf("log.filename", "不能为空")
+	} else {
+		v.writableDir("log.filename", filepath.Dir(c.Log.Filename))
+	}
+	v.nonNegative("log.max_size", c.Log.MaxSize)
+	v.nonNegative("log.max_backups", c.Log.MaxBackups)
+	v.nonNegative("log.max_age", c.Log.MaxAge)
+	v.nonNegative("log.access.body_max_size", c.Log.Access.BodyMaxSize)
+
+	// 注册和邮件
+	v.oneOf("register.mode", c.Register.Mode, "open", "invite", "closed")
+	if c.Register.EmailVerification {
+		if c.SMTP.Host == "" {
+			v.addf("register.email_verification", "需要配置 smtp.host")
+		}
+		if c.Register.VerifyURL == "" {
+			v.addf("register.verify_url", "开启邮箱验证时不能为空")
+		}
+	}
+	if c.Register.VerifyURL != "" {
+		if u, err := url.Parse(c.Register.VerifyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.addf("register.verify_url", "%q 不是合法的URL", c.Register.VerifyURL)
+		}
+	}
+	if c.Register.VerifyExpire <= 0 {
+		v.addf("register.verify_expire", "必须大于 0")
+	}
+	if c.SMTP.Host != "" {
+		v.port("smtp.port", strconv.Itoa(c.SMTP.Port))
+		if _, err := mail.ParseAddress(c.SMTP.From); err != nil {
+			v.addf("smtp.from", "%q 不是合法的发件人地址", c.SMTP.From)
+		}
+	}
+
+	// CD2
+	names := make(map[string]bool)
+	for i, cd2 := range c.CD2 {
+		field := fmt.Sprintf("cd2[%d]", i)
+		if cd2.Name == "" {
+			v.addf(field+".name", "不能为空")
+		} else if names[cd2.Name] {
+			v.addf(field+".name", "实例名称 %q 重复", cd2.Name)
+		}
+		names[cd2.Name] = true
+		if cd2.Address == "" {
+			v.addf(field+".address", "不能为空")
+		} else {
+			v.address(field+".address", cd2.Address)
+		}
+		v.nonNegative(field+".timeout", cd2.Timeout)
+		for j, m := range cd2.Mounts {
+			if err := m.Validate(); err != nil {
+				v.addf(fmt.Sprintf("%s.mounts[%d]", field, j), "%v", err)
+			}
+		}
+	}
+
+	// 后台任务
+	v.nonNegative("watchdog.interval", c.Watchdog.Interval)
+	v.nonNegative("watchdog.probe_timeout", c.Watchdog.ProbeTimeout)
+	v.nonNegative("watchdog.max_remount_attempts", c.Watchdog.MaxRemountAttempts)
+	v.nonNegative("backup.monitor_interval", c.Backup.MonitorInterval)
+	v.nonNegative("backup.history_days", c.Backup.HistoryDays)
+	v.nonNegative("quota.interval", c.Quota.Interval)
+	v.nonNegative("quota.history_days", c.Quota.HistoryDays)
+	if c.Quota.MinFreePercent < 0 || c.Quota.MinFreePercent > 100 {
+		v.addf("quota.min_free_percent", "应在 0-100 之间")
+	}
+	if c.Quota.MinFreeGB < 0 {
+		v.addf("quota.min_free_gb", "不能小于 0")
+	}
+	v.nonNegative("quota.forecast_days", c.Quota.ForecastDays)
+	v.nonNegative("quota.forecast_window", c.Quota.ForecastWindow)
+	v.nonNegative("index.interval", c.Index.Interval)
+	for i, r := range c.Index.Roots {
+		field := fmt.Sprintf("index.roots[%d]", i)
+		if !strings.HasPrefix(r.Path, "/") {
+			v.addf(field+".path", "%q 应为绝对路径", r.Path)
+		}
+		if r.Instance != "" && !names[r.Instance] {
+			v.addf(field+".instance", "CD2实例 %q 不存在", r.Instance)
+		}
+	}
+	v.nonNegative("trash.retention_days", c.Trash.RetentionDays)
+	v.nonNegative("trash.purge_interval", c.Trash.PurgeInterval)
+
+	// 通知、监控
+	if c.Notify.Webhook != "" {
+		if u, err := url.Parse(c.Notify.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf("notify.webhook", "%q 不是合法的HTTP地址", c.Notify.Webhook)
+		}
+	}
+	for i, e := range c.Notify.Emails {
+		if _, err := mail.ParseAddress(e); err != nil {
+			v.addf(fmt.Sprintf("notify.emails[%d]", i), "%q 不是合法的邮箱", e)
+		}
+	}
+	if c.Tracing.Enabled {
+		if c.Tracing.Endpoint == "" {
+			v.addf("tracing.endpoint", "开启链路追踪时不能为空")
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			v.addf("tracing.sample_ratio", "应在 0-1 之间")
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// validConfig 返回默认值加上必填项的配置，路径都在临时目录中
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	setDefaults()
+	c := &Config{}
+	if err := decode(viper.AllSettings(), c, false); err != nil {
+		t.Fatalf("解析默认配置失败: %v", err)
+	}
+	dir := t.TempDir()
+	c.Database.SQLitePath = filepath.Join(dir, "data", "cinexus.db")
+	c.Log.Filename = filepath.Join(dir, "logs", "cinexus.log")
+	c.Server.DataDir = filepath.Join(dir, "data")
+	c.JWT.Secret = strings.Repeat("s", minSecretLength)
+	return c
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Fatalf("默认配置应通过校验: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string // 错误信息中应包含的配置项，为空表示应通过校验
+	}{
+		{
+			name:   "端口不合法",
+			modify: func(c *Config) { c.Server.Port = "70000" },
+			want:   []string{"server.port"},
+		},
+		{
+			name:   "运行模式不支持",
+			modify: func(c *Config) { c.Server.RunMode = "prod" },
+			want:   []string{"server.run_mode"},
+		},
+		{
+			name:   "数据库类型不支持",
+			modify: func(c *Config) { c.Database.Type = "postgres" },
+			want:   []string{"database.type"},
+		},
+		{
+			name: "MySQL缺少连接信息",
+			modify: func(c *Config) {
+				c.Database.Type = "mysql"
+				c.Database.Host, c.Database.User, c.Database.Name = "", "", ""
+			},
+			want: []string{"database.host", "database.user", "database.name"},
+		},
+		{
+			name:   "空闲连接数大于最大连接数",
+			modify: func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 5, 10 },
+			want:   []string{"database.max_idle_conns"},
+		},
+		{
+			name:   "JWT密钥为示例值",
+			modify: func(c *Config) { c.JWT.Secret = "your-secret-key-here" },
+			want:   []string{"jwt.secret"},
+		},
+		{
+			name:   "JWT密钥过短",
+			modify: func(c *Config) { c.JWT.Secret = "short" },
+			want:   []string{"jwt.secret"},
+		},
+		{
+			name:   "JWT密钥留空时启动服务时生成",
+			modify: func(c *Config) { c.JWT.Secret = "" },
+		},
+		{
+			name:   "数据目录为空",
+			modify: func(c *Config) { c.Server.DataDir = "" },
+			want:   []string{"server.data_dir"},
+		},
+		{
+			name:   "日志级别不支持",
+			modify: func(c *Config) { c.Log.Level = "trace" },
+			want:   []string{"log.level"},
+		},
+		{
+			name:   "日志级别不区分大小写",
+			modify: func(c *Config) { c.Log.Level = "WARN" },
+		},
+		{
+			name:   "开启邮箱验证但未配置邮件服务",
+			modify: func(c *Config) { c.Register.EmailVerification = true },
+			want:   []string{"register.email_verification", "register.verify_url"},
+		},
+		{
+			name: "开启邮箱验证",
+			modify: func(c *Config) {
+				c.Register.EmailVerification = true
+				c.Register.VerifyURL = "https://cinexus.example.com/verify"
+				c.SMTP.Host, c.SMTP.Port, c.SMTP.From = "smtp.example.com", 465, "Cinexus <noreply@example.com>"
+			},
+		},
+		{
+			name:   "发件人地址不合法",
+			modify: func(c *Config) { c.SMTP.Host, c.SMTP.Port, c.SMTP.From = "smtp.example.com", 25, "noreply" },
+			want:   []string{"smtp.from"},
+		},
+		{
+			name: "CD2实例名称重复且地址不合法",
+			modify: func(c *Config) {
+				c.CD2 = []CD2Config{{Name: "a", Address: "127.0.0.1:19798"}, {Name: "a", Address: "127.0.0.1"}}
+			},
+			want: []string{"cd2[1].name", "cd2[1].address"},
+		},
+		{
+			name: "只读挂载设置了写权限",
+			modify: func(c *Config) {
+				c.CD2 = []CD2Config{{Name: "a", Address: "127.0.0.1:19798", Mounts: []MountConfig{
+					{MountPoint: "/mnt/cloud", SourceDir: "/115", ReadOnly: true, Permissions: "0755"},
+				}}}
+			},
+			want: []string{"cd2[0].mounts[0]"},
+		},
+		{
+			name: "挂载到系统目录",
+			modify: func(c *Config) {
+				c.CD2 = []CD2Config{{Name: "a", Address: "127.0.0.1:19798", Mounts: []MountConfig{
+					{MountPoint: "/etc", SourceDir: "/115"},
+				}}}
+			},
+			want: []string{"cd2[0].mounts[0]"},
+		},
+		{
+			name: "合法的挂载点",
+			modify: func(c *Config) {
+				c.CD2 = []CD2Config{{Name: "a", Address: "127.0.0.1:19798", Mounts: []MountConfig{
+					{MountPoint: "/mnt/cloud", SourceDir: "/115", ReadOnly: true, Permissions: "0555"},
+					{MountPoint: "D:", SourceDir: "/aliyun"},
+				}}}
+			},
+		},
+		{
+			name: "索引目录的实例不存在",
+			modify: func(c *Config) {
+				c.Index.Roots = []IndexRootConfig{{Instance: "missing", Path: "movies"}}
+			},
+			want: []string{"index.roots[0].path", "index.roots[0].instance"},
+		},
+		{
+			name:   "剩余空间百分比超出范围",
+			modify: func(c *Config) { c.Quota.MinFreePercent = 120 },
+			want:   []string{"quota.min_free_percent"},
+		},
+		{
+			name:   "Webhook不是HTTP地址",
+			modify: func(c *Config) { c.Notify.Webhook = "ftp://example.com" },
+			want:   []string{"notify.webhook"},
+		},
+		{
+			name:   "开启链路追踪但没有地址",
+			modify: func(c *Config) { c.Tracing.Enabled, c.Tracing.Endpoint = true, "" },
+			want:   []string{"tracing.endpoint"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig(t)
+			tt.modify(c)
+			err := c.Validate()
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("应通过校验: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("err = %v，应为 *ValidationError", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Errorf("错误数量 = %d，应为 %d:\n%v", len(verr.Problems), len(tt.want), err)
+			}
+			for _, field := range tt.want {
+				found := false
+				for _, p := range verr.Problems {
+					if strings.HasPrefix(p, field+":") {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("缺少 %s 的错误:\n%v", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMountConfigValidate(t *testing.T) {
+	tests := []struct {
+		mount MountConfig
+		ok    bool
+	}{
+		{MountConfig{MountPoint: "/mnt/cloud", SourceDir: "/115"}, true},
+		{MountConfig{MountPoint: "/mnt/cloud/", SourceDir: "/115", Permissions: "755"}, true},
+		{MountConfig{MountPoint: "Z:\\", SourceDir: "/115"}, true},
+		{MountConfig{MountPoint: "/mnt/local", LocalMount: true}, true},
+		{MountConfig{MountPoint: "/mnt/cloud", SourceDir: "/115", ReadOnly: true, Permissions: "0444"}, true},
+		{MountConfig{MountPoint: "mnt/cloud", SourceDir: "/115"}, false},
+		{MountConfig{MountPoint: "/usr/", SourceDir: "/115"}, false},
+		{MountConfig{MountPoint: "/mnt/cloud", SourceDir: "115"}, false},
+		{MountConfig{MountPoint: "/mnt/cloud", SourceDir: "/115", Permissions: "0855"}, false},
+		{MountConfig{MountPoint: "/mnt/cloud", SourceDir: "/115", Permissions: "rwx"}, false},
+		{MountConfig{MountPoint: "/mnt/cloud", SourceDir: "/115", ReadOnly: true, Permissions: "0575"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.mount.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v: err = %v, ok = %v", tt.mount, err, tt.ok)
+		}
+	}
+}
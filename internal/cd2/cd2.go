@@ -0,0 +1,317 @@
+package cd2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"cinexus/config"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/pb"
+)
+
+// ErrInstanceNotFound CD2实例不存在
+var ErrInstanceNotFound = errors.New("CD2实例不存在")
+
+// InterceptorFactory 按实例名称创建gRPC客户端拦截器
+type InterceptorFactory func(instance string) grpc.UnaryClientInterceptor
+
+// Client CloudDrive2 客户端
+type Client struct {
+	pb.CloudDriveFileSrvClient
+
+	Name    string
+	conf    config.CD2Config
+	conn    *grpc.ClientConn
+	timeout time.Duration
+	done    chan struct{} // 客户端关闭时关闭，用于停止后台任务
+
+	mu          sync.Mutex
+	token       string
+	tokenExpire time.Time
+}
+
+var (
+	mu      sync.RWMutex
+	clients []*Client
+	pushCtx context.Context // StartPushListener 的上下文，重新加载配置时为新实例建立推送消息流
+
+	factories     []InterceptorFactory
+	subscribeOnce sync.Once
+)
+
+// Init 根据配置创建所有CD2客户端，连接在首次调用时建立
+// 配置文件中的 cd2 修改后自动重新加载，配置未变化的实例保留原有连接
+func Init(interceptors ...InterceptorFactory) error {
+	factories = interceptors
+	if err := load(config.Get().CD2); err != nil {
+		return err
+	}
+
+	subscribeOnce.Do(func() {
+		config.OnChange("cd2", func(_, new *config.Config) {
+			if err := load(new.CD2); err != nil {
+				logger.Error("重新加载CD2实例失败，继续使用原有实例", zap.Error(err))
+				return
+			}
+			logger.Info("CD2实例配置已重新加载", zap.Int("instances", len(new.CD2)))
+		})
+	})
+	return nil
+}
+
+// load 按配置替换客户端列表，配置未变化的实例复用原客户端，其余的新建，不再使用的客户端关闭
+func load(confs []config.CD2Config) error {
+	mu.RLock()
+	existing := make(map[string]*Client, len(clients))
+	for _, c := range clients {
+		existing[c.Name] = c
+	}
+	mu.RUnlock()
+
+	var next, created []*Client
+	reused := make(map[*Client]bool)
+	names := make(map[string]bool)
+
+	for _, conf := range confs {
+		if conf.Name == "" || conf.Address == "" {
+			closeClients(created)
+			return errors.New("CD2实例的 name 和 address 不能为空")
+		}
+		if names[conf.Name] {
+			closeClients(created)
+			return fmt.Errorf("CD2实例名称重复: %s", conf.Name)
+		}
+		names[conf.Name] = true
+
+		if c, ok := existing[conf.Name]; ok && reflect.DeepEqual(c.conf, conf) {
+			next = append(next, c)
+			reused[c] = true
+			continue
+		}
+
+		client, err := newClient(conf, factories)
+		if err != nil {
+			closeClients(created)
+			return fmt.Errorf("创建CD2客户端 %s 失败: %w", conf.Name, err)
+		}
+		next = append(next, client)
+		created = append(created, client)
+	}
+
+	mu.Lock()
+	old := clients
+	clients = next
+	ctx := pushCtx
+	mu.Unlock()
+
+	var removed []*Client
+	for _, c := range old {
+		if !reused[c] {
+			removed = append(removed, c)
+		}
+	}
+	closeClients(removed)
+
+	if ctx != nil {
+		for _, c := range created {
+			go c.listenPush(ctx)
+		}
+	}
+	return nil
+}
+
+// Get 获取指定名称的CD2客户端，名称为空时返回默认（第一个）实例
+func Get(name string) (*Client, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if len(clients) == 0 {
+		return nil, errors.New("未配置CD2实例")
+	}
+	if name == "" {
+		return clients[0], nil
+	}
+	for _, c := range clients {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
+}
+
+// All 获取所有CD2客户端
+func All() []*Client {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]*Client(nil), clients...)
+}
+
+// Close 关闭所有CD2连接
+func Close() {
+	mu.Lock()
+	old := clients
+	clients = nil
+	mu.Unlock()
+
+	closeClients(old)
+}
+
+// Address 获取实例的gRPC地址
+func (c *Client) Address() string {
+	return c.conf.Address
+}
+
+// Config 获取实例配置
+func (c *Client) Config() config.CD2Config {
+	return c.conf
+}
+
+// Done 返回客户端关闭时关闭的通道
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// newClient 创建单个CD2客户端
+func newClient(conf config.CD2Config, interceptors []InterceptorFactory) (*Client, error) {
+	timeout := time.Duration(conf.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	c := &Client{
+		Name:    conf.Name,
+		conf:    conf,
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+
+	// 指标拦截器放在最外层，获取令牌失败的调用也会被记录
+	unary := []grpc.UnaryClientInterceptor{metrics.CD2UnaryInterceptor(conf.Name), c.unaryInterceptor}
+	for _, factory := range interceptors {
+		unary = append(unary, factory(conf.Name))
+	}
+
+	conn, err := grpc.NewClient(conf.Address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(metrics.CD2StreamInterceptor(conf.Name), c.streamInterceptor),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+	c.CloudDriveFileSrvClient = pb.NewCloudDriveFileSrvClient(conn)
+	return c, nil
+}
+
+// unaryInterceptor 为普通调用附加认证信息和默认超时
+func (c *Client) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	ctx, err := c.authorize(withRequestID(ctx), method)
+	if err != nil {
+		return err
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// streamInterceptor 为流式调用附加认证信息，流式调用的生命周期由调用方控制
+func (c *Client) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, err := c.authorize(withRequestID(ctx), method)
+	if err != nil {
+		return nil, err
+	}
+
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// withRequestID 将当前请求的请求ID传给CD2，便于对照CD2日志排查
+func withRequestID(ctx context.Context) context.Context {
+	if id := logger.RequestID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, "x-request-id", id)
+	}
+	return ctx
+}
+
+// authorize 在请求元数据中附加令牌，获取令牌的接口本身无需认证
+func (c *Client) authorize(ctx context.Context, method string) (context.Context, error) {
+	if method == pb.CloudDriveFileSrv_GetToken_FullMethodName ||
+		method == pb.CloudDriveFileSrv_GetSystemInfo_FullMethodName {
+		return ctx, nil
+	}
+
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return ctx, nil
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), nil
+}
+
+// getToken 获取访问令牌，使用账号密码时在过期前自动刷新
+func (c *Client) getToken(ctx context.Context) (string, error) {
+	if c.conf.APIToken != "" {
+		return c.conf.APIToken, nil
+	}
+	if c.conf.Username == "" {
+		return "", nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Add(5*time.Minute).Before(c.tokenExpire) {
+		return c.token, nil
+	}
+
+	resp, err := c.CloudDriveFileSrvClient.GetToken(ctx, &pb.GetTokenRequest{
+		UserName: c.conf.Username,
+		Password: c.conf.Password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("获取CD2令牌失败: %w", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("获取CD2令牌失败: %s", resp.ErrorMessage)
+	}
+
+	c.token = resp.Token
+	c.tokenExpire = time.Now().Add(time.Hour)
+	if resp.Expiration != nil {
+		c.tokenExpire = resp.Expiration.AsTime()
+	}
+	return c.token, nil
+}
+
+// closeClients 关闭客户端连接
+func closeClients(list []*Client) {
+	for _, c := range list {
+		close(c.done)
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cd2
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/pkg/logger"
+	"cinexus/pkg/pb"
+)
+
+// PushHandler CD2推送消息处理函数
+type PushHandler func(instance string, msg *pb.CloudDrivePushMessage)
+
+// 推送流断开后的重连间隔
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+var (
+	handlerMu sync.RWMutex
+	handlers  []PushHandler
+)
+
+// Subscribe 订阅所有CD2实例的推送消息，需在 StartPushListener 之前调用
+func Subscribe(handler PushHandler) {
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+
+	handlers = append(handlers, handler)
+}
+
+// StartPushListener 为每个CD2实例建立推送消息流，断开后自动重连，直到ctx取消或客户端关闭
+// 重新加载配置后新建的实例也会自动建立推送消息流
+func StartPushListener(ctx context.Context) {
+	mu.Lock()
+	pushCtx = ctx
+	mu.Unlock()
+
+	for _, c := range All() {
+		go c.listenPush(ctx)
+	}
+}
+
+// listenPush 持续接收单个实例的推送消息
+func (c *Client) listenPush(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	delay := minReconnectDelay
+
+	for {
+		err := c.receivePush(ctx, func() { delay = minReconnectDelay })
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Warn("CD2推送消息流断开，稍后重连",
+			zap.String("instance", c.Name),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// receivePush 接收推送消息直到流出错，成功收到消息时调用 onMessage
+func (c *Client) receivePush(ctx context.Context, onMessage func()) error {
+	stream, err := c.PushMessage(ctx, &emptypb.Empty{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		onMessage()
+
+		handlerMu.RLock()
+		list := handlers
+		handlerMu.RUnlock()
+
+		for _, h := range list {
+			h(c.Name, msg)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// AuditController 审计日志控制器
+type AuditController struct {
+	auditService service.AuditService
+}
+
+// NewAuditController 创建审计日志控制器
+func NewAuditController() *AuditController {
+	return &AuditController{
+		auditService: service.AuditService{},
+	}
+}
+
+// List 查询审计日志
+func (c *AuditController) List(ctx *gin.Context) {
+	var query service.AuditQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	logs, total, err := c.auditService.List(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, logs, total, query.Page, query.PageSize)
+}
+
+// Export 导出审计日志，支持csv和json（按行分隔）格式
+func (c *AuditController) Export(ctx *gin.Context) {
+	var query service.AuditQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	format := ctx.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		response.BadRequest(ctx, "不支持的导出格式: "+format)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().Format("20060102150405"), format)
+	ctx.Header("Content-Disposition", "attachment; filename="+filename)
+
+	var err error
+	if format == "csv" {
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		err = c.exportCSV(ctx, &query)
+	} else {
+		ctx.Header("Content-Type", "application/x-ndjson")
+		err = c.exportJSON(ctx, &query)
+	}
+
+	// 响应头已写出，只能将错误附加在输出末尾
+	if err != nil {
+		_ = ctx.Error(err)
+		fmt.Fprintf(ctx.Writer, "\n# 导出中断: %s\n", err.Error())
+	}
+}
+
+// exportCSV 以CSV格式写出审计日志
+func (c *AuditController) exportCSV(ctx *gin.Context, query *service.AuditQuery) error {
+	// 写入BOM，便于Excel正确识别UTF-8编码
+	ctx.Writer.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(ctx.Writer)
+	w.Write([]string{"id", "time", "user_id", "username", "action", "target_type", "target", "instance", "ip", "result", "error", "before", "after"})
+
+	err := c.auditService.Export(ctx.Request.Context(), query, func(logs []model.AuditLog) error {
+		for _, l := range logs {
+			w.Write([]string{
+				strconv.FormatUint(uint64(l.ID), 10),
+				l.CreatedAt.Format(time.RFC3339),
+				strconv.FormatUint(uint64(l.UserID), 10),
+				l.Username,
+				l.Action,
+				l.TargetType,
+				l.Target,
+				l.Instance,
+				l.IP,
+				l.Result,
+				l.Error,
+				l.Before,
+				l.After,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	})
+
+	w.Flush()
+	return err
+}
+
+// exportJSON 以每行一个JSON对象的格式写出审计日志
+func (c *AuditController) exportJSON(ctx *gin.Context, query *service.AuditQuery) error {
+	enc := json.NewEncoder(ctx.Writer)
+
+	return c.auditService.Export(ctx.Request.Context(), query, func(logs []model.AuditLog) error {
+		for i := range logs {
+			if err := enc.Encode(&logs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// BackupController 备份策略控制器
+type BackupController struct {
+	backupService service.BackupService
+}
+
+// NewBackupController 创建备份策略控制器
+func NewBackupController() *BackupController {
+	return &BackupController{
+		backupService: service.BackupService{},
+	}
+}
+
+// List 查询备份策略
+func (c *BackupController) List(ctx *gin.Context) {
+	var query service.BackupPolicyQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	policies, total, err := c.backupService.List(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, policies, total, query.Page, query.PageSize)
+}
+
+// Get 获取备份策略
+func (c *BackupController) Get(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	policy, err := c.backupService.Get(ctx.Request.Context(), id)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, policy)
+}
+
+// Create 创建备份策略
+func (c *BackupController) Create(ctx *gin.Context) {
+	var req service.BackupPolicyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	policy, err := c.backupService.Create(ctx.Request.Context(), &req)
+	c.policyResponse(ctx, policy, err, "创建成功")
+}
+
+// Update 修改备份策略
+func (c *BackupController) Update(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	var req service.BackupPolicyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if before, err := c.backupService.Get(ctx.Request.Context(), id); err == nil {
+		middleware.SetAuditSnapshot(ctx, before, nil)
+	}
+	policy, err := c.backupService.Update(ctx.Request.Context(), id, &req)
+	if policy != nil {
+		middleware.SetAuditSnapshot(ctx, nil, policy)
+	}
+	c.policyResponse(ctx, policy, err, "修改成功")
+}
+
+// UpdateStrategies 修改冲突和删除规则
+func (c *BackupController) UpdateStrategies(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	var req service.BackupStrategiesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	policy, err := c.backupService.UpdateStrategies(ctx.Request.Context(), id, &req)
+	c.policyResponse(ctx, policy, err, "修改成功")
+}
+
+// Enable 启用备份
+func (c *BackupController) Enable(ctx *gin.Context) {
+	c.setEnabled(ctx, true)
+}
+
+// Disable 停用备份
+func (c *BackupController) Disable(ctx *gin.Context) {
+	c.setEnabled(ctx, false)
+}
+
+// Delete 删除备份策略
+func (c *BackupController) Delete(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.backupService.Delete(ctx.Request.Context(), id); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "删除成功", nil)
+}
+
+// RestartWalk 重新全量扫描
+func (c *BackupController) RestartWalk(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.backupService.RestartWalk(ctx.Request.Context(), id); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "已开始重新扫描", nil)
+}
+
+// Status 获取实时状态
+func (c *BackupController) Status(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	state, err := c.backupService.Status(ctx.Request.Context(), id)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, state)
+}
+
+// History 获取状态历史
+func (c *BackupController) History(ctx *gin.Context) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	var query service.BackupHistoryQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	logs, total, err := c.backupService.History(ctx.Request.Context(), id, &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, logs, total, query.Page, query.PageSize)
+}
+
+// Sync 将策略推送到CD2
+func (c *BackupController) Sync(ctx *gin.Context) {
+	instance := ctx.Query("instance")
+	middleware.SetAuditTarget(ctx, model.AuditTargetCD2, instance)
+
+	result := c.backupService.Sync(ctx.Request.Context(), instance)
+	response.Success(ctx, result)
+}
+
+// setEnabled 启用或停用备份
+func (c *BackupController) setEnabled(ctx *gin.Context, enabled bool) {
+	id, ok := backupID(ctx)
+	if !ok {
+		return
+	}
+
+	policy, err := c.backupService.SetEnabled(ctx.Request.Context(), id, enabled)
+	msg := "已停用"
+	if enabled {
+		msg = "已启用"
+	}
+	c.policyResponse(ctx, policy, err, msg)
+}
+
+// policyResponse 策略已保存但推送到CD2失败时仍返回成功，并在消息中说明，策略会在下次推送时重试
+func (c *BackupController) policyResponse(ctx *gin.Context, policy *model.BackupPolicy, err error, msg string) {
+	if policy != nil {
+		middleware.SetAuditTarget(ctx, model.AuditTargetBackup, policy.SourcePath)
+	}
+	if err != nil && policy == nil {
+		cd2Error(ctx, err)
+		return
+	}
+	if err != nil {
+		msg += "，但推送到CD2失败: " + err.Error()
+	}
+
+	response.SuccessWithMsg(ctx, msg, policy)
+}
+
+// backupID 解析路径中的策略ID
+func backupID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "策略ID错误")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// CloudAccountController 云盘账号管理控制器
+type CloudAccountController struct {
+	cloudAccountService service.CloudAccountService
+}
+
+// NewCloudAccountController 创建云盘账号管理控制器
+func NewCloudAccountController() *CloudAccountController {
+	return &CloudAccountController{
+		cloudAccountService: service.CloudAccountService{},
+	}
+}
+
+// List 获取云盘账号列表
+func (c *CloudAccountController) List(ctx *gin.Context) {
+	accounts, err := c.cloudAccountService.List(ctx.Request.Context(), ctx.Query("instance"))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, accounts)
+}
+
+// Capacity 检查是否还能添加云盘账号
+func (c *CloudAccountController) Capacity(ctx *gin.Context) {
+	can, reason, err := c.cloudAccountService.CanAddMore(ctx.Request.Context(), ctx.Query("instance"))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"can_add_more": can,
+		"reason":       reason,
+	})
+}
+
+// GetConfig 获取云盘账号设置
+func (c *CloudAccountController) GetConfig(ctx *gin.Context) {
+	var ref service.CloudAccountRef
+	if err := ctx.ShouldBindQuery(&ref); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	cfg, err := c.cloudAccountService.GetConfig(ctx.Request.Context(), ctx.Query("instance"), &ref)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, cfg)
+}
+
+// SetConfig 修改云盘账号设置
+func (c *CloudAccountController) SetConfig(ctx *gin.Context) {
+	var req service.SetCloudConfigRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetCloud, req.CloudName+"/"+req.UserName)
+	if err := c.cloudAccountService.SetConfig(ctx.Request.Context(), ctx.Query("instance"), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "修改成功", nil)
+}
+
+// Remove 删除云盘账号
+func (c *CloudAccountController) Remove(ctx *gin.Context) {
+	var req service.RemoveCloudRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetCloud, req.CloudName+"/"+req.UserName)
+	if err := c.cloudAccountService.Remove(ctx.Request.Context(), ctx.Query("instance"), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "删除成功", nil)
+}
+
+// LoginWebDav 添加WebDAV账号
+func (c *CloudAccountController) LoginWebDav(ctx *gin.Context) {
+	var req service.WebDavLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetCloud, "webdav:"+req.ServerURL)
+	if err := c.cloudAccountService.LoginWebDav(ctx.Request.Context(), ctx.Query("instance"), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "添加成功", nil)
+}
+
+// AddLocalFolder 添加本地目录
+func (c *CloudAccountController) AddLocalFolder(ctx *gin.Context) {
+	var req service.LocalFolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetCloud, "local:"+req.Path)
+	if err := c.cloudAccountService.AddLocalFolder(ctx.Request.Context(), ctx.Query("instance"), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "添加成功", nil)
+}
+
+// LoginToken 使用令牌或Cookie登录云盘
+func (c *CloudAccountController) LoginToken(ctx *gin.Context) {
+	var req service.TokenLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	provider := ctx.Param("provider")
+	middleware.SetAuditTarget(ctx, model.AuditTargetCloud, provider)
+	if err := c.cloudAccountService.LoginToken(ctx.Request.Context(), ctx.Query("instance"), provider, &req); err != nil {
+		if errors.Is(err, service.ErrUnsupportedProvider) {
+			response.BadRequest(ctx, err.Error())
+			return
+		}
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "登录成功", nil)
+}
+
+// QRCodeLogin 扫码登录，以 Server-Sent Events 转发二维码和扫码状态，事件名为消息类型
+func (c *CloudAccountController) QRCodeLogin(ctx *gin.Context) {
+	var req service.QRCodeLoginRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	provider := ctx.Param("provider")
+	middleware.SetAuditTarget(ctx, model.AuditTargetCloud, provider)
+
+	started := false
+	err := c.cloudAccountService.QRCodeLogin(ctx.Request.Context(), ctx.Query("instance"), provider, &req, func(msg service.QRCodeMessage) {
+		if !started {
+			ctx.Header("Cache-Control", "no-cache")
+			ctx.Header("X-Accel-Buffering", "no")
+			started = true
+		}
+		ctx.SSEvent(msg.Type, msg)
+		ctx.Writer.Flush()
+	})
+
+	// 开始推送后只能通过事件通知错误
+	if started {
+		if err != nil {
+			_ = ctx.Error(err)
+			if !errors.As(err, new(*service.FileOperationError)) {
+				ctx.SSEvent("error", service.QRCodeMessage{Type: "error", Message: err.Error()})
+				ctx.Writer.Flush()
+			}
+		}
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedProvider) {
+			response.BadRequest(ctx, err.Error())
+			return
+		}
+		cd2Error(ctx, err)
+		return
+	}
+	response.SuccessWithMsg(ctx, "扫码登录已结束", nil)
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// DedupeController 重复文件查找控制器
+type DedupeController struct {
+	dedupeService service.DedupeService
+}
+
+// NewDedupeController 创建重复文件查找控制器
+func NewDedupeController() *DedupeController {
+	return &DedupeController{
+		dedupeService: service.DedupeService{},
+	}
+}
+
+// Create 创建扫描任务
+func (c *DedupeController) Create(ctx *gin.Context) {
+	var req service.CreateDedupeScanRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	scan, err := c.dedupeService.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetDedupe, strconv.FormatUint(uint64(scan.ID), 10))
+	response.SuccessWithMsg(ctx, "扫描已开始", scan)
+}
+
+// List 查询扫描任务
+func (c *DedupeController) List(ctx *gin.Context) {
+	var query service.DedupeScanQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	scans, total, err := c.dedupeService.List(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, scans, total, query.Page, query.PageSize)
+}
+
+// Get 获取扫描任务
+func (c *DedupeController) Get(ctx *gin.Context) {
+	id, ok := dedupeID(ctx)
+	if !ok {
+		return
+	}
+
+	scan, err := c.dedupeService.Get(ctx.Request.Context(), id)
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, scan)
+}
+
+// ListGroups 查询重复分组
+func (c *DedupeController) ListGroups(ctx *gin.Context) {
+	id, ok := dedupeID(ctx)
+	if !ok {
+		return
+	}
+
+	var query service.DedupeGroupQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	groups, total, err := c.dedupeService.ListGroups(ctx.Request.Context(), id, &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, groups, total, query.Page, query.PageSize)
+}
+
+// Cancel 取消扫描
+func (c *DedupeController) Cancel(ctx *gin.Context) {
+	id, ok := dedupeID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.dedupeService.Cancel(id); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "正在取消", nil)
+}
+
+// Review 确认分组中保留的文件
+func (c *DedupeController) Review(ctx *gin.Context) {
+	id, ok := dedupeID(ctx)
+	if !ok {
+		return
+	}
+
+	var req service.ReviewDedupeGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	group, err := c.dedupeService.Review(ctx.Request.Context(), id, &req)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "已确认", group)
+}
+
+// Apply 将已确认分组中的其余副本移入云盘回收站
+func (c *DedupeController) Apply(ctx *gin.Context) {
+	id, ok := dedupeID(ctx)
+	if !ok {
+		return
+	}
+
+	var req service.ApplyDedupeRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetDedupe, strconv.FormatUint(uint64(id), 10))
+	result, err := c.dedupeService.Apply(ctx.Request.Context(), id, &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "清理完成", result)
+}
+
+// dedupeID 解析路径中的ID
+func dedupeID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "ID错误")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cinexus/internal/cd2"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// FileController CD2文件浏览控制器
+type FileController struct {
+	fileService service.FileService
+}
+
+// NewFileController 创建文件浏览控制器
+func NewFileController() *FileController {
+	return &FileController{
+		fileService: service.FileService{},
+	}
+}
+
+// ListInstances 获取CD2实例列表
+func (c *FileController) ListInstances(ctx *gin.Context) {
+	response.Success(ctx, c.fileService.ListInstances())
+}
+
+// List 列出目录内容
+func (c *FileController) List(ctx *gin.Context) {
+	var req service.ListFilesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	files, total, err := c.fileService.List(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithPage(ctx, files, total, req.Page, req.PageSize)
+}
+
+// Stat 获取文件详情
+func (c *FileController) Stat(ctx *gin.Context) {
+	var req service.StatFileRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	detail, err := c.fileService.Stat(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, detail)
+}
+
+// Search 搜索文件
+func (c *FileController) Search(ctx *gin.Context) {
+	var req service.SearchFilesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	files, total, err := c.fileService.Search(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithPage(ctx, files, total, req.Page, req.PageSize)
+}
+
+// Space 获取空间信息
+func (c *FileController) Space(ctx *gin.Context) {
+	var req service.SpaceInfoRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	info, err := c.fileService.Space(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, info)
+}
+
+// CreateFolder 创建目录
+func (c *FileController) CreateFolder(ctx *gin.Context) {
+	var req service.CreateFolderRequest
+	if !bindFileOperation(ctx, &req, &req.FileOperationBase) {
+		return
+	}
+
+	result, err := c.fileService.CreateFolder(ctx.Request.Context(), &req)
+	fileOperationResponse(ctx, result, err)
+}
+
+// Rename 重命名
+func (c *FileController) Rename(ctx *gin.Context) {
+	var req service.RenameFileRequest
+	if !bindFileOperation(ctx, &req, &req.FileOperationBase) {
+		return
+	}
+
+	result, err := c.fileService.Rename(ctx.Request.Context(), &req)
+	fileOperationResponse(ctx, result, err)
+}
+
+// BatchRename 批量重命名
+func (c *FileController) BatchRename(ctx *gin.Context) {
+	var req service.BatchRenameRequest
+	if !bindFileOperation(ctx, &req, &req.FileOperationBase) {
+		return
+	}
+
+	result, err := c.fileService.BatchRename(ctx.Request.Context(), &req)
+	fileOperationResponse(ctx, result, err)
+}
+
+// Move 移动
+func (c *FileController) Move(ctx *gin.Context) {
+	var req service.MoveFilesRequest
+	if !bindFileOperation(ctx, &req, &req.FileOperationBase) {
+		return
+	}
+
+	result, err := c.fileService.Move(ctx.Request.Context(), &req)
+	fileOperationResponse(ctx, result, err)
+}
+
+// Copy 复制
+func (c *FileController) Copy(ctx *gin.Context) {
+	var req service.CopyFilesRequest
+	if !bindFileOperation(ctx, &req, &req.FileOperationBase) {
+		return
+	}
+
+	result, err := c.fileService.Copy(ctx.Request.Context(), &req)
+	fileOperationResponse(ctx, result, err)
+}
+
+// Delete 删除，永久删除需要额外权限和确认令牌
+func (c *FileController) Delete(ctx *gin.Context) {
+	var req service.DeleteFilesRequest
+	if !bindFileOperation(ctx, &req, &req.FileOperationBase) {
+		return
+	}
+
+	if req.Permanent && !model.HasPermission(ctx.GetString("role"), model.PermissionFileDeletePermanently) {
+		response.Forbidden(ctx, "没有永久删除权限")
+		return
+	}
+
+	result, err := c.fileService.Delete(ctx.Request.Context(), &req)
+	fileOperationResponse(ctx, result, err)
+}
+
+// bindFileOperation 绑定文件操作请求，dry_run 也可以通过查询参数指定
+func bindFileOperation(ctx *gin.Context, req any, base *service.FileOperationBase) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return false
+	}
+
+	if dryRun, err := strconv.ParseBool(ctx.Query("dry_run")); err == nil && dryRun {
+		base.DryRun = true
+	}
+	if base.Instance == "" {
+		base.Instance = ctx.Query("instance")
+	}
+	return true
+}
+
+// fileOperationResponse 输出文件操作结果
+func fileOperationResponse(ctx *gin.Context, result *service.FileOperationResult, err error) {
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	if result.DryRun {
+		response.SuccessWithMsg(ctx, "试运行完成，未做任何修改", result)
+		return
+	}
+	response.SuccessWithMsg(ctx, "操作成功", result)
+}
+
+// cd2Error 将CD2调用错误转换为对应的HTTP响应，服务层的 NotFoundError 统一返回404
+func cd2Error(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+
+	var notFound *service.NotFoundError
+	if errors.Is(err, cd2.ErrInstanceNotFound) || errors.As(err, &notFound) {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrConfirmRequired) {
+		response.Forbidden(ctx, err.Error())
+		return
+	}
+
+	var opErr *service.FileOperationError
+	if errors.As(err, &opErr) {
+		msg := strings.ToLower(opErr.Message)
+		switch {
+		case containsAny(msg, "not found", "not exist", "no such", "不存在", "找不到"):
+			response.NotFound(ctx, opErr.Error())
+		case containsAny(msg, "already exist", "exists", "已存在"):
+			response.Conflict(ctx, opErr.Error())
+		case containsAny(msg, "permission", "denied", "forbidden", "权限"):
+			response.Forbidden(ctx, opErr.Error())
+		default:
+			response.BadRequest(ctx, opErr.Error())
+		}
+		return
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		response.NotFound(ctx, st.Message())
+	case codes.InvalidArgument, codes.AlreadyExists, codes.FailedPrecondition, codes.OutOfRange:
+		response.BadRequest(ctx, st.Message())
+	case codes.PermissionDenied:
+		response.Forbidden(ctx, st.Message())
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Unauthenticated:
+		response.BadGateway(ctx, "CD2调用失败: "+st.Message())
+	default:
+		response.ServerError(ctx, "CD2调用失败: "+st.Message())
+	}
+}
+
+// containsAny 判断字符串是否包含任一子串
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// FileIndexController 文件索引控制器
+type FileIndexController struct {
+	fileIndexService service.FileIndexService
+}
+
+// NewFileIndexController 创建文件索引控制器
+func NewFileIndexController() *FileIndexController {
+	return &FileIndexController{
+		fileIndexService: service.FileIndexService{},
+	}
+}
+
+// Search 在本地索引中按文件名、通配符、大小和修改时间搜索文件
+func (c *FileIndexController) Search(ctx *gin.Context) {
+	var req service.IndexSearchRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := c.fileIndexService.Search(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+// Roots 获取已建立索引的目录及扫描状态
+func (c *FileIndexController) Roots(ctx *gin.Context) {
+	roots, err := c.fileIndexService.Roots(ctx.Request.Context())
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, roots)
+}
+
+// Scan 添加索引目录或重新扫描
+func (c *FileIndexController) Scan(ctx *gin.Context) {
+	var req service.IndexRootRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetIndex, req.Path)
+	root, err := c.fileIndexService.Scan(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "已开始扫描", root)
+}
+
+// RemoveRoot 删除索引目录及其下的索引
+func (c *FileIndexController) RemoveRoot(ctx *gin.Context) {
+	var req service.IndexRootRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetIndex, req.Path)
+	if err := c.fileIndexService.RemoveRoot(ctx.Request.Context(), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "删除成功", nil)
+}
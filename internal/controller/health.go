@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/service"
+)
+
+// HealthController 健康检查控制器
+type HealthController struct {
+	healthService service.HealthService
+}
+
+// NewHealthController 创建健康检查控制器
+func NewHealthController() *HealthController {
+	return &HealthController{
+		healthService: service.HealthService{},
+	}
+}
+
+// Live 存活检查，用于判断进程是否需要重启
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.healthService.Live())
+}
+
+// Ready 就绪检查，关键组件（数据库、CD2实例）异常时返回503
+func (c *HealthController) Ready(ctx *gin.Context) {
+	report := c.healthService.Ready(ctx.Request.Context())
+
+	code := http.StatusOK
+	if report.Status == service.HealthDown {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, report)
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// LogController 运行日志控制器
+type LogController struct {
+	logService service.LogService
+}
+
+// NewLogController 创建运行日志控制器
+func NewLogController() *LogController {
+	return &LogController{
+		logService: service.LogService{},
+	}
+}
+
+// SetLogLevelRequest 修改日志级别请求
+type SetLogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error"`
+}
+
+// GetLevel 获取当前日志级别
+func (c *LogController) GetLevel(ctx *gin.Context) {
+	response.Success(ctx, gin.H{"level": c.logService.GetLevel()})
+}
+
+// SetLevel 运行中修改日志级别，重启后恢复为配置文件中的级别
+func (c *LogController) SetLevel(ctx *gin.Context) {
+	var req SetLogLevelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	before := gin.H{"level": c.logService.GetLevel()}
+	if err := c.logService.SetLevel(req.Level); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+	after := gin.H{"level": c.logService.GetLevel()}
+	middleware.SetAuditSnapshot(ctx, before, after)
+
+	response.SuccessWithMsg(ctx, "日志级别已修改", after)
+}
+
+// Query 按级别、时间范围、请求ID和关键字查找日志
+func (c *LogController) Query(ctx *gin.Context) {
+	var query service.LogQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	entries, err := c.logService.Query(&query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, entries)
+}
+
+// Stream 以 Server-Sent Events 推送新写入的日志，事件名为 log
+func (c *LogController) Stream(ctx *gin.Context) {
+	var query service.LogQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 先返回响应头，没有新日志时客户端也能确认连接已建立
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Writer.WriteHeaderNow()
+	ctx.Writer.Flush()
+
+	c.logService.Stream(ctx.Request.Context(), &query, func(entry service.LogEntry) {
+		ctx.SSEvent("log", entry)
+		ctx.Writer.Flush()
+	})
+}
+
+// CD2Files 列出CD2实例的日志文件
+func (c *LogController) CD2Files(ctx *gin.Context) {
+	files, err := c.logService.CD2LogFiles(ctx.Request.Context(), ctx.Query("instance"))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, files)
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// MigrationController 跨云盘迁移控制器
+type MigrationController struct {
+	migrationService service.MigrationService
+}
+
+// NewMigrationController 创建跨云盘迁移控制器
+func NewMigrationController() *MigrationController {
+	return &MigrationController{
+		migrationService: service.MigrationService{},
+	}
+}
+
+// Create 创建迁移任务，dry_run 时只返回迁移计划
+func (c *MigrationController) Create(ctx *gin.Context) {
+	var req service.CreateMigrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	job, plan, err := c.migrationService.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	if plan != nil {
+		response.Success(ctx, plan)
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetMigration, strconv.FormatUint(uint64(job.ID), 10))
+	response.SuccessWithMsg(ctx, "迁移任务已创建", job)
+}
+
+// List 查询迁移任务
+func (c *MigrationController) List(ctx *gin.Context) {
+	var query service.MigrationQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	jobs, total, err := c.migrationService.List(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, jobs, total, query.Page, query.PageSize)
+}
+
+// Get 获取迁移任务详情
+func (c *MigrationController) Get(ctx *gin.Context) {
+	id, ok := migrationID(ctx)
+	if !ok {
+		return
+	}
+
+	job, err := c.migrationService.Get(ctx.Request.Context(), id)
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, job)
+}
+
+// ListItems 查询迁移任务的文件
+func (c *MigrationController) ListItems(ctx *gin.Context) {
+	id, ok := migrationID(ctx)
+	if !ok {
+		return
+	}
+
+	var query service.MigrationItemQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	items, total, err := c.migrationService.ListItems(ctx.Request.Context(), id, &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, items, total, query.Page, query.PageSize)
+}
+
+// Report 获取迁移报告
+func (c *MigrationController) Report(ctx *gin.Context) {
+	id, ok := migrationID(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.migrationService.Report(ctx.Request.Context(), id)
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, report)
+}
+
+// Cancel 取消迁移任务
+func (c *MigrationController) Cancel(ctx *gin.Context) {
+	id, ok := migrationID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.migrationService.Cancel(id); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "正在取消", nil)
+}
+
+// Resume 恢复迁移任务
+func (c *MigrationController) Resume(ctx *gin.Context) {
+	id, ok := migrationID(ctx)
+	if !ok {
+		return
+	}
+
+	job, err := c.migrationService.Resume(ctx.Request.Context(), id)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "迁移任务已恢复", job)
+}
+
+// migrationID 解析路径中的任务ID
+func migrationID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "任务ID错误")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// MountController 挂载点管理控制器
+type MountController struct {
+	mountService service.MountService
+}
+
+// NewMountController 创建挂载点管理控制器
+func NewMountController() *MountController {
+	return &MountController{
+		mountService: service.MountService{},
+	}
+}
+
+// List 获取挂载点列表
+func (c *MountController) List(ctx *gin.Context) {
+	mounts, err := c.mountService.List(ctx.Request.Context(), ctx.Query("instance"))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, mounts)
+}
+
+// Health 获取挂载点健康检查状态
+func (c *MountController) Health(ctx *gin.Context) {
+	response.Success(ctx, service.MountHealthList())
+}
+
+// Capacity 检查是否还能添加挂载点
+func (c *MountController) Capacity(ctx *gin.Context) {
+	can, reason, err := c.mountService.CanAddMore(ctx.Request.Context(), ctx.Query("instance"))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"can_add_more": can,
+		"reason":       reason,
+	})
+}
+
+// Add 添加挂载点
+func (c *MountController) Add(ctx *gin.Context) {
+	var req service.MountOptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetMount, req.MountPoint)
+	if err := c.mountService.Add(ctx.Request.Context(), ctx.Query("instance"), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "添加成功", nil)
+}
+
+// Update 更新挂载点选项
+func (c *MountController) Update(ctx *gin.Context) {
+	var req service.UpdateMountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetMount, req.MountPoint)
+	if err := c.mountService.Update(ctx.Request.Context(), ctx.Query("instance"), &req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "更新成功", nil)
+}
+
+// Mount 挂载
+func (c *MountController) Mount(ctx *gin.Context) {
+	var req service.MountPointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetMount, req.MountPoint)
+	if err := c.mountService.Mount(ctx.Request.Context(), ctx.Query("instance"), req.MountPoint); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "挂载成功", nil)
+}
+
+// Unmount 卸载
+func (c *MountController) Unmount(ctx *gin.Context) {
+	var req service.MountPointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetMount, req.MountPoint)
+	if err := c.mountService.Unmount(ctx.Request.Context(), ctx.Query("instance"), req.MountPoint); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "卸载成功", nil)
+}
+
+// Remove 删除挂载点
+func (c *MountController) Remove(ctx *gin.Context) {
+	var req service.MountPointRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetMount, req.MountPoint)
+	if err := c.mountService.Remove(ctx.Request.Context(), ctx.Query("instance"), req.MountPoint); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "删除成功", nil)
+}
+
+// Reconcile 按配置调整挂载点，不指定实例时调整所有实例
+func (c *MountController) Reconcile(ctx *gin.Context) {
+	if instance := ctx.Query("instance"); instance != "" {
+		response.Success(ctx, []service.ReconcileResult{c.mountService.Reconcile(ctx.Request.Context(), instance)})
+		return
+	}
+
+	response.Success(ctx, c.mountService.ReconcileAll(ctx.Request.Context()))
+}
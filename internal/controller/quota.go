@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// QuotaController 云盘空间监控控制器
+type QuotaController struct {
+	quotaService service.QuotaService
+}
+
+// NewQuotaController 创建云盘空间监控控制器
+func NewQuotaController() *QuotaController {
+	return &QuotaController{
+		quotaService: service.QuotaService{},
+	}
+}
+
+// Status 获取所有云盘的当前空间、增长预测和告警
+func (c *QuotaController) Status(ctx *gin.Context) {
+	list, err := c.quotaService.Status(ctx.Request.Context())
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, list)
+}
+
+// History 获取云盘的空间采样
+func (c *QuotaController) History(ctx *gin.Context) {
+	var query service.SpaceHistoryQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	samples, err := c.quotaService.History(ctx.Request.Context(), &query)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, samples)
+}
+
+// Collect 立即采集空间，不指定实例时采集所有实例
+func (c *QuotaController) Collect(ctx *gin.Context) {
+	list, err := c.quotaService.Collect(ctx.Request.Context(), ctx.Query("instance"))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, list)
+}
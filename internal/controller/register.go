@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// RegisterController 注册管理控制器
+type RegisterController struct {
+	registerService service.RegisterService
+}
+
+// NewRegisterController 创建注册管理控制器
+func NewRegisterController() *RegisterController {
+	return &RegisterController{
+		registerService: service.RegisterService{},
+	}
+}
+
+// GetSettings 获取注册设置
+func (c *RegisterController) GetSettings(ctx *gin.Context) {
+	response.Success(ctx, c.registerService.GetSettings())
+}
+
+// UpdateSettings 更新注册设置
+func (c *RegisterController) UpdateSettings(ctx *gin.Context) {
+	var req service.RegisterSettings
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	before := c.registerService.GetSettings()
+	if err := c.registerService.UpdateSettings(ctx.Request.Context(), &req); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+	middleware.SetAuditSnapshot(ctx, before, c.registerService.GetSettings())
+
+	response.SuccessWithMsg(ctx, "更新成功", c.registerService.GetSettings())
+}
+
+// ListInviteCodes 获取邀请码列表
+func (c *RegisterController) ListInviteCodes(ctx *gin.Context) {
+	var page service.PageRequest
+	if err := ctx.ShouldBindQuery(&page); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	codes, total, err := c.registerService.ListInviteCodes(ctx.Request.Context(), &page)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, codes, total, page.Page, page.PageSize)
+}
+
+// CreateInviteCode 创建邀请码
+func (c *RegisterController) CreateInviteCode(ctx *gin.Context) {
+	userID, _ := ctx.Get("user_id")
+
+	var req service.CreateInviteCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	code, err := c.registerService.CreateInviteCode(ctx.Request.Context(), userID.(uint), &req)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+	middleware.SetAuditSnapshot(ctx, nil, code)
+
+	response.SuccessWithMsg(ctx, "创建成功", code)
+}
+
+// DeleteInviteCode 删除邀请码
+func (c *RegisterController) DeleteInviteCode(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "邀请码ID错误")
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetInvite, ctx.Param("id"))
+	if err := c.registerService.DeleteInviteCode(ctx.Request.Context(), uint(id)); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "删除成功", nil)
+}
+
+// VerifyEmail 验证邮箱
+func (c *RegisterController) VerifyEmail(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		response.BadRequest(ctx, "缺少验证令牌")
+		return
+	}
+
+	if err := c.registerService.VerifyEmail(ctx.Request.Context(), token); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "邮箱验证成功", nil)
+}
+
+// ResendVerification 重新发送验证邮件
+func (c *RegisterController) ResendVerification(ctx *gin.Context) {
+	var req service.ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := c.registerService.ResendVerification(ctx.Request.Context(), &req); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "如果该邮箱已注册且尚未验证，验证邮件将发送到该邮箱", nil)
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// RenamerController 批量重命名控制器
+type RenamerController struct {
+	renamerService service.RenamerService
+}
+
+// NewRenamerController 创建批量重命名控制器
+func NewRenamerController() *RenamerController {
+	return &RenamerController{
+		renamerService: service.RenamerService{},
+	}
+}
+
+// Preview 预览重命名结果
+func (c *RenamerController) Preview(ctx *gin.Context) {
+	var req service.RenamePreviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	preview, err := c.renamerService.Preview(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, preview)
+}
+
+// Apply 执行重命名
+func (c *RenamerController) Apply(ctx *gin.Context) {
+	var req service.RenameApplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	batch, err := c.renamerService.Apply(ctx.Request.Context(), &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetRename, strconv.FormatUint(uint64(batch.ID), 10))
+	response.SuccessWithMsg(ctx, "重命名完成", batch)
+}
+
+// ListBatches 查询重命名记录
+func (c *RenamerController) ListBatches(ctx *gin.Context) {
+	var query service.RenameBatchQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	batches, total, err := c.renamerService.ListBatches(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, batches, total, query.Page, query.PageSize)
+}
+
+// GetBatch 获取重命名记录详情
+func (c *RenamerController) GetBatch(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "记录ID错误")
+		return
+	}
+
+	batch, err := c.renamerService.GetBatch(ctx.Request.Context(), uint(id))
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, batch)
+}
+
+// Undo 撤销重命名
+func (c *RenamerController) Undo(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "记录ID错误")
+		return
+	}
+
+	batch, err := c.renamerService.Undo(ctx.Request.Context(), uint(id))
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "撤销完成", batch)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/config"
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// SettingController 配置项控制器
+type SettingController struct {
+	settingService service.SettingService
+}
+
+// NewSettingController 创建配置项控制器
+func NewSettingController() *SettingController {
+	return &SettingController{
+		settingService: service.SettingService{},
+	}
+}
+
+// List 获取可以在线修改的配置项及其当前值
+func (c *SettingController) List(ctx *gin.Context) {
+	items, err := c.settingService.List(ctx.Request.Context())
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, items)
+}
+
+// Update 修改配置项，保存到数据库后立即生效，值为 null 时恢复为配置文件中的值
+func (c *SettingController) Update(ctx *gin.Context) {
+	var req service.UpdateSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	keys := make([]string, 0, len(req.Values))
+	for key := range req.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	middleware.SetAuditTarget(ctx, model.AuditTargetSetting, strings.Join(keys, ","))
+
+	before := c.settingService.Snapshot(keys)
+	changed, err := c.settingService.Update(ctx.Request.Context(), &req)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+	middleware.SetAuditSnapshot(ctx, before, c.settingService.Snapshot(keys))
+
+	response.SuccessWithMsg(ctx, "配置已更新", gin.H{
+		"changed":          changed,
+		"restart_required": config.RestartRequired(changed),
+	})
+}
+
+// History 查询配置项修改记录
+func (c *SettingController) History(ctx *gin.Context) {
+	var query service.SettingHistoryQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	records, total, err := c.settingService.History(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, records, total, query.Page, query.PageSize)
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// TransferController 传输任务控制器
+type TransferController struct {
+	transferService service.TransferService
+}
+
+// NewTransferController 创建传输任务控制器
+func NewTransferController() *TransferController {
+	return &TransferController{
+		transferService: service.TransferService{},
+	}
+}
+
+// ListUploads 上传任务列表
+func (c *TransferController) ListUploads(ctx *gin.Context) {
+	c.list(ctx, c.transferService.ListUploads)
+}
+
+// ListDownloads 下载任务列表
+func (c *TransferController) ListDownloads(ctx *gin.Context) {
+	c.list(ctx, c.transferService.ListDownloads)
+}
+
+// ListCopyTasks 复制任务列表
+func (c *TransferController) ListCopyTasks(ctx *gin.Context) {
+	c.list(ctx, c.transferService.ListCopyTasks)
+}
+
+// PauseUploads 暂停上传任务
+func (c *TransferController) PauseUploads(ctx *gin.Context) {
+	c.uploadAction(ctx, service.UploadActionPause)
+}
+
+// ResumeUploads 继续上传任务
+func (c *TransferController) ResumeUploads(ctx *gin.Context) {
+	c.uploadAction(ctx, service.UploadActionResume)
+}
+
+// CancelUploads 取消上传任务
+func (c *TransferController) CancelUploads(ctx *gin.Context) {
+	c.uploadAction(ctx, service.UploadActionCancel)
+}
+
+// PauseCopyTask 暂停复制任务
+func (c *TransferController) PauseCopyTask(ctx *gin.Context) {
+	c.copyTaskAction(ctx, "已暂停", func(req *service.CopyTaskActionRequest) error {
+		return c.transferService.PauseCopyTask(ctx.Request.Context(), req, true)
+	})
+}
+
+// ResumeCopyTask 继续复制任务
+func (c *TransferController) ResumeCopyTask(ctx *gin.Context) {
+	c.copyTaskAction(ctx, "已继续", func(req *service.CopyTaskActionRequest) error {
+		return c.transferService.PauseCopyTask(ctx.Request.Context(), req, false)
+	})
+}
+
+// CancelCopyTask 取消复制任务
+func (c *TransferController) CancelCopyTask(ctx *gin.Context) {
+	c.copyTaskAction(ctx, "已取消", func(req *service.CopyTaskActionRequest) error {
+		return c.transferService.CancelCopyTask(ctx.Request.Context(), req)
+	})
+}
+
+// RestartCopyTask 重新开始复制任务
+func (c *TransferController) RestartCopyTask(ctx *gin.Context) {
+	c.copyTaskAction(ctx, "已重新开始", func(req *service.CopyTaskActionRequest) error {
+		return c.transferService.RestartCopyTask(ctx.Request.Context(), req)
+	})
+}
+
+// RemoveCompletedCopyTasks 清除已完成的复制任务
+func (c *TransferController) RemoveCompletedCopyTasks(ctx *gin.Context) {
+	instance := ctx.Query("instance")
+	middleware.SetAuditTarget(ctx, model.AuditTargetCD2, instance)
+
+	results, err := c.transferService.RemoveCompletedCopyTasks(ctx.Request.Context(), instance)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, results)
+}
+
+// list 查询传输任务
+func (c *TransferController) list(ctx *gin.Context, fn func(context.Context, *service.TransferQuery) (*service.TransferList, error)) {
+	var query service.TransferQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	list, err := fn(ctx.Request.Context(), &query)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, list)
+}
+
+// uploadAction 执行上传任务操作
+func (c *TransferController) uploadAction(ctx *gin.Context, action string) {
+	var req service.UploadActionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetCD2, req.Instance)
+	results, err := c.transferService.UploadAction(ctx.Request.Context(), action, &req)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, results)
+}
+
+// copyTaskAction 执行复制任务操作
+func (c *TransferController) copyTaskAction(ctx *gin.Context, msg string, fn func(req *service.CopyTaskActionRequest) error) {
+	var req service.CopyTaskActionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetCD2Path, req.SourcePath+" -> "+req.DestPath)
+	if err := fn(&req); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, msg, nil)
+}
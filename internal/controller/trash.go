@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/response"
+)
+
+// TrashController 回收站控制器
+type TrashController struct {
+	trashService service.TrashService
+}
+
+// NewTrashController 创建回收站控制器
+func NewTrashController() *TrashController {
+	return &TrashController{
+		trashService: service.TrashService{},
+	}
+}
+
+// List 查询回收站中的文件
+func (c *TrashController) List(ctx *gin.Context) {
+	var query service.TrashQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	items, total, err := c.trashService.List(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, items, total, query.Page, query.PageSize)
+}
+
+// Restore 将文件恢复到原位置
+func (c *TrashController) Restore(ctx *gin.Context) {
+	id, ok := trashID(ctx)
+	if !ok {
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetTrash, strconv.FormatUint(uint64(id), 10))
+	item, err := c.trashService.Restore(ctx.Request.Context(), id)
+	if err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "恢复成功", item)
+}
+
+// Purge 立即永久删除回收站中的文件
+func (c *TrashController) Purge(ctx *gin.Context) {
+	id, ok := trashID(ctx)
+	if !ok {
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetTrash, strconv.FormatUint(uint64(id), 10))
+	if err := c.trashService.Purge(ctx.Request.Context(), id); err != nil {
+		cd2Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "已永久删除", nil)
+}
+
+// trashID 解析路径中的回收站记录ID
+func trashID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "记录ID错误")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -1,8 +1,13 @@
 package controller
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 
+	"cinexus/internal/middleware"
+	"cinexus/internal/model"
 	"cinexus/internal/service"
 	"cinexus/pkg/response"
 )
@@ -27,7 +32,7 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.userService.Login(&req)
+	resp, err := c.userService.Login(ctx.Request.Context(), &req)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
@@ -44,12 +49,21 @@ func (c *UserController) Register(ctx *gin.Context) {
 		return
 	}
 
-	err := c.userService.Register(&req)
+	user, err := c.userService.Register(ctx.Request.Context(), &req)
+	if errors.Is(err, service.ErrVerificationMailFailed) {
+		response.SuccessWithMsg(ctx, err.Error(), nil)
+		return
+	}
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
 	}
 
+	if user.Status == model.UserStatusPending {
+		response.SuccessWithMsg(ctx, "注册成功，请查收验证邮件", nil)
+		return
+	}
+
 	response.SuccessWithMsg(ctx, "注册成功", nil)
 }
 
@@ -57,7 +71,7 @@ func (c *UserController) Register(ctx *gin.Context) {
 func (c *UserController) GetUserInfo(ctx *gin.Context) {
 	userID, _ := ctx.Get("user_id")
 
-	user, err := c.userService.GetUserByID(userID.(uint))
+	user, err := c.userService.GetUserByID(ctx.Request.Context(), userID.(uint))
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
@@ -76,12 +90,18 @@ func (c *UserController) UpdateUserInfo(ctx *gin.Context) {
 		return
 	}
 
-	err := c.userService.UpdateUser(userID.(uint), &req)
+	before, _ := c.userService.GetUserByID(ctx.Request.Context(), userID.(uint))
+	middleware.SetAuditTarget(ctx, model.AuditTargetUser, strconv.FormatUint(uint64(userID.(uint)), 10))
+
+	err := c.userService.UpdateUser(ctx.Request.Context(), userID.(uint), &req)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
 	}
 
+	after, _ := c.userService.GetUserByID(ctx.Request.Context(), userID.(uint))
+	middleware.SetAuditSnapshot(ctx, before, after)
+
 	response.SuccessWithMsg(ctx, "更新成功", nil)
 }
 
@@ -95,7 +115,9 @@ func (c *UserController) UpdatePassword(ctx *gin.Context) {
 		return
 	}
 
-	err := c.userService.UpdatePassword(userID.(uint), &req)
+	middleware.SetAuditTarget(ctx, model.AuditTargetUser, strconv.FormatUint(uint64(userID.(uint)), 10))
+
+	err := c.userService.UpdatePassword(ctx.Request.Context(), userID.(uint), &req)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
@@ -103,3 +125,50 @@ func (c *UserController) UpdatePassword(ctx *gin.Context) {
 
 	response.SuccessWithMsg(ctx, "密码更新成功", nil)
 }
+
+// ListUsers 获取用户列表
+func (c *UserController) ListUsers(ctx *gin.Context) {
+	var query service.UserQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	users, total, err := c.userService.ListUsers(ctx.Request.Context(), &query)
+	if err != nil {
+		response.ServerError(ctx, err.Error())
+		return
+	}
+
+	response.SuccessWithPage(ctx, users, total, query.Page, query.PageSize)
+}
+
+// SetRole 修改用户角色，授予或收回 editor 等角色
+func (c *UserController) SetRole(ctx *gin.Context) {
+	userID, _ := ctx.Get("user_id")
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "用户ID错误")
+		return
+	}
+
+	var req service.SetRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(ctx, model.AuditTargetUser, ctx.Param("id"))
+	before, _ := c.userService.GetUserByID(ctx.Request.Context(), uint(id))
+
+	if err := c.userService.SetRole(ctx.Request.Context(), userID.(uint), uint(id), req.Role); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	after, _ := c.userService.GetUserByID(ctx.Request.Context(), uint(id))
+	middleware.SetAuditSnapshot(ctx, before, after)
+
+	response.SuccessWithMsg(ctx, "角色已修改，用户重新登录后生效", after)
+}
@@ -15,6 +15,8 @@ import (
 
 	"cinexus/config"
 	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/tracing"
 )
 
 // DB 全局数据库连接
@@ -24,31 +26,32 @@ var DB *gorm.DB
 func Init() error {
 	var err error
 	var dialector gorm.Dialector
+	conf := config.Get().Database
 
-	switch config.Conf.Database.Type {
+	switch conf.Type {
 	case "mysql":
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			config.Conf.Database.User,
-			config.Conf.Database.Password,
-			config.Conf.Database.Host,
-			config.Conf.Database.Port,
-			config.Conf.Database.Name)
+			conf.User,
+			conf.Password,
+			conf.Host,
+			conf.Port,
+			conf.Name)
 		dialector = mysql.Open(dsn)
 	case "sqlite":
 		// 确保SQLite数据库目录存在
-		dbDir := filepath.Dir(config.Conf.Database.SQLitePath)
+		dbDir := filepath.Dir(conf.SQLitePath)
 		if err := os.MkdirAll(dbDir, 0755); err != nil {
 			return fmt.Errorf("创建SQLite数据库目录失败: %w", err)
 		}
-		dialector = sqlite.Open(config.Conf.Database.SQLitePath)
+		dialector = sqlite.Open(conf.SQLitePath)
 	default:
-		return fmt.Errorf("不支持的数据库类型: %s", config.Conf.Database.Type)
+		return fmt.Errorf("不支持的数据库类型: %s", conf.Type)
 	}
 
 	// 配置GORM
 	gormConfig := &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
-			TablePrefix:   config.Conf.Database.TablePrefix,
+			TablePrefix:   conf.TablePrefix,
 			SingularTable: true,
 		},
 		Logger: gormlogger.New(
@@ -74,13 +77,46 @@ func Init() error {
 		return fmt.Errorf("获取数据库连接失败: %w", err)
 	}
 
-	sqlDB.SetMaxIdleConns(config.Conf.Database.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(config.Conf.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
+	if tracing.Enabled() {
+		if err := DB.Use(newTracingPlugin(conf.Type)); err != nil {
+			return fmt.Errorf("注册数据库追踪插件失败: %w", err)
+		}
+	}
+
+	if err := metrics.RegisterDB(sqlDB, conf.Type); err != nil {
+		logger.Warn("注册数据库连接池指标失败", zap.Error(err))
+	}
+
+	config.OnChange("database", onConfigChange)
+
 	return nil
 }
 
+// onConfigChange 连接池大小修改后立即生效，连接参数修改需要重启
+func onConfigChange(old, new *config.Config) {
+	if new.Database.MaxIdleConns != old.Database.MaxIdleConns || new.Database.MaxOpenConns != old.Database.MaxOpenConns {
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.SetMaxIdleConns(new.Database.MaxIdleConns)
+			sqlDB.SetMaxOpenConns(new.Database.MaxOpenConns)
+			logger.Info("数据库连接池配置已更新",
+				zap.Int("max_idle_conns", new.Database.MaxIdleConns),
+				zap.Int("max_open_conns", new.Database.MaxOpenConns),
+			)
+		}
+	}
+
+	oldConn, newConn := old.Database, new.Database
+	oldConn.MaxIdleConns, oldConn.MaxOpenConns = 0, 0
+	newConn.MaxIdleConns, newConn.MaxOpenConns = 0, 0
+	if oldConn != newConn {
+		logger.Warn("数据库连接配置修改后需要重启才能生效")
+	}
+}
+
 // Close 关闭数据库连接
 func Close() error {
 	if DB == nil {
@@ -106,7 +142,7 @@ func (w *GormLogWriter) Printf(format string, args ...interface{}) {
 
 // 根据配置获取GORM日志级别
 func getGormLogLevel() gormlogger.LogLevel {
-	switch config.Conf.Log.Level {
+	switch config.Get().Log.Level {
 	case "debug":
 		return gormlogger.Info
 	case "info":
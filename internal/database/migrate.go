@@ -0,0 +1,255 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cinexus/internal/model"
+	"cinexus/pkg/logger"
+)
+
+// Migration 一个版本的数据库迁移，Up 或 Down 与版本记录在同一事务中执行
+// MySQL 的 DDL 会隐式提交事务，出错时不会回滚，迁移中的结构变更应先检查再执行（如 HasColumn）
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // 为空表示不可回滚
+}
+
+// MigrationStatus 迁移版本的执行状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // 为空表示未执行
+	Unknown   bool       // 数据库中已执行但当前程序中不存在的版本，通常是数据库已被更新版本的程序迁移过
+}
+
+// Migrate 按版本顺序执行未执行的迁移，再以 AutoMigrate 同步模型中新增的表和字段，返回本次执行的迁移
+// 全新的数据库直接按模型创建表结构，并将所有迁移标记为已执行
+func Migrate() ([]Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// 以 user 表判断是否为全新的数据库，引入版本迁移之前创建的数据库从第一个版本开始执行
+	if len(applied) == 0 && !DB.Migrator().HasTable(&model.User{}) {
+		if err := DB.AutoMigrate(models...); err != nil {
+			return nil, err
+		}
+		records := make([]model.SchemaMigration, len(migrations))
+		for i, m := range migrations {
+			records[i] = model.SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		}
+		if err := DB.Create(&records).Error; err != nil {
+			return nil, err
+		}
+		logger.Info("已按模型创建数据库表结构", zap.Int("migrations", len(migrations)))
+		return nil, nil
+	}
+
+	if err := checkUnknown(applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := runMigration(m, true); err != nil {
+			return done, fmt.Errorf("执行迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+
+	if err := DB.AutoMigrate(models...); err != nil {
+		return done, err
+	}
+	return done, nil
+}
+
+// Rollback 按版本倒序回滚最近执行的 steps 个迁移，返回已回滚的迁移
+// 第一个版本（baseline）是回滚的下限，不会被回滚，steps 超出时回滚到 baseline 为止
+func Rollback(steps int) ([]Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	var done []Migration
+	for i := 0; i < steps && i < len(versions); i++ {
+		if len(migrations) > 0 && versions[i] <= migrations[0].Version {
+			break
+		}
+		m, ok := findMigration(versions[i])
+		if !ok {
+			return done, fmt.Errorf("版本 %d 不在当前程序中，无法回滚", versions[i])
+		}
+		if m.Down == nil {
+			return done, fmt.Errorf("迁移 %d_%s 不可回滚", m.Version, m.Name)
+		}
+		if err := runMigration(m, false); err != nil {
+			return done, fmt.Errorf("回滚迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrationStatuses 列出所有迁移版本及执行状态，包括数据库中存在但当前程序中没有的版本
+func MigrationStatuses() ([]MigrationStatus, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if _, ok := findMigration(version); ok {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: record.Name, AppliedAt: &appliedAt, Unknown: true})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// PendingMigrations 返回未执行的迁移，全新的数据库返回所有迁移
+func PendingMigrations() ([]Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnknown(applied); err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// SchemaDrift 检查模型中的表和字段是否都已存在，返回缺少的表和字段
+// 新增表和字段只由 AutoMigrate 同步，不会出现在版本迁移中，关闭自动迁移时需要与未执行的迁移一起检查
+func SchemaDrift() ([]string, error) {
+	migrator := DB.Migrator()
+
+	var missing []string
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: DB}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		if !migrator.HasTable(m) {
+			missing = append(missing, "表 "+stmt.Schema.Table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			if !migrator.HasColumn(m, field.DBName) {
+				missing = append(missing, "字段 "+stmt.Schema.Table+"."+field.DBName)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// appliedMigrations 读取已执行的迁移，版本表不存在时创建
+func appliedMigrations() (map[int64]model.SchemaMigration, error) {
+	if err := checkMigrations(); err != nil {
+		return nil, err
+	}
+	if err := DB.AutoMigrate(&model.SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("创建迁移版本表失败: %w", err)
+	}
+
+	var records []model.SchemaMigration
+	if err := DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]model.SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// checkMigrations 检查迁移列表按版本严格递增
+func checkMigrations() error {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return fmt.Errorf("迁移版本 %d 应大于 %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+	return nil
+}
+
+// checkUnknown 数据库中有当前程序不认识的版本时拒绝继续，避免旧版本程序在新的表结构上运行
+func checkUnknown(applied map[int64]model.SchemaMigration) error {
+	for version, record := range applied {
+		if _, ok := findMigration(version); !ok {
+			return fmt.Errorf("数据库已执行当前程序中没有的迁移 %d_%s，请使用更新版本的程序，或先用其执行 migrate down 回滚", version, record.Name)
+		}
+	}
+	return nil
+}
+
+// findMigration 按版本查找迁移
+func findMigration(version int64) (Migration, bool) {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+// runMigration 在事务中执行迁移并更新版本记录
+func runMigration(m Migration, up bool) error {
+	if up && m.Up == nil {
+		return errors.New("迁移没有 Up")
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if !up {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&model.SchemaMigration{Version: m.Version}).Error
+		}
+
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&model.SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+	})
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"cinexus/config"
+	"cinexus/internal/model"
+	"cinexus/pkg/logger"
+)
+
+// TestMain 初始化配置和日志，数据库由每个测试单独创建
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "cinexus-database-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.toml")
+	content := fmt.Sprintf(`
+[database]
+type = "sqlite"
+sqlite_path = %q
+
+[jwt]
+secret = "test-secret-0123456789abcdef0123456789"
+
+[log]
+level = "error"
+filename = %q
+`, filepath.Join(dir, "test.db"), filepath.Join(dir, "logs", "test.log"))
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := config.Init(file); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := logger.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return m.Run()
+}
+
+// useTestDB 将 DB 替换为临时目录中的SQLite数据库，测试结束后恢复
+func useTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	old := DB
+	DB = db
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		DB = old
+	})
+}
+
+// useMigrations 临时替换迁移列表
+func useMigrations(t *testing.T, list ...Migration) {
+	t.Helper()
+	old := migrations
+	migrations = list
+	t.Cleanup(func() { migrations = old })
+}
+
+// sampleMigration 新增一张表的示例迁移，表结构在迁移内定义
+var sampleMigration = Migration{
+	Version: 2,
+	Name:    "add_sample_note",
+	Up: func(tx *gorm.DB) error {
+		type SampleNote struct {
+			ID      uint `gorm:"primaryKey"`
+			Content string
+		}
+		if tx.Migrator().HasTable("sample_note") {
+			return nil
+		}
+		return tx.Migrator().CreateTable(&SampleNote{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("sample_note")
+	},
+}
+
+// appliedVersions 返回已执行的迁移版本
+func appliedVersions(t *testing.T) map[int64]bool {
+	t.Helper()
+	applied, err := appliedMigrations()
+	if err != nil {
+		t.Fatalf("读取已执行的迁移失败: %v", err)
+	}
+	versions := make(map[int64]bool, len(applied))
+	for version := range applied {
+		versions[version] = true
+	}
+	return versions
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+	useTestDB(t)
+	useMigrations(t, migrations[0])
+
+	// 全新的数据库按模型建表，并将 baseline 标记为已执行
+	done, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(done) != 0 {
+		t.Errorf("全新的数据库不应逐个执行迁移: %v", done)
+	}
+	if v := appliedVersions(t); !v[1] {
+		t.Fatalf("baseline 应标记为已执行: %v", v)
+	}
+
+	// 只有 baseline 时没有可回滚的迁移
+	done, err = Rollback(1)
+	if err != nil {
+		t.Fatalf("回滚到 baseline 不应出错: %v", err)
+	}
+	if len(done) != 0 {
+		t.Errorf("baseline 不应被回滚: %v", done)
+	}
+
+	// 追加迁移后执行新版本
+	useMigrations(t, migrations[0], sampleMigration)
+	done, err = Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(done) != 1 || done[0].Version != sampleMigration.Version {
+		t.Fatalf("应执行迁移 %d，实际执行 %v", sampleMigration.Version, done)
+	}
+	if !DB.Migrator().HasTable("sample_note") {
+		t.Fatal("迁移后 sample_note 表应存在")
+	}
+
+	// 回滚步数超出时回滚到 baseline 为止
+	done, err = Rollback(5)
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if len(done) != 1 || done[0].Version != sampleMigration.Version {
+		t.Fatalf("应回滚迁移 %d，实际回滚 %v", sampleMigration.Version, done)
+	}
+	if DB.Migrator().HasTable("sample_note") {
+		t.Error("回滚后 sample_note 表应被删除")
+	}
+	if v := appliedVersions(t); !v[1] || v[sampleMigration.Version] {
+		t.Errorf("回滚后应只保留 baseline: %v", v)
+	}
+
+	// 再次执行时重新应用
+	done, err = Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(done) != 1 || !DB.Migrator().HasTable("sample_note") {
+		t.Errorf("应重新执行迁移 %d: %v", sampleMigration.Version, done)
+	}
+}
+
+func TestMigrateRejectsUnknownVersion(t *testing.T) {
+	useTestDB(t)
+	useMigrations(t, migrations[0], sampleMigration)
+	if _, err := Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	// 旧版本程序中没有迁移 2，应拒绝执行和回滚
+	useMigrations(t, migrations[0])
+	if _, err := Migrate(); err == nil {
+		t.Error("数据库中有未知版本时 Migrate 应返回错误")
+	}
+	if _, err := Rollback(1); err == nil {
+		t.Error("未知版本不应被回滚")
+	}
+}
+
+func TestSchemaDrift(t *testing.T) {
+	useTestDB(t)
+	useMigrations(t, migrations[0])
+	if _, err := Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	missing, err := SchemaDrift()
+	if err != nil {
+		t.Fatalf("SchemaDrift: %v", err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("迁移后不应缺少表或字段: %v", missing)
+	}
+
+	// 模拟新版本程序在模型中新增了表和字段
+	if err := DB.Migrator().DropColumn(&model.Setting{}, "updated_by"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Migrator().DropTable(&model.TrashItem{}); err != nil {
+		t.Fatal(err)
+	}
+	missing, err = SchemaDrift()
+	if err != nil {
+		t.Fatalf("SchemaDrift: %v", err)
+	}
+	want := []string{"字段 setting.updated_by", "表 trash_item"}
+	sort.Strings(missing)
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("SchemaDrift = %v，应为 %v", missing, want)
+	}
+
+	// migrate up 中的 AutoMigrate 补齐缺少的表和字段
+	if _, err := Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if missing, err := SchemaDrift(); err != nil || len(missing) != 0 {
+		t.Errorf("Migrate 后 SchemaDrift = %v, %v", missing, err)
+	}
+}
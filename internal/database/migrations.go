@@ -0,0 +1,45 @@
+package database
+
+import (
+	"gorm.io/gorm"
+
+	"cinexus/internal/model"
+)
+
+// models 由 AutoMigrate 同步表结构的模型，新增表和字段只需加入或修改模型，
+// 关闭自动迁移时启动前由 SchemaDrift 检查这些表和字段是否存在
+// 重命名字段、迁移数据、删除表或字段等 AutoMigrate 无法完成的变更需要在 migrations 中追加新版本
+var models = []any{
+	&model.User{},
+	&model.InviteCode{},
+	&model.EmailVerification{},
+	&model.AuditLog{},
+	&model.RenameBatch{},
+	&model.RenameRecord{},
+	&model.MigrationJob{},
+	&model.MigrationItem{},
+	&model.BackupPolicy{},
+	&model.BackupStatusLog{},
+	&model.SpaceSample{},
+	&model.DedupeScan{},
+	&model.DedupeGroup{},
+	&model.DedupeFile{},
+	&model.FileIndex{},
+	&model.FileIndexRoot{},
+	&model.TrashItem{},
+	&model.Setting{},
+	&model.SettingHistory{},
+	// 添加其他模型...
+}
+
+// migrations 按版本递增排列的数据库迁移，已发布的版本不能修改，只能追加
+// 迁移在 AutoMigrate 之前执行，操作的表结构应在迁移函数内定义，不能引用 model 中会继续变化的模型
+// 需同时兼容 MySQL 和 SQLite，优先使用 tx.Migrator() 而不是手写SQL
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		// 引入版本迁移之前由 AutoMigrate 创建的表结构，无需变更
+		Up: func(tx *gorm.DB) error { return nil },
+	},
+}
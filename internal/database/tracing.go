@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "cinexus:tracing_span"
+
+// Ctx 返回带有上下文中请求ID和链路信息的数据库连接，请求中的查询记录为请求 span 的子 span
+// 不继承上下文的取消和超时，客户端断开或后台任务停止时已开始的数据库写入仍会完成
+func Ctx(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		return DB
+	}
+	return DB.WithContext(detachedContext{ctx})
+}
+
+// detachedContext 只保留上下文中的值
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// tracingPlugin 为每条SQL创建span
+// 只在上下文中已有span时记录（通过 Ctx 传入请求上下文），避免后台任务的查询各自产生一条孤立的链路
+type tracingPlugin struct {
+	tracer trace.Tracer
+	system string
+}
+
+func newTracingPlugin(system string) *tracingPlugin {
+	return &tracingPlugin{tracer: otel.Tracer("cinexus/database"), system: system}
+}
+
+// Name 插件名称
+func (p *tracingPlugin) Name() string {
+	return "cinexus:tracing"
+}
+
+// Initialize 在各类操作前后注册回调
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+func (p *tracingPlugin) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+		ctx, span := p.tracer.Start(ctx, "gorm."+op, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (p *tracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	defer span.End()
+
+	// SQL 中的参数为占位符，不会记录具体的值
+	span.SetAttributes(
+		attribute.String("db.system", p.system),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
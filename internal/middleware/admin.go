@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/model"
+)
+
+// Admin 中间件，仅允许管理员访问，需在JWT中间件之后使用
+func Admin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != model.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code": 403,
+				"msg":  "需要管理员权限",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
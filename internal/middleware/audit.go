@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/model"
+	"cinexus/internal/service"
+	"cinexus/pkg/actor"
+)
+
+// 审计信息在gin上下文中的键
+const (
+	auditTargetTypeKey = "audit_target_type"
+	auditTargetKey     = "audit_target"
+	auditBeforeKey     = "audit_before"
+	auditAfterKey      = "audit_after"
+)
+
+// Audit 中间件，为所有修改类请求（POST/PUT/PATCH/DELETE）写入审计日志
+func Audit() gin.HandlerFunc {
+	auditService := &service.AuditService{}
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return
+		}
+
+		// 未匹配到路由的请求不记录
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+
+		targetType := c.GetString(auditTargetTypeKey)
+		target := c.GetString(auditTargetKey)
+		if targetType == "" {
+			targetType = model.AuditTargetHTTP
+			target = routeParams(c)
+		}
+
+		var err error
+		if status := c.Writer.Status(); status >= http.StatusBadRequest {
+			err = errors.New(http.StatusText(status))
+			if len(c.Errors) > 0 {
+				err = errors.New(c.Errors.String())
+			}
+		}
+
+		before, _ := c.Get(auditBeforeKey)
+		after, _ := c.Get(auditAfterKey)
+
+		// 登录等未认证的请求也需要记录来源IP
+		ctx := c.Request.Context()
+		if actor.FromContext(ctx).IP == "" {
+			ctx = actor.With(ctx, actor.Actor{IP: c.ClientIP()})
+		}
+
+		auditService.Record(ctx, service.AuditEntry{
+			Action:     c.Request.Method + " " + route,
+			TargetType: targetType,
+			Target:     target,
+			Before:     before,
+			After:      after,
+			Err:        err,
+		})
+	}
+}
+
+// SetAuditTarget 设置当前请求的审计目标
+func SetAuditTarget(c *gin.Context, targetType, target string) {
+	c.Set(auditTargetTypeKey, targetType)
+	c.Set(auditTargetKey, target)
+}
+
+// SetAuditSnapshot 设置当前请求修改前后的数据快照，任一参数为nil时不设置
+func SetAuditSnapshot(c *gin.Context, before, after any) {
+	if before != nil {
+		c.Set(auditBeforeKey, before)
+	}
+	if after != nil {
+		c.Set(auditAfterKey, after)
+	}
+}
+
+// routeParams 将路由参数拼接为审计目标
+func routeParams(c *gin.Context) string {
+	parts := make([]string, 0, len(c.Params))
+	for _, p := range c.Params {
+		parts = append(parts, p.Key+"="+p.Value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"cinexus/pkg/actor"
 	"cinexus/pkg/jwt"
 	"cinexus/pkg/logger"
 )
@@ -39,7 +40,7 @@ func JWT() gin.HandlerFunc {
 		// 解析令牌
 		claims, err := jwt.ParseToken(parts[1])
 		if err != nil {
-			logger.Error("解析令牌失败", zap.Error(err))
+			logger.Ctx(c.Request.Context()).Error("解析令牌失败", zap.Error(err))
 
 			var msg string
 			switch err {
@@ -66,6 +67,13 @@ func JWT() gin.HandlerFunc {
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
 
+		// 将操作者写入请求上下文，供服务层审计使用
+		c.Request = c.Request.WithContext(actor.With(c.Request.Context(), actor.Actor{
+			UserID:   claims.UserID,
+			Username: claims.Username,
+			IP:       c.ClientIP(),
+		}))
+
 		c.Next()
 	}
 }
@@ -3,67 +3,227 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"mime"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"cinexus/config"
 	"cinexus/pkg/logger"
 )
 
+// 未配置 redact_fields 时默认脱敏的字段关键字
+var defaultRedactFields = []string{"password", "token", "secret", "authorization", "cookie", "api_key"}
+
 // Logger 中间件，用于记录HTTP请求日志
-func Logger() gin.HandlerFunc {
+// 请求体和响应体只在配置了 body_max_size 且内容类型匹配时记录，超出部分截断，敏感字段脱敏
+// sensitive 为请求结构体，其中标记了 redact:"true" 的字段不论 redact_fields 如何配置都会脱敏
+func Logger(sensitive ...any) gin.HandlerFunc {
+	always := redactTagFields(sensitive)
+	var state atomic.Pointer[accessLogState]
+	state.Store(newAccessLogState(config.Get().Log.Access, always))
+	config.OnChange("log", func(_, new *config.Config) {
+		state.Store(newAccessLogState(new.Log.Access, always))
+	})
+
 	return func(c *gin.Context) {
+		st := state.Load()
+		conf, redactor := st.conf, st.redactor
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
 
-		// 获取请求体
+		// 只读取限制大小以内的请求体，剩余部分原样交给后续处理
 		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		if conf.BodyMaxSize > 0 && c.Request.Body != nil && matchContentType(c.ContentType(), conf.BodyContentTypes) {
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(conf.BodyMaxSize)+1))
+			c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(requestBody), c.Request.Body), Closer: c.Request.Body}
 		}
 
-		// 使用自定义ResponseWriter记录响应
-		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
-		c.Writer = blw
+		var blw *bodyLogWriter
+		if conf.BodyMaxSize > 0 {
+			blw = &bodyLogWriter{ResponseWriter: c.Writer, limit: conf.BodyMaxSize + 1}
+			c.Writer = blw
+		}
 
 		// 处理请求
 		c.Next()
 
-		// 计算耗时
-		latency := time.Since(start)
+		status := c.Writer.Status()
+		if status < 400 && skipPath(path, conf.SkipPaths) {
+			return
+		}
 
-		// 记录日志
-		logger.Info("HTTP请求",
+		fields := []zap.Field{
 			zap.String("method", c.Request.Method),
 			zap.String("path", path),
-			zap.String("query", query),
+			zap.String("query", redactor.query(c.Request.URL.RawQuery)),
 			zap.String("ip", c.ClientIP()),
 			zap.String("user-agent", c.Request.UserAgent()),
-			zap.Int("status", c.Writer.Status()),
-			zap.Duration("latency", latency),
-			zap.String("request", string(requestBody)),
-			zap.String("response", blw.body.String()),
-		)
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if requestBody != nil {
+			fields = append(fields, zap.String("request", redactor.body(requestBody, conf.BodyMaxSize)))
+		}
+		if blw != nil && matchContentType(c.Writer.Header().Get("Content-Type"), conf.BodyContentTypes) {
+			fields = append(fields, zap.String("response", redactor.body(blw.body.Bytes(), conf.BodyMaxSize)))
+		}
+
+		logger.Ctx(c.Request.Context()).Info("HTTP请求", fields...)
+	}
+}
+
+// accessLogState 请求日志配置和对应的脱敏规则，配置重新加载时整体替换
+type accessLogState struct {
+	conf     config.AccessLogConfig
+	redactor *redactor
+}
+
+func newAccessLogState(conf config.AccessLogConfig, always []string) *accessLogState {
+	fields := conf.RedactFields
+	if len(fields) == 0 {
+		fields = defaultRedactFields
 	}
+	return &accessLogState{conf: conf, redactor: newRedactor(append(append([]string(nil), fields...), always...))}
 }
 
-// bodyLogWriter 是一个自定义的ResponseWriter，用于捕获响应体
+// redactTagFields 收集结构体中标记了 redact:"true" 的字段的JSON名称，包括嵌入的结构体
+func redactTagFields(types []any) []string {
+	var fields []string
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				collect(f.Type)
+				continue
+			}
+			if f.Tag.Get("redact") != "true" {
+				continue
+			}
+			if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+				fields = append(fields, name)
+			}
+		}
+	}
+	for _, v := range types {
+		collect(reflect.TypeOf(v))
+	}
+	return fields
+}
+
+// skipPath 判断路径是否在跳过列表中，以 * 结尾表示前缀匹配
+func skipPath(path string, patterns []string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// matchContentType 判断内容类型是否在配置的列表中，忽略 charset 等参数
+func matchContentType(contentType string, allowed []string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactor 按字段名关键字对请求日志脱敏
+type redactor struct {
+	json *regexp.Regexp
+	form *regexp.Regexp
+}
+
+func newRedactor(fields []string) *redactor {
+	var quoted []string
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			quoted = append(quoted, regexp.QuoteMeta(f))
+		}
+	}
+	alt := strings.Join(quoted, "|")
+	return &redactor{
+		// 按文本替换，请求体被截断、不是合法JSON时同样生效
+		json: regexp.MustCompile(`(?i)("[^"]*(?:` + alt + `)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"?`),
+		form: regexp.MustCompile(`(?i)((?:^|&)[^=&]*(?:` + alt + `)[^=&]*=)[^&]*`),
+	}
+}
+
+// body 截断并脱敏请求体或响应体
+func (r *redactor) body(b []byte, limit int) string {
+	truncated := len(b) > limit
+	if truncated {
+		b = b[:limit]
+	}
+	s := r.json.ReplaceAllString(string(b), `$1"***"`)
+	s = r.form.ReplaceAllString(s, `$1***`)
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+// query 对查询参数脱敏
+func (r *redactor) query(raw string) string {
+	return r.form.ReplaceAllString(raw, `$1***`)
+}
+
+// readCloser 组合读取和关闭，用于放回已部分读取的请求体
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter 是一个自定义的ResponseWriter，用于捕获响应体，最多保留 limit 字节
 type bodyLogWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body  bytes.Buffer
+	limit int
 }
 
 // Write 重写Write方法，同时写入原始ResponseWriter和缓冲区
 func (w *bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	w.capture(b)
 	return w.ResponseWriter.Write(b)
 }
 
 // WriteString 重写WriteString方法，同时写入原始ResponseWriter和缓冲区
 func (w *bodyLogWriter) WriteString(s string) (int, error) {
-	w.body.WriteString(s)
+	w.capture([]byte(s))
 	return w.ResponseWriter.WriteString(s)
 }
+
+func (w *bodyLogWriter) capture(b []byte) {
+	if n := w.limit - w.body.Len(); n > 0 {
+		if len(b) > n {
+			b = b[:n]
+		}
+		w.body.Write(b)
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"reflect"
+	"testing"
+
+	"cinexus/config"
+)
+
+func TestRedactorBody(t *testing.T) {
+	r := newRedactor(defaultRedactFields)
+
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		want  string
+	}{
+		{
+			name: "JSON字段",
+			body: `{"username":"admin","password":"p@ss"}`,
+			want: `{"username":"admin","password":"***"}`,
+		},
+		{
+			name: "字段名包含关键字且不区分大小写",
+			body: `{"old_Password": "a", "refreshToken":"b", "X-Api_Key":"c"}`,
+			want: `{"old_Password": "***", "refreshToken":"***", "X-Api_Key":"***"}`,
+		},
+		{
+			name: "值中的转义引号",
+			body: `{"secret":"a\"b\\","name":"x"}`,
+			want: `{"secret":"***","name":"x"}`,
+		},
+		{
+			name: "嵌套对象中的字段",
+			body: `{"config":{"cookie":"UID=1; CID=2","cloud":"115"}}`,
+			want: `{"config":{"cookie":"***","cloud":"115"}}`,
+		},
+		{
+			name: "非字符串值保持不变",
+			body: `{"token_expire":3600,"password_set":true}`,
+			want: `{"token_expire":3600,"password_set":true}`,
+		},
+		{
+			name:  "截断后不完整的JSON",
+			body:  `{"user":"a","password":"0123456789"}`,
+			limit: 28,
+			want:  `{"user":"a","password":"***"...(truncated)`,
+		},
+		{
+			name: "表单",
+			body: "username=admin&password=p%40ss&authorization=Bearer+x",
+			want: "username=admin&password=***&authorization=***",
+		},
+		{
+			name: "没有敏感字段",
+			body: `{"path":"/115/movies"}`,
+			want: `{"path":"/115/movies"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			if limit == 0 {
+				limit = 1024
+			}
+			if got := r.body([]byte(tt.body), limit); got != tt.want {
+				t.Errorf("body(%s)\n got  %s\n want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorQuery(t *testing.T) {
+	r := newRedactor([]string{"token"})
+
+	tests := map[string]string{
+		"token=abc":                     "token=***",
+		"instance=default&token=abc&x=": "instance=default&token=***&x=",
+		"access_token=abc&page=2":       "access_token=***&page=2",
+		"TOKEN=abc":                     "TOKEN=***",
+		"password=abc":                  "password=abc",
+		"":                              "",
+	}
+	for raw, want := range tests {
+		if got := r.query(raw); got != want {
+			t.Errorf("query(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestRedactTagFields(t *testing.T) {
+	type embedded struct {
+		Cookie string `json:"cookie" redact:"true"`
+	}
+	type request struct {
+		embedded
+		Name     string `json:"name"`
+		Password string `json:"password,omitempty" redact:"true"`
+		Hidden   string `json:"-" redact:"true"`
+		NoJSON   string `redact:"true"`
+	}
+
+	got := redactTagFields([]any{request{}, &embedded{}, 1})
+	want := []string{"cookie", "password", "cookie"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redactTagFields = %v, want %v", got, want)
+	}
+}
+
+func TestAccessLogStateAlwaysRedactsTaggedFields(t *testing.T) {
+	st := newAccessLogState(config.AccessLogConfig{RedactFields: []string{"password"}}, []string{"cookie"})
+
+	got := st.redactor.body([]byte(`{"password":"a","cookie":"b","token":"c"}`), 1024)
+	want := `{"password":"***","cookie":"***","token":"c"}`
+	if got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/response"
+)
+
+// Metrics 中间件，按路由模板记录HTTP请求数和耗时，未匹配的路由统一记为 unmatched，避免标签数量无限增长
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := metrics.HTTPStart()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		done(c.Request.Method, route, c.Writer.Status())
+	}
+}
+
+// MetricsToken 中间件，校验访问指标接口的 Bearer 令牌，令牌为空时不校验
+func MetricsToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			response.Unauthorized(c, "指标令牌无效")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"cinexus/internal/model"
+)
+
+// Permission 中间件，检查当前用户角色是否拥有指定权限，需在JWT中间件之后使用
+func Permission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !model.HasPermission(c.GetString("role"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code": 403,
+				"msg":  "没有操作权限",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -18,7 +18,7 @@ func Recovery() gin.HandlerFunc {
 			if err := recover(); err != nil {
 				// 记录堆栈信息
 				stack := string(debug.Stack())
-				logger.Error("[Recovery from panic]",
+				logger.Ctx(c.Request.Context()).Error("[Recovery from panic]",
 					zap.Any("error", err),
 					zap.String("stack", stack),
 					zap.String("path", c.Request.URL.Path),
@@ -0,0 +1,12 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TraceFilter 链路追踪过滤器，健康检查和指标采集请求频繁且无排查价值，不创建span
+func TraceFilter(r *http.Request) bool {
+	p := r.URL.Path
+	return p != "/metrics" && !strings.HasPrefix(p, "/health/")
+}
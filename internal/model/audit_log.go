@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// 审计结果
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// 审计目标类型
+const (
+	AuditTargetHTTP      = "http"     // 未指定具体目标的接口调用
+	AuditTargetCD2Path   = "cd2_path" // CloudDrive2 文件路径
+	AuditTargetCD2       = "cd2"      // CloudDrive2 实例级别的资源（挂载点、云盘账号等）
+	AuditTargetUser      = "user"
+	AuditTargetLibrary   = "library"
+	AuditTargetInvite    = "invite_code"
+	AuditTargetRename    = "rename_batch"
+	AuditTargetMount     = "mount_point"
+	AuditTargetMigration = "migration_job"
+	AuditTargetBackup    = "backup_policy"
+	AuditTargetCloud     = "cloud_account"
+	AuditTargetDedupe    = "dedupe_scan"
+	AuditTargetIndex     = "file_index"
+	AuditTargetTrash     = "trash_item"
+	AuditTargetSetting   = "setting"
+)
+
+// AuditLog 审计日志，记录管理操作和破坏性操作
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"` // 0 表示匿名或系统操作
+	Username   string    `gorm:"size:50" json:"username"`
+	Action     string    `gorm:"size:128;not null;index" json:"action"`
+	TargetType string    `gorm:"size:32;index" json:"target_type"`
+	Target     string    `gorm:"size:1024" json:"target"`
+	Instance   string    `gorm:"size:64" json:"instance"` // CloudDrive2 实例名称
+	IP         string    `gorm:"size:64" json:"ip"`
+	Before     string    `gorm:"type:text" json:"before"`
+	After      string    `gorm:"type:text" json:"after"`
+	Result     string    `gorm:"size:16;index" json:"result"`
+	Error      string    `gorm:"size:1024" json:"error"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+)
+
+// 备份规则类型
+const (
+	BackupRuleExtensions = "extensions" // 扩展名，逗号分隔
+	BackupRuleFileNames  = "file_names" // 文件名，逗号分隔
+	BackupRuleRegex      = "regex"
+	BackupRuleMinSize    = "min_size" // 最小文件大小（字节）
+)
+
+// BackupPolicy 备份策略，以数据库为准推送到CD2
+type BackupPolicy struct {
+	ID                   uint             `gorm:"primarykey" json:"id"`
+	Instance             string           `gorm:"size:64;uniqueIndex:idx_backup_source" json:"instance"`
+	SourcePath           string           `gorm:"size:512;uniqueIndex:idx_backup_source" json:"source_path"`
+	Name                 string           `gorm:"size:100" json:"name"`
+	Destinations         []BackupTarget   `gorm:"type:text;serializer:json" json:"destinations"`
+	Rules                []BackupRule     `gorm:"type:text;serializer:json" json:"rules"`
+	ReplaceRule          string           `gorm:"size:32" json:"replace_rule"`    // skip, overwrite, keep_history
+	DeleteRule           string           `gorm:"size:32" json:"delete_rule"`     // delete, recycle, keep, move_to_history
+	CompletionRule       string           `gorm:"size:32" json:"completion_rule"` // none, delete_source, delete_source_and_empty_folder
+	Enabled              bool             `json:"enabled"`
+	FileSystemWatch      bool             `json:"file_system_watch"`
+	WalkIntervalSecs     int64            `json:"walk_interval_secs"` // 0 表示不自动全量扫描
+	ForceWalkOnStart     bool             `json:"force_walk_on_start"`
+	TimeSchedules        []BackupSchedule `gorm:"type:text;serializer:json" json:"time_schedules"`
+	TimeSchedulesEnabled bool             `json:"time_schedules_enabled"`
+	CreatedBy            uint             `json:"created_by"`
+	SyncedAt             *time.Time       `json:"synced_at"` // 最后一次成功推送到CD2的时间
+	SyncError            string           `gorm:"size:512" json:"sync_error"`
+	CreatedAt            time.Time        `json:"created_at"`
+	UpdatedAt            time.Time        `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (BackupPolicy) TableName() string {
+	return "backup_policy"
+}
+
+// BackupTarget 备份目标
+type BackupTarget struct {
+	Path    string `json:"path"`
+	Enabled bool   `json:"enabled"`
+}
+
+// BackupRule 备份文件过滤规则
+type BackupRule struct {
+	Type          string `json:"type"`
+	Value         string `json:"value"`
+	Enabled       bool   `json:"enabled"`
+	BlackList     bool   `json:"black_list"` // 为 true 时排除匹配的文件，否则只备份匹配的文件
+	ApplyToFolder bool   `json:"apply_to_folder"`
+}
+
+// BackupSchedule 定时备份时间
+type BackupSchedule struct {
+	Enabled    bool     `json:"enabled"`
+	Hour       uint32   `json:"hour"`
+	Minute     uint32   `json:"minute"`
+	Second     uint32   `json:"second"`
+	DaysOfWeek []uint32 `json:"days_of_week"` // 周日为0，为空表示每天
+}
+
+// BackupStatusLog 备份状态历史，状态变化时记录
+type BackupStatusLog struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	PolicyID       uint      `gorm:"not null;index" json:"policy_id"`
+	Status         string    `gorm:"size:32" json:"status"`
+	StatusMessage  string    `gorm:"size:512" json:"status_message"`
+	WatcherStatus  string    `gorm:"size:32" json:"watcher_status"`
+	WatcherMessage string    `gorm:"size:512" json:"watcher_message"`
+	ErrorCount     int       `json:"error_count"`
+	LastError      string    `gorm:"size:512" json:"last_error"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (BackupStatusLog) TableName() string {
+	return "backup_status_log"
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+)
+
+// 重复文件扫描状态
+const (
+	DedupeStatusScanning  = "scanning"  // 扫描中
+	DedupeStatusCompleted = "completed" // 扫描完成
+	DedupeStatusFailed    = "failed"    // 扫描失败
+	DedupeStatusCancelled = "cancelled" // 已取消或因服务重启中断
+)
+
+// 重复文件分组状态
+const (
+	DedupeGroupPending  = "pending"  // 等待确认
+	DedupeGroupReviewed = "reviewed" // 已确认保留的文件，等待清理
+	DedupeGroupIgnored  = "ignored"  // 确认不处理
+	DedupeGroupApplied  = "applied"  // 已将其余文件移入回收站
+	DedupeGroupPartial  = "partial"  // 部分文件清理失败
+)
+
+// 重复文件的判断依据
+const (
+	DedupeMatchHash     = "hash"      // 大小和哈希都相同
+	DedupeMatchNameSize = "name_size" // 缺少可比较的哈希，按文件名和大小判断
+)
+
+// DedupeScan 重复文件扫描任务
+type DedupeScan struct {
+	ID          uint          `gorm:"primarykey" json:"id"`
+	UserID      uint          `gorm:"index" json:"user_id"`
+	Instance    string        `gorm:"size:64" json:"instance"`
+	Roots       []DedupeRoot  `gorm:"type:text;serializer:json" json:"roots"`
+	MinSize     int64         `json:"min_size"`
+	Extensions  []string      `gorm:"type:text;serializer:json" json:"extensions"`
+	Status      string        `gorm:"size:16;index" json:"status"`
+	Files       int           `json:"files"`        // 扫描的文件数
+	Groups      int           `json:"groups"`       // 重复分组数
+	WastedBytes int64         `json:"wasted_bytes"` // 每组只保留一份时可释放的空间
+	Error       string        `gorm:"size:512" json:"error"`
+	FinishedAt  *time.Time    `json:"finished_at"`
+	GroupList   []DedupeGroup `gorm:"foreignKey:ScanID" json:"group_list,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DedupeScan) TableName() string {
+	return "dedupe_scan"
+}
+
+// DedupeRoot 扫描的目录，Name 为该目录所属的媒体库名称
+type DedupeRoot struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// DedupeGroup 一组重复文件
+type DedupeGroup struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	ScanID     uint         `gorm:"not null;index" json:"scan_id"`
+	MatchBy    string       `gorm:"size:16" json:"match_by"`
+	HashType   string       `gorm:"size:16" json:"hash_type"`
+	Size       int64        `json:"size"`
+	Count      int          `json:"count"`
+	KeepFileID uint         `json:"keep_file_id"`
+	Status     string       `gorm:"size:16;index" json:"status"`
+	Files      []DedupeFile `gorm:"foreignKey:GroupID" json:"files,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DedupeGroup) TableName() string {
+	return "dedupe_group"
+}
+
+// DedupeFile 重复分组中的单个文件
+type DedupeFile struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	GroupID      uint       `gorm:"not null;index" json:"group_id"`
+	Path         string     `gorm:"size:1024" json:"path"`
+	Library      string     `gorm:"size:100" json:"library"`
+	Cloud        string     `gorm:"size:64" json:"cloud"`
+	CloudAccount string     `gorm:"size:100" json:"cloud_account"`
+	Size         int64      `json:"size"`
+	Hash         string     `gorm:"size:128" json:"hash"`
+	ModifiedAt   *time.Time `json:"modified_at"`
+	Keep         bool       `json:"keep"`
+	Trashed      bool       `json:"trashed"`
+	Error        string     `gorm:"size:512" json:"error"`
+}
+
+// TableName 指定表名
+func (DedupeFile) TableName() string {
+	return "dedupe_file"
+}
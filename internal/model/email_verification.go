@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// EmailVerification 邮箱验证记录
+type EmailVerification struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Email     string     `gorm:"size:100;not null" json:"email"`
+	Token     string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (EmailVerification) TableName() string {
+	return "email_verification"
+}
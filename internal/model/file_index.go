@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+)
+
+// FileIndex 本地保存的CD2文件树，用于快速搜索
+type FileIndex struct {
+	ID         uint              `gorm:"primarykey" json:"id"`
+	Instance   string            `gorm:"size:64;uniqueIndex:idx_file_index_path;index:idx_file_index_parent" json:"instance"`
+	PathHash   string            `gorm:"size:40;uniqueIndex:idx_file_index_path" json:"-"` // 路径的SHA1，路径过长无法直接建唯一索引
+	ParentHash string            `gorm:"size:40;index:idx_file_index_parent" json:"-"`
+	Path       string            `gorm:"size:1024" json:"path"`
+	Parent     string            `gorm:"size:1024" json:"parent"`
+	Name       string            `gorm:"size:255;index" json:"name"`
+	Ext        string            `gorm:"size:16;index" json:"ext"`
+	Size       int64             `gorm:"index" json:"size"`
+	IsDir      bool              `json:"is_dir"`
+	ModifiedAt *time.Time        `gorm:"index" json:"modified_at"`
+	Hashes     map[string]string `gorm:"type:text;serializer:json" json:"hashes,omitempty"`
+	Cloud      string            `gorm:"size:64" json:"cloud"`
+	IndexedAt  time.Time         `gorm:"index" json:"indexed_at"` // 最后一次扫描或推送更新的时间
+}
+
+// TableName 指定表名
+func (FileIndex) TableName() string {
+	return "file_index"
+}
+
+// FileIndexRoot 建立索引的目录及最近一次扫描结果
+type FileIndexRoot struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	Instance   string     `gorm:"size:64;uniqueIndex:idx_file_index_root" json:"instance"`
+	Path       string     `gorm:"size:512;uniqueIndex:idx_file_index_root" json:"path"`
+	Status     string     `gorm:"size:16" json:"status"` // scanning, completed, failed
+	Files      int        `json:"files"`
+	Dirs       int        `json:"dirs"`
+	Error      string     `gorm:"size:512" json:"error"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// TableName 指定表名
+func (FileIndexRoot) TableName() string {
+	return "file_index_root"
+}
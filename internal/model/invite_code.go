@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InviteCode 邀请码模型
+type InviteCode struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	Code      string         `gorm:"size:64;not null;uniqueIndex" json:"code"`
+	MaxUses   int            `gorm:"default:1" json:"max_uses"` // 最大使用次数，0 表示不限
+	UsedCount int            `gorm:"default:0" json:"used_count"`
+	Role      string         `gorm:"size:20;default:user" json:"role"` // 使用该邀请码注册的用户角色
+	ExpiresAt *time.Time     `json:"expires_at"`                       // 为空表示永不过期
+	Remark    string         `gorm:"size:255" json:"remark"`
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 指定表名
+func (InviteCode) TableName() string {
+	return "invite_code"
+}
+
+// Usable 检查邀请码当前是否可用
+func (i *InviteCode) Usable() bool {
+	if i.ExpiresAt != nil && time.Now().After(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.UsedCount < i.MaxUses
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+)
+
+// 迁移任务状态
+const (
+	MigrationStatusPending   = "pending"   // 等待执行
+	MigrationStatusPlanning  = "planning"  // 扫描源目录
+	MigrationStatusRunning   = "running"   // 复制和校验中
+	MigrationStatusCompleted = "completed" // 全部成功
+	MigrationStatusPartial   = "partial"   // 部分失败
+	MigrationStatusFailed    = "failed"    // 执行失败
+	MigrationStatusCancelled = "cancelled" // 已取消或因服务重启中断
+)
+
+// 迁移文件状态
+const (
+	MigrationItemPending  = "pending"   // 等待复制
+	MigrationItemCopying  = "copying"   // 已提交给CD2，等待完成
+	MigrationItemVerified = "verified"  // 已复制并校验通过
+	MigrationItemSizeOnly = "size_only" // 已复制，只比较了大小（未要求校验哈希或双方没有共同的哈希类型），不会删除源文件
+	MigrationItemSkipped  = "skipped"   // 目标已存在且一致，无需复制
+	MigrationItemFailed   = "failed"    // 重试后仍失败
+)
+
+// MigrationJob 跨云盘迁移任务
+type MigrationJob struct {
+	ID            uint            `gorm:"primarykey" json:"id"`
+	UserID        uint            `gorm:"index" json:"user_id"`
+	Instance      string          `gorm:"size:64" json:"instance"`
+	SourcePath    string          `gorm:"size:1024" json:"source_path"`
+	DestPath      string          `gorm:"size:1024" json:"dest_path"`
+	VerifyHash    bool            `json:"verify_hash"`    // 是否比较哈希，否则只比较大小
+	DeleteSource  bool            `json:"delete_source"`  // 哈希校验通过后是否删除源文件（移入回收站），需要 VerifyHash
+	MaxConcurrent int             `json:"max_concurrent"` // 同时进行的复制任务数
+	MaxRetries    int             `json:"max_retries"`    // 单个文件失败后的重试次数
+	Status        string          `gorm:"size:16;index" json:"status"`
+	Total         int             `json:"total"`
+	Verified      int             `json:"verified"`
+	Skipped       int             `json:"skipped"`
+	Failed        int             `json:"failed"`
+	SourceDeleted int             `json:"source_deleted"`
+	TotalBytes    int64           `json:"total_bytes"`
+	Error         string          `gorm:"size:512" json:"error"`
+	StartedAt     *time.Time      `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at"`
+	Items         []MigrationItem `gorm:"foreignKey:JobID" json:"items,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (MigrationJob) TableName() string {
+	return "migration_job"
+}
+
+// MigrationItem 单个文件的迁移记录
+type MigrationItem struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	JobID         uint      `gorm:"not null;index" json:"job_id"`
+	SourcePath    string    `gorm:"size:1024" json:"source_path"`
+	DestPath      string    `gorm:"size:1024" json:"dest_path"`
+	Size          int64     `json:"size"`
+	Status        string    `gorm:"size:16;index" json:"status"`
+	Attempts      int       `json:"attempts"`
+	HashType      string    `gorm:"size:16" json:"hash_type"` // 校验使用的哈希类型，为空表示只比较了大小
+	SourceDeleted bool      `json:"source_deleted"`
+	DestCreated   bool      `json:"dest_created"` // 目标文件由本任务复制创建，重试时可以替换，其他已存在的文件不会覆盖
+	Error         string    `gorm:"size:512" json:"error"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (MigrationItem) TableName() string {
+	return "migration_item"
+}
@@ -0,0 +1,25 @@
+package model
+
+// 权限
+const (
+	PermissionAll                   = "*"
+	PermissionFileWrite             = "file:write"              // 创建、重命名、移动、复制、删除到回收站
+	PermissionFileDeletePermanently = "file:delete_permanently" // 永久删除
+)
+
+// rolePermissions 角色拥有的权限，普通用户只能浏览，修改文件需要由邀请码或管理员授予 editor 角色
+var rolePermissions = map[string][]string{
+	RoleAdmin:  {PermissionAll},
+	RoleEditor: {PermissionFileWrite},
+	RoleUser:   {},
+}
+
+// HasPermission 检查角色是否拥有指定权限
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == PermissionAll || p == permission {
+			return true
+		}
+	}
+	return false
+}
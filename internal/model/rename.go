@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// 批量重命名状态
+const (
+	RenameStatusPending = "pending" // 执行中
+	RenameStatusApplied = "applied" // 全部成功
+	RenameStatusPartial = "partial" // 部分成功
+	RenameStatusFailed  = "failed"  // 全部失败
+	RenameStatusUndone  = "undone"  // 已撤销
+)
+
+// RenameBatch 批量重命名记录，用于撤销
+type RenameBatch struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"index" json:"user_id"`
+	Instance  string         `gorm:"size:64" json:"instance"`
+	Path      string         `gorm:"size:1024" json:"path"`
+	Pattern   string         `gorm:"size:512" json:"pattern"`
+	Template  string         `gorm:"size:512" json:"template"`
+	Total     int            `json:"total"`
+	Succeeded int            `json:"succeeded"`
+	Status    string         `gorm:"size:16;index" json:"status"`
+	Items     []RenameRecord `gorm:"foreignKey:BatchID" json:"items,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RenameBatch) TableName() string {
+	return "rename_batch"
+}
+
+// RenameRecord 单个文件的重命名记录
+type RenameRecord struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	BatchID   uint      `gorm:"not null;index" json:"batch_id"`
+	OldPath   string    `gorm:"size:1024" json:"old_path"`
+	NewPath   string    `gorm:"size:1024" json:"new_path"`
+	Status    string    `gorm:"size:16" json:"status"`
+	Error     string    `gorm:"size:512" json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RenameRecord) TableName() string {
+	return "rename_record"
+}
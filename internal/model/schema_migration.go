@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// SchemaMigration 已执行的数据库迁移版本
+type SchemaMigration struct {
+	Version   int64     `gorm:"primarykey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"size:128" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// Setting 保存在数据库中的配置项，覆盖配置文件和环境变量中的同名配置，值为JSON
+type Setting struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Key       string    `gorm:"size:128;uniqueIndex;not null" json:"key"` // 配置项名称，如 notify.webhook
+	Value     string    `gorm:"type:text" json:"value"`
+	UpdatedBy uint      `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Setting) TableName() string {
+	return "setting"
+}
+
+// SettingHistory 配置项修改记录，OldValue 或 NewValue 为空表示修改前未设置或已恢复为默认值
+type SettingHistory struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Key       string    `gorm:"size:128;index" json:"key"`
+	OldValue  *string   `gorm:"type:text" json:"old_value"`
+	NewValue  *string   `gorm:"type:text" json:"new_value"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Username  string    `gorm:"size:50" json:"username"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (SettingHistory) TableName() string {
+	return "setting_history"
+}
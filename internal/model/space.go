@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// SpaceSample 云盘空间采样，按固定间隔记录每个云盘根目录的空间使用情况
+type SpaceSample struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Instance   string    `gorm:"size:64;index:idx_space_root" json:"instance"`
+	CloudRoot  string    `gorm:"size:512;index:idx_space_root" json:"cloud_root"`
+	TotalSpace int64     `json:"total_space"`
+	UsedSpace  int64     `json:"used_space"`
+	FreeSpace  int64     `json:"free_space"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (SpaceSample) TableName() string {
+	return "space_sample"
+}
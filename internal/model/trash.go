@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// 回收站文件状态
+const (
+	TrashStatusTrashed  = "trashed"  // 在回收站中
+	TrashStatusRestored = "restored" // 已恢复到原位置
+	TrashStatusPurged   = "purged"   // 已永久删除
+	TrashStatusMissing  = "missing"  // 回收站中的文件已不存在（被手动移走或删除）
+)
+
+// 删除来源
+const (
+	TrashSourceAPI       = "api"       // 通过文件操作接口删除
+	TrashSourceDedupe    = "dedupe"    // 重复文件清理
+	TrashSourceMigration = "migration" // 迁移后删除源文件或清理不完整的目标文件
+)
+
+// TrashItem Cinexus 移入 .cinexus-trash 的文件，记录原位置以便恢复
+type TrashItem struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	Instance     string     `gorm:"size:64;index" json:"instance"`
+	OriginalPath string     `gorm:"size:1024" json:"original_path"`
+	TrashPath    string     `gorm:"size:1024" json:"trash_path"`
+	Name         string     `gorm:"size:255;index" json:"name"`
+	IsDir        bool       `json:"is_dir"`
+	Size         int64      `json:"size"`
+	Source       string     `gorm:"size:16" json:"source"`
+	UserID       uint       `gorm:"index" json:"user_id"`
+	Status       string     `gorm:"size:16;index" json:"status"`
+	Error        string     `gorm:"size:512" json:"error"`
+	ExpiresAt    *time.Time `gorm:"index" json:"expires_at"` // 超过该时间后永久删除，为空表示不自动删除
+	RestoredAt   *time.Time `json:"restored_at"`
+	PurgedAt     *time.Time `json:"purged_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TrashItem) TableName() string {
+	return "trash_item"
+}
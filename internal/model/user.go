@@ -7,6 +7,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// 用户状态
+const (
+	UserStatusDisabled = 0 // 禁用
+	UserStatusActive   = 1 // 启用
+	UserStatusPending  = 2 // 待邮箱验证
+)
+
+// 用户角色
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor" // 可以修改CD2中的文件
+	RoleUser   = "user"   // 只读，自助注册的默认角色
+)
+
 // User 用户模型
 type User struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -16,8 +30,8 @@ type User struct {
 	Email     string         `gorm:"size:100;uniqueIndex" json:"email"`
 	Phone     string         `gorm:"size:20" json:"phone"`
 	Avatar    string         `gorm:"size:255" json:"avatar"`
-	Role      string         `gorm:"size:20;default:user" json:"role"` // admin, user
-	Status    int            `gorm:"default:1" json:"status"`          // 0: 禁用, 1: 启用
+	Role      string         `gorm:"size:20;default:user" json:"role"` // admin, editor, user
+	Status    int            `gorm:"default:1" json:"status"`          // 0: 禁用, 1: 启用, 2: 待验证
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
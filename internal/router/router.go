@@ -5,24 +5,44 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"cinexus/config"
 	"cinexus/internal/controller"
 	"cinexus/internal/middleware"
+	"cinexus/internal/model"
+	"cinexus/pkg/metrics"
 )
 
 // RegisterRoutes 注册所有路由
 func RegisterRoutes(r *gin.Engine) {
 	// 全局中间件
-	r.Use(middleware.Cors())
-
-	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
-		})
-	})
+	r.Use(middleware.Cors(), middleware.Audit())
+	if metricsConf := config.Get().Metrics; metricsConf.Enabled {
+		r.Use(middleware.Metrics())
+		r.GET("/metrics", middleware.MetricsToken(metricsConf.Token), gin.WrapH(metrics.Handler()))
+	}
 
 	// 创建控制器
+	healthController := controller.NewHealthController()
 	userController := controller.NewUserController()
+	registerController := controller.NewRegisterController()
+	auditController := controller.NewAuditController()
+	fileController := controller.NewFileController()
+	renamerController := controller.NewRenamerController()
+	mountController := controller.NewMountController()
+	transferController := controller.NewTransferController()
+	migrationController := controller.NewMigrationController()
+	backupController := controller.NewBackupController()
+	cloudAccountController := controller.NewCloudAccountController()
+	quotaController := controller.NewQuotaController()
+	dedupeController := controller.NewDedupeController()
+	fileIndexController := controller.NewFileIndexController()
+	trashController := controller.NewTrashController()
+	logController := controller.NewLogController()
+	settingController := controller.NewSettingController()
+
+	// 健康检查
+	r.GET("/health/live", healthController.Live)
+	r.GET("/health/ready", healthController.Ready)
 
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
@@ -30,6 +50,9 @@ func RegisterRoutes(r *gin.Engine) {
 		// 无需认证的路由
 		v1.POST("/auth/login", userController.Login)
 		v1.POST("/auth/register", userController.Register)
+		v1.GET("/auth/register/settings", registerController.GetSettings)
+		v1.GET("/auth/verify-email", registerController.VerifyEmail)
+		v1.POST("/auth/verify-email/resend", registerController.ResendVerification)
 
 		// 需要认证的路由
 		auth := v1.Group("")
@@ -40,8 +63,170 @@ func RegisterRoutes(r *gin.Engine) {
 			auth.PUT("/user/info", userController.UpdateUserInfo)
 			auth.PUT("/user/password", userController.UpdatePassword)
 
+			// CD2文件浏览
+			auth.GET("/cd2/instances", fileController.ListInstances)
+			auth.GET("/files", fileController.List)
+			auth.GET("/files/stat", fileController.Stat)
+			auth.GET("/files/search", fileController.Search)
+			auth.GET("/files/index/search", fileIndexController.Search)
+			auth.GET("/files/space", fileController.Space)
+
+			// CD2文件操作
+			files := auth.Group("/files")
+			files.Use(middleware.Permission(model.PermissionFileWrite))
+			{
+				files.POST("/folder", fileController.CreateFolder)
+				files.POST("/rename", fileController.Rename)
+				files.POST("/batch-rename", fileController.BatchRename)
+				files.POST("/move", fileController.Move)
+				files.POST("/copy", fileController.Copy)
+				files.POST("/delete", fileController.Delete)
+			}
+
+			// 批量重命名
+			renamer := auth.Group("/renamer")
+			renamer.Use(middleware.Permission(model.PermissionFileWrite))
+			{
+				renamer.POST("/preview", renamerController.Preview)
+				renamer.POST("/apply", renamerController.Apply)
+				renamer.GET("/batches", renamerController.ListBatches)
+				renamer.GET("/batches/:id", renamerController.GetBatch)
+				renamer.POST("/batches/:id/undo", renamerController.Undo)
+			}
+
+			// CD2传输任务
+			auth.GET("/transfers/uploads", transferController.ListUploads)
+			auth.GET("/transfers/downloads", transferController.ListDownloads)
+			auth.GET("/transfers/copy-tasks", transferController.ListCopyTasks)
+
+			transfers := auth.Group("/transfers")
+			transfers.Use(middleware.Permission(model.PermissionFileWrite))
+			{
+				transfers.POST("/uploads/pause", transferController.PauseUploads)
+				transfers.POST("/uploads/resume", transferController.ResumeUploads)
+				transfers.POST("/uploads/cancel", transferController.CancelUploads)
+				transfers.POST("/copy-tasks/pause", transferController.PauseCopyTask)
+				transfers.POST("/copy-tasks/resume", transferController.ResumeCopyTask)
+				transfers.POST("/copy-tasks/cancel", transferController.CancelCopyTask)
+				transfers.POST("/copy-tasks/restart", transferController.RestartCopyTask)
+				transfers.POST("/copy-tasks/remove-completed", transferController.RemoveCompletedCopyTasks)
+			}
+
+			// 跨云盘迁移
+			migrations := auth.Group("/migrations")
+			migrations.Use(middleware.Permission(model.PermissionFileWrite))
+			{
+				migrations.POST("", migrationController.Create)
+				migrations.GET("", migrationController.List)
+				migrations.GET("/:id", migrationController.Get)
+				migrations.GET("/:id/items", migrationController.ListItems)
+				migrations.GET("/:id/report", migrationController.Report)
+				migrations.POST("/:id/cancel", migrationController.Cancel)
+				migrations.POST("/:id/resume", migrationController.Resume)
+			}
+
+			// 重复文件查找
+			dedupe := auth.Group("/dedupe")
+			dedupe.Use(middleware.Permission(model.PermissionFileWrite))
+			{
+				dedupe.POST("/scans", dedupeController.Create)
+				dedupe.GET("/scans", dedupeController.List)
+				dedupe.GET("/scans/:id", dedupeController.Get)
+				dedupe.GET("/scans/:id/groups", dedupeController.ListGroups)
+				dedupe.POST("/scans/:id/cancel", dedupeController.Cancel)
+				dedupe.POST("/scans/:id/apply", dedupeController.Apply)
+				dedupe.PUT("/groups/:id", dedupeController.Review)
+			}
+
+			// 回收站
+			trash := auth.Group("/trash")
+			trash.Use(middleware.Permission(model.PermissionFileWrite))
+			{
+				trash.GET("", trashController.List)
+				trash.POST("/:id/restore", trashController.Restore)
+				trash.DELETE("/:id", middleware.Permission(model.PermissionFileDeletePermanently), trashController.Purge)
+			}
+
 			// 其他API路由...
 		}
+
+		// 需要管理员权限的路由
+		admin := v1.Group("/admin")
+		admin.Use(middleware.JWT(), middleware.Admin())
+		{
+			// 注册管理
+			admin.GET("/register/settings", registerController.GetSettings)
+			admin.PUT("/register/settings", registerController.UpdateSettings)
+			admin.GET("/invite-codes", registerController.ListInviteCodes)
+			admin.POST("/invite-codes", registerController.CreateInviteCode)
+			admin.DELETE("/invite-codes/:id", registerController.DeleteInviteCode)
+
+			// 用户管理
+			admin.GET("/users", userController.ListUsers)
+			admin.PUT("/users/:id/role", userController.SetRole)
+
+			// 审计日志
+			admin.GET("/audit", auditController.List)
+			admin.GET("/audit/export", auditController.Export)
+
+			// 运行日志
+			admin.GET("/logs", logController.Query)
+			admin.GET("/logs/stream", logController.Stream)
+			admin.GET("/logs/level", logController.GetLevel)
+			admin.PUT("/logs/level", logController.SetLevel)
+			admin.GET("/cd2/logs", logController.CD2Files)
+
+			// 在线修改的配置项
+			admin.GET("/settings", settingController.List)
+			admin.PUT("/settings", settingController.Update)
+			admin.GET("/settings/history", settingController.History)
+
+			// CD2挂载点管理
+			admin.GET("/cd2/mounts", mountController.List)
+			admin.GET("/cd2/mounts/capacity", mountController.Capacity)
+			admin.GET("/cd2/mounts/health", mountController.Health)
+			admin.POST("/cd2/mounts", mountController.Add)
+			admin.PUT("/cd2/mounts", mountController.Update)
+			admin.DELETE("/cd2/mounts", mountController.Remove)
+			admin.POST("/cd2/mounts/mount", mountController.Mount)
+			admin.POST("/cd2/mounts/unmount", mountController.Unmount)
+			admin.POST("/cd2/mounts/reconcile", mountController.Reconcile)
+
+			// CD2云盘账号管理
+			admin.GET("/cd2/clouds", cloudAccountController.List)
+			admin.GET("/cd2/clouds/capacity", cloudAccountController.Capacity)
+			admin.GET("/cd2/clouds/config", cloudAccountController.GetConfig)
+			admin.PUT("/cd2/clouds/config", cloudAccountController.SetConfig)
+			admin.DELETE("/cd2/clouds", cloudAccountController.Remove)
+			admin.POST("/cd2/clouds/webdav", cloudAccountController.LoginWebDav)
+			admin.POST("/cd2/clouds/local-folder", cloudAccountController.AddLocalFolder)
+			admin.POST("/cd2/clouds/token/:provider", cloudAccountController.LoginToken)
+			admin.POST("/cd2/clouds/qrcode/:provider", cloudAccountController.QRCodeLogin)
+
+			// 云盘空间监控
+			admin.GET("/quota", quotaController.Status)
+			admin.GET("/quota/history", quotaController.History)
+			admin.POST("/quota/collect", quotaController.Collect)
+
+			// 文件索引管理
+			admin.GET("/index", fileIndexController.Roots)
+			admin.POST("/index/scan", fileIndexController.Scan)
+			admin.DELETE("/index/roots", fileIndexController.RemoveRoot)
+
+			// CD2备份策略
+			admin.GET("/backups", backupController.List)
+			admin.POST("/backups", backupController.Create)
+			admin.POST("/backups/sync", backupController.Sync)
+			admin.GET("/backups/:id", backupController.Get)
+			admin.PUT("/backups/:id", backupController.Update)
+			admin.DELETE("/backups/:id", backupController.Delete)
+			admin.PUT("/backups/:id/strategies", backupController.UpdateStrategies)
+			admin.POST("/backups/:id/enable", backupController.Enable)
+			admin.POST("/backups/:id/disable", backupController.Disable)
+			admin.POST("/backups/:id/walk", backupController.RestartWalk)
+			admin.GET("/backups/:id/status", backupController.Status)
+			admin.GET("/backups/:id/history", backupController.History)
+		}
 	}
 
 	// 404处理
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/pb"
+)
+
+// AuditService 审计日志服务
+type AuditService struct{}
+
+// AuditEntry 一条待记录的审计条目
+type AuditEntry struct {
+	Action     string
+	TargetType string
+	Target     string
+	Instance   string
+	Before     any
+	After      any
+	Err        error
+}
+
+// AuditQuery 审计日志查询条件
+type AuditQuery struct {
+	PageRequest
+	UserID     uint      `form:"user_id"`
+	Action     string    `form:"action"`
+	TargetType string    `form:"target_type"`
+	Target     string    `form:"target"`
+	Instance   string    `form:"instance"`
+	IP         string    `form:"ip"`
+	Result     string    `form:"result" binding:"omitempty,oneof=success failure"`
+	Start      time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	End        time.Time `form:"end" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// 导出审计日志的最大条数
+const maxAuditExport = 100000
+
+// errAuditExportLimit 导出条数超出上限
+var errAuditExportLimit = errors.New("导出条数超出上限，请缩小查询范围")
+
+// Record 写入一条审计日志，写入失败只记录错误日志，不影响业务流程
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	operator := actor.FromContext(ctx)
+
+	log := model.AuditLog{
+		UserID:     operator.UserID,
+		Username:   operator.Username,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		Target:     truncate(entry.Target, 1024),
+		Instance:   entry.Instance,
+		IP:         operator.IP,
+		Before:     snapshot(entry.Before),
+		After:      snapshot(entry.After),
+		Result:     model.AuditResultSuccess,
+	}
+	if entry.Err != nil {
+		log.Result = model.AuditResultFailure
+		log.Error = truncate(entry.Err.Error(), 1024)
+	}
+
+	if err := database.Ctx(ctx).Create(&log).Error; err != nil {
+		logger.Ctx(ctx).Error("写入审计日志失败",
+			zap.String("action", entry.Action),
+			zap.String("target", entry.Target),
+			zap.Error(err),
+		)
+	}
+}
+
+// List 分页查询审计日志
+func (s *AuditService) List(ctx context.Context, query *AuditQuery) ([]model.AuditLog, int64, error) {
+	var logs []model.AuditLog
+	var total int64
+
+	query.Normalize()
+	db := s.filter(ctx, query)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&logs).Error
+	return logs, total, err
+}
+
+// Export 按条件导出审计日志，逐批回调避免一次性加载全部数据
+func (s *AuditService) Export(ctx context.Context, query *AuditQuery, fn func([]model.AuditLog) error) error {
+	var batch []model.AuditLog
+	exported := 0
+
+	return s.filter(ctx, query).Order("id DESC").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		if exported >= maxAuditExport {
+			return errAuditExportLimit
+		}
+		exported += len(batch)
+		return fn(batch)
+	}).Error
+}
+
+// filter 构建审计日志查询条件
+func (s *AuditService) filter(ctx context.Context, query *AuditQuery) *gorm.DB {
+	db := database.Ctx(ctx).Model(&model.AuditLog{})
+
+	if query.UserID != 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Action != "" {
+		db = db.Where("action LIKE ?", "%"+query.Action+"%")
+	}
+	if query.TargetType != "" {
+		db = db.Where("target_type = ?", query.TargetType)
+	}
+	if query.Target != "" {
+		db = db.Where("target LIKE ?", "%"+query.Target+"%")
+	}
+	if query.Instance != "" {
+		db = db.Where("instance = ?", query.Instance)
+	}
+	if query.IP != "" {
+		db = db.Where("ip = ?", query.IP)
+	}
+	if query.Result != "" {
+		db = db.Where("result = ?", query.Result)
+	}
+	if !query.Start.IsZero() {
+		db = db.Where("created_at >= ?", query.Start)
+	}
+	if !query.End.IsZero() {
+		db = db.Where("created_at <= ?", query.End)
+	}
+
+	return db
+}
+
+// cd2MutatingMethods 需要审计的CD2修改类方法
+var cd2MutatingMethods = map[string]bool{
+	pb.CloudDriveFileSrv_CreateFolder_FullMethodName:                    true,
+	pb.CloudDriveFileSrv_RenameFile_FullMethodName:                      true,
+	pb.CloudDriveFileSrv_RenameFiles_FullMethodName:                     true,
+	pb.CloudDriveFileSrv_MoveFile_FullMethodName:                        true,
+	pb.CloudDriveFileSrv_CopyFile_FullMethodName:                        true,
+	pb.CloudDriveFileSrv_DeleteFile_FullMethodName:                      true,
+	pb.CloudDriveFileSrv_DeleteFilePermanently_FullMethodName:           true,
+	pb.CloudDriveFileSrv_DeleteFiles_FullMethodName:                     true,
+	pb.CloudDriveFileSrv_DeleteFilesPermanently_FullMethodName:          true,
+	pb.CloudDriveFileSrv_AddOfflineFiles_FullMethodName:                 true,
+	pb.CloudDriveFileSrv_RemoveOfflineFiles_FullMethodName:              true,
+	pb.CloudDriveFileSrv_AddMountPoint_FullMethodName:                   true,
+	pb.CloudDriveFileSrv_RemoveMountPoint_FullMethodName:                true,
+	pb.CloudDriveFileSrv_Mount_FullMethodName:                           true,
+	pb.CloudDriveFileSrv_Unmount_FullMethodName:                         true,
+	pb.CloudDriveFileSrv_UpdateMountPoint_FullMethodName:                true,
+	pb.CloudDriveFileSrv_CancelAllUploadFiles_FullMethodName:            true,
+	pb.CloudDriveFileSrv_CancelUploadFiles_FullMethodName:               true,
+	pb.CloudDriveFileSrv_PauseAllUploadFiles_FullMethodName:             true,
+	pb.CloudDriveFileSrv_PauseUploadFiles_FullMethodName:                true,
+	pb.CloudDriveFileSrv_ResumeAllUploadFiles_FullMethodName:            true,
+	pb.CloudDriveFileSrv_ResumeUploadFiles_FullMethodName:               true,
+	pb.CloudDriveFileSrv_CancelCopyTask_FullMethodName:                  true,
+	pb.CloudDriveFileSrv_PauseCopyTask_FullMethodName:                   true,
+	pb.CloudDriveFileSrv_RestartCopyTask_FullMethodName:                 true,
+	pb.CloudDriveFileSrv_RemoveCompletedCopyTasks_FullMethodName:        true,
+	pb.CloudDriveFileSrv_APILoginWebDav_FullMethodName:                  true,
+	pb.CloudDriveFileSrv_APIAddLocalFolder_FullMethodName:               true,
+	pb.CloudDriveFileSrv_APILogin115Editthiscookie_FullMethodName:       true,
+	pb.CloudDriveFileSrv_APILoginAliyundriveOAuth_FullMethodName:        true,
+	pb.CloudDriveFileSrv_APILoginAliyundriveRefreshtoken_FullMethodName: true,
+	pb.CloudDriveFileSrv_APILoginBaiduPanOAuth_FullMethodName:           true,
+	pb.CloudDriveFileSrv_APILoginOneDriveOAuth_FullMethodName:           true,
+	pb.CloudDriveFileSrv_ApiLoginGoogleDriveOAuth_FullMethodName:        true,
+	pb.CloudDriveFileSrv_ApiLoginGoogleDriveRefreshToken_FullMethodName: true,
+	pb.CloudDriveFileSrv_ApiLoginXunleiOAuth_FullMethodName:             true,
+	pb.CloudDriveFileSrv_ApiLogin123PanOAuth_FullMethodName:             true,
+	pb.CloudDriveFileSrv_RemoveCloudAPI_FullMethodName:                  true,
+	pb.CloudDriveFileSrv_SetCloudAPIConfig_FullMethodName:               true,
+	pb.CloudDriveFileSrv_SetSystemSettings_FullMethodName:               true,
+	pb.CloudDriveFileSrv_BackupAdd_FullMethodName:                       true,
+	pb.CloudDriveFileSrv_BackupRemove_FullMethodName:                    true,
+	pb.CloudDriveFileSrv_BackupUpdate_FullMethodName:                    true,
+	pb.CloudDriveFileSrv_BackupAddDestination_FullMethodName:            true,
+	pb.CloudDriveFileSrv_BackupRemoveDestination_FullMethodName:         true,
+	pb.CloudDriveFileSrv_BackupSetEnabled_FullMethodName:                true,
+	pb.CloudDriveFileSrv_BackupUpdateStrategies_FullMethodName:          true,
+	pb.CloudDriveFileSrv_BackupRestartWalkingThrough_FullMethodName:     true,
+	pb.CloudDriveFileSrv_RestartService_FullMethodName:                  true,
+	pb.CloudDriveFileSrv_ShutdownService_FullMethodName:                 true,
+}
+
+// cd2SecretMethods 请求中包含账号密码等凭据的方法，审计时不保存请求快照
+var cd2SecretMethods = map[string]bool{
+	pb.CloudDriveFileSrv_APILoginWebDav_FullMethodName:                  true,
+	pb.CloudDriveFileSrv_SetCloudAPIConfig_FullMethodName:               true,
+	pb.CloudDriveFileSrv_APILogin115Editthiscookie_FullMethodName:       true,
+	pb.CloudDriveFileSrv_APILoginAliyundriveOAuth_FullMethodName:        true,
+	pb.CloudDriveFileSrv_APILoginAliyundriveRefreshtoken_FullMethodName: true,
+	pb.CloudDriveFileSrv_APILoginBaiduPanOAuth_FullMethodName:           true,
+	pb.CloudDriveFileSrv_APILoginOneDriveOAuth_FullMethodName:           true,
+	pb.CloudDriveFileSrv_ApiLoginGoogleDriveOAuth_FullMethodName:        true,
+	pb.CloudDriveFileSrv_ApiLoginGoogleDriveRefreshToken_FullMethodName: true,
+	pb.CloudDriveFileSrv_ApiLoginXunleiOAuth_FullMethodName:             true,
+	pb.CloudDriveFileSrv_ApiLogin123PanOAuth_FullMethodName:             true,
+}
+
+// AuditUnaryInterceptor 返回记录CD2修改类调用的gRPC客户端拦截器
+func AuditUnaryInterceptor(instance string) grpc.UnaryClientInterceptor {
+	auditService := &AuditService{}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !cd2MutatingMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		// CD2 的业务失败通过返回值表示，同样记为失败
+		auditErr := err
+		if auditErr == nil {
+			auditErr = cd2ResultError(reply)
+		}
+
+		var before any = req
+		if cd2SecretMethods[method] {
+			before = nil
+		}
+
+		targetType, target := cd2AuditTarget(req)
+		auditService.Record(ctx, AuditEntry{
+			Action:     "cd2." + method[strings.LastIndex(method, "/")+1:],
+			TargetType: targetType,
+			Target:     target,
+			Instance:   instance,
+			Before:     before,
+			After:      reply,
+			Err:        auditErr,
+		})
+
+		return err
+	}
+}
+
+// cd2AuditTarget 从CD2请求中提取审计目标
+func cd2AuditTarget(req any) (string, string) {
+	switch r := req.(type) {
+	case *pb.FileRequest:
+		return model.AuditTargetCD2Path, r.Path
+	case *pb.MultiFileRequest:
+		return model.AuditTargetCD2Path, strings.Join(r.Path, ", ")
+	case *pb.CreateFolderRequest:
+		return model.AuditTargetCD2Path, strings.TrimSuffix(r.ParentPath, "/") + "/" + r.FolderName
+	case *pb.RenameFileRequest:
+		return model.AuditTargetCD2Path, r.TheFilePath
+	case *pb.RenameFilesRequest:
+		paths := make([]string, 0, len(r.RenameFiles))
+		for _, f := range r.RenameFiles {
+			paths = append(paths, f.TheFilePath)
+		}
+		return model.AuditTargetCD2Path, strings.Join(paths, ", ")
+	case *pb.MoveFileRequest:
+		return model.AuditTargetCD2Path, strings.Join(r.TheFilePaths, ", ") + " -> " + r.DestPath
+	case *pb.CopyFileRequest:
+		return model.AuditTargetCD2Path, strings.Join(r.TheFilePaths, ", ") + " -> " + r.DestPath
+	case *pb.CopyTaskRequest:
+		return model.AuditTargetCD2Path, r.SourcePath + " -> " + r.DestPath
+	case *pb.MountOption:
+		return model.AuditTargetCD2, "mount:" + r.MountPoint
+	case *pb.MountPointRequest:
+		return model.AuditTargetCD2, "mount:" + r.MountPoint
+	case *pb.UpdateMountPointRequest:
+		return model.AuditTargetCD2, "mount:" + r.MountPoint
+	case *pb.LoginWebDavRequest:
+		return model.AuditTargetCD2, "webdav:" + r.ServerUrl
+	case *pb.AddLocalFolderRequest:
+		return model.AuditTargetCD2, "local:" + r.LocalFolderPath
+	case *pb.MultpleUploadFileKeyRequest:
+		return model.AuditTargetCD2, "upload:" + strings.Join(r.Keys, ", ")
+	case *pb.PauseCopyTaskRequest:
+		return model.AuditTargetCD2Path, r.SourcePath + " -> " + r.DestPath
+	case *pb.RemoveCloudAPIRequest:
+		return model.AuditTargetCD2, "cloud:" + r.CloudName + "/" + r.UserName
+	case *pb.SetCloudAPIConfigRequest:
+		return model.AuditTargetCD2, "cloud:" + r.CloudName + "/" + r.UserName
+	case *pb.Backup:
+		return model.AuditTargetCD2Path, r.SourcePath
+	case *pb.BackupModifyRequest:
+		return model.AuditTargetCD2Path, r.SourcePath
+	case *pb.BackupSetEnabledRequest:
+		return model.AuditTargetCD2Path, r.SourcePath
+	case *pb.StringValue:
+		return model.AuditTargetCD2Path, r.Value
+	default:
+		return model.AuditTargetCD2, ""
+	}
+}
+
+// cd2ResultError 将CD2返回值中的失败信息转换为错误
+func cd2ResultError(reply any) error {
+	switch r := reply.(type) {
+	case *pb.FileOperationResult:
+		if !r.Success {
+			return errors.New(r.ErrorMessage)
+		}
+	case *pb.MountPointResult:
+		if !r.Success {
+			return errors.New(r.FailReason)
+		}
+	case *pb.APILoginResult:
+		if !r.Success {
+			return errors.New(r.ErrorMessage)
+		}
+	case *pb.CreateFolderResult:
+		if r.Result != nil && !r.Result.Success {
+			return errors.New(r.Result.ErrorMessage)
+		}
+	}
+	return nil
+}
+
+// snapshot 将快照对象序列化为JSON字符串
+func snapshot(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	var data []byte
+	var err error
+	if msg, ok := v.(proto.Message); ok {
+		data, err = protojson.Marshal(msg)
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// truncate 按字节截断字符串，保证不截断多字节字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
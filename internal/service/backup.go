@@ -0,0 +1,789 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/notify"
+	"cinexus/pkg/pb"
+)
+
+// BackupService 备份策略服务
+type BackupService struct{}
+
+// BackupPolicyRequest 创建或修改备份策略请求
+type BackupPolicyRequest struct {
+	Instance             string                 `json:"instance"`
+	Name                 string                 `json:"name"`
+	SourcePath           string                 `json:"source_path" binding:"required"`
+	Destinations         []model.BackupTarget   `json:"destinations" binding:"required"`
+	Rules                []model.BackupRule     `json:"rules"`
+	ReplaceRule          string                 `json:"replace_rule"`
+	DeleteRule           string                 `json:"delete_rule"`
+	CompletionRule       string                 `json:"completion_rule"`
+	Enabled              bool                   `json:"enabled"`
+	FileSystemWatch      bool                   `json:"file_system_watch"`
+	WalkIntervalSecs     int64                  `json:"walk_interval_secs"`
+	ForceWalkOnStart     bool                   `json:"force_walk_on_start"`
+	TimeSchedules        []model.BackupSchedule `json:"time_schedules"`
+	TimeSchedulesEnabled bool                   `json:"time_schedules_enabled"`
+}
+
+// BackupStrategiesRequest 修改备份策略的冲突和删除规则
+type BackupStrategiesRequest struct {
+	ReplaceRule      *string `json:"replace_rule"`
+	DeleteRule       *string `json:"delete_rule"`
+	FileSystemWatch  *bool   `json:"file_system_watch"`
+	WalkIntervalSecs *int64  `json:"walk_interval_secs"`
+}
+
+// BackupPolicyQuery 备份策略查询条件
+type BackupPolicyQuery struct {
+	PageRequest
+	Instance string `form:"instance"`
+}
+
+// BackupHistoryQuery 备份状态历史查询条件
+type BackupHistoryQuery struct {
+	PageRequest
+	Start *time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	End   *time.Time `form:"end" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// BackupState CD2中备份的实时状态
+type BackupState struct {
+	Status         string          `json:"status"`
+	StatusMessage  string          `json:"status_message"`
+	WatcherStatus  string          `json:"watcher_status"`
+	WatcherMessage string          `json:"watcher_message"`
+	Errors         []CopyTaskError `json:"errors"`
+	LastFinish     map[string]any  `json:"last_finish"` // 各目标最后完成时间
+	InSync         bool            `json:"in_sync"`     // CD2中的配置与策略是否一致
+}
+
+// BackupSyncResult 推送策略的结果
+type BackupSyncResult struct {
+	Instance  string   `json:"instance"`
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+	Failed    []string `json:"failed"`
+	Unmanaged []string `json:"unmanaged"` // CD2中存在但没有对应策略的备份
+	Error     string   `json:"error,omitempty"`
+}
+
+var (
+	replaceRules = map[string]pb.FileReplaceRule{
+		"skip":         pb.FileReplaceRule_Skip,
+		"overwrite":    pb.FileReplaceRule_Overwrite,
+		"keep_history": pb.FileReplaceRule_KeepHistoryVersion,
+	}
+	deleteRules = map[string]pb.FileDeleteRule{
+		"delete":          pb.FileDeleteRule_Delete,
+		"recycle":         pb.FileDeleteRule_Recycle,
+		"keep":            pb.FileDeleteRule_Keep,
+		"move_to_history": pb.FileDeleteRule_MoveToVersionHistory,
+	}
+	completionRules = map[string]pb.FileCompletionRule{
+		"none":                           pb.FileCompletionRule_None,
+		"delete_source":                  pb.FileCompletionRule_DeleteSource,
+		"delete_source_and_empty_folder": pb.FileCompletionRule_DeleteSourceAndEmptyFolder,
+	}
+)
+
+// ErrBackupNotFound 备份策略不存在
+var ErrBackupNotFound error = &NotFoundError{Resource: "备份策略"}
+
+// List 分页查询备份策略
+func (s *BackupService) List(ctx context.Context, query *BackupPolicyQuery) ([]model.BackupPolicy, int64, error) {
+	var policies []model.BackupPolicy
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.BackupPolicy{})
+	if query.Instance != "" {
+		db = db.Where("instance = ?", query.Instance)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id").Offset(query.Offset()).Limit(query.PageSize).Find(&policies).Error
+	return policies, total, err
+}
+
+// Get 获取备份策略
+func (s *BackupService) Get(ctx context.Context, id uint) (*model.BackupPolicy, error) {
+	var policy model.BackupPolicy
+	if err := database.Ctx(ctx).First(&policy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBackupNotFound
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Create 创建备份策略并推送到CD2，CD2中已有相同源目录的备份时以策略覆盖
+func (s *BackupService) Create(ctx context.Context, req *BackupPolicyRequest) (*model.BackupPolicy, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &model.BackupPolicy{Instance: client.Name, CreatedBy: actor.FromContext(ctx).UserID}
+	if err := applyBackupRequest(policy, req); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	database.Ctx(ctx).Model(&model.BackupPolicy{}).Where("instance = ? AND source_path = ?", policy.Instance, policy.SourcePath).Count(&count)
+	if count > 0 {
+		return nil, errors.New("该源目录已有备份策略")
+	}
+
+	if err := database.Ctx(ctx).Create(policy).Error; err != nil {
+		return nil, err
+	}
+
+	if _, err := pushBackup(ctx, client, policy); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// Update 修改备份策略并推送到CD2
+func (s *BackupService) Update(ctx context.Context, id uint, req *BackupPolicyRequest) (*model.BackupPolicy, error) {
+	policy, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cd2.Get(policy.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	oldSource := policy.SourcePath
+	if err := applyBackupRequest(policy, req); err != nil {
+		return nil, err
+	}
+
+	if policy.SourcePath != oldSource {
+		var count int64
+		database.Ctx(ctx).Model(&model.BackupPolicy{}).Where("instance = ? AND source_path = ? AND id <> ?", policy.Instance, policy.SourcePath, id).Count(&count)
+		if count > 0 {
+			return nil, errors.New("该源目录已有备份策略")
+		}
+	}
+
+	if err := database.Ctx(ctx).Save(policy).Error; err != nil {
+		return nil, err
+	}
+
+	// CD2 以源目录标识备份，源目录变化时先删除旧的备份
+	if policy.SourcePath != oldSource {
+		if _, err := client.BackupRemove(ctx, &pb.StringValue{Value: oldSource}); err != nil && !isNotFound(err) {
+			return policy, recordSyncError(policy, err)
+		}
+	}
+
+	if _, err := pushBackup(ctx, client, policy); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// UpdateStrategies 只修改冲突和删除规则，通过 BackupUpdateStrategies 推送到CD2
+func (s *BackupService) UpdateStrategies(ctx context.Context, id uint, req *BackupStrategiesRequest) (*model.BackupPolicy, error) {
+	policy, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cd2.Get(policy.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	modify := &pb.BackupModifyRequest{SourcePath: policy.SourcePath}
+	if req.ReplaceRule != nil {
+		rule, ok := replaceRules[*req.ReplaceRule]
+		if !ok {
+			return nil, fmt.Errorf("不支持的冲突规则: %s", *req.ReplaceRule)
+		}
+		policy.ReplaceRule = *req.ReplaceRule
+		modify.FileReplaceRule = &rule
+	}
+	if req.DeleteRule != nil {
+		rule, ok := deleteRules[*req.DeleteRule]
+		if !ok {
+			return nil, fmt.Errorf("不支持的删除规则: %s", *req.DeleteRule)
+		}
+		policy.DeleteRule = *req.DeleteRule
+		modify.FileDeleteRule = &rule
+	}
+	if req.FileSystemWatch != nil {
+		policy.FileSystemWatch = *req.FileSystemWatch
+		modify.FileSystemWatchEnabled = req.FileSystemWatch
+	}
+	if req.WalkIntervalSecs != nil {
+		if *req.WalkIntervalSecs < 0 {
+			return nil, errors.New("扫描间隔不能为负数")
+		}
+		policy.WalkIntervalSecs = *req.WalkIntervalSecs
+		modify.WalkingThroughIntervalSecs = req.WalkIntervalSecs
+	}
+
+	if err := database.Ctx(ctx).Save(policy).Error; err != nil {
+		return nil, err
+	}
+
+	if _, err := client.BackupUpdateStrategies(ctx, modify); err != nil {
+		return policy, recordSyncError(policy, err)
+	}
+	return policy, recordSyncSuccess(policy)
+}
+
+// SetEnabled 启用或停用备份
+func (s *BackupService) SetEnabled(ctx context.Context, id uint, enabled bool) (*model.BackupPolicy, error) {
+	policy, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cd2.Get(policy.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	policy.Enabled = enabled
+	if err := database.Ctx(ctx).Model(policy).Update("enabled", enabled).Error; err != nil {
+		return nil, err
+	}
+
+	_, err = client.BackupSetEnabled(ctx, &pb.BackupSetEnabledRequest{SourcePath: policy.SourcePath, IsEnabled: enabled})
+	if err != nil {
+		return policy, recordSyncError(policy, err)
+	}
+	return policy, recordSyncSuccess(policy)
+}
+
+// Delete 删除备份策略，同时删除CD2中的备份
+func (s *BackupService) Delete(ctx context.Context, id uint) error {
+	policy, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := cd2.Get(policy.Instance)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.BackupRemove(ctx, &pb.StringValue{Value: policy.SourcePath}); err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", id).Delete(&model.BackupStatusLog{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(policy).Error
+	})
+}
+
+// RestartWalk 立即重新全量扫描源目录
+func (s *BackupService) RestartWalk(ctx context.Context, id uint) error {
+	policy, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := cd2.Get(policy.Instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.BackupRestartWalkingThrough(ctx, &pb.StringValue{Value: policy.SourcePath})
+	return err
+}
+
+// Status 查询CD2中备份的实时状态
+func (s *BackupService) Status(ctx context.Context, id uint) (*BackupState, error) {
+	policy, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cd2.Get(policy.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := client.BackupGetStatus(ctx, &pb.StringValue{Value: policy.SourcePath})
+	if err != nil {
+		return nil, err
+	}
+
+	desired, err := buildBackup(policy)
+	if err != nil {
+		return nil, err
+	}
+	return toBackupState(st, desired), nil
+}
+
+// History 分页查询备份状态历史
+func (s *BackupService) History(ctx context.Context, id uint, query *BackupHistoryQuery) ([]model.BackupStatusLog, int64, error) {
+	var logs []model.BackupStatusLog
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.BackupStatusLog{}).Where("policy_id = ?", id)
+	if query.Start != nil {
+		db = db.Where("created_at >= ?", *query.Start)
+	}
+	if query.End != nil {
+		db = db.Where("created_at < ?", *query.End)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&logs).Error
+	return logs, total, err
+}
+
+// Sync 将实例的所有策略推送到CD2：缺少的添加，配置不一致的更新，CD2中多出的备份只报告不删除
+func (s *BackupService) Sync(ctx context.Context, instance string) BackupSyncResult {
+	result := BackupSyncResult{Instance: instance}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Instance = client.Name
+
+	var policies []model.BackupPolicy
+	if err := database.Ctx(ctx).Where("instance = ?", client.Name).Find(&policies).Error; err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	remote, err := client.BackupGetAll(ctx, &emptypb.Empty{})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	existing := make(map[string]*pb.Backup, len(remote.Backups))
+	for _, b := range remote.Backups {
+		if b.Backup != nil {
+			existing[b.Backup.SourcePath] = b.Backup
+		}
+	}
+
+	managed := make(map[string]bool, len(policies))
+	for i := range policies {
+		p := &policies[i]
+		managed[p.SourcePath] = true
+
+		action, err := pushBackupWith(ctx, client, p, existing[p.SourcePath])
+		switch {
+		case err != nil:
+			result.Failed = append(result.Failed, p.SourcePath)
+		case action == "add":
+			result.Added = append(result.Added, p.SourcePath)
+		case action == "update":
+			result.Updated = append(result.Updated, p.SourcePath)
+		default:
+			result.Unchanged = append(result.Unchanged, p.SourcePath)
+		}
+	}
+
+	for source := range existing {
+		if !managed[source] {
+			result.Unmanaged = append(result.Unmanaged, source)
+		}
+	}
+
+	return result
+}
+
+// StartBackupMonitor 启动时推送所有策略，之后定期采集备份状态并在状态变化时记录历史
+func StartBackupMonitor(ctx context.Context) {
+	interval := time.Duration(config.Get().Backup.MonitorInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	s := &BackupService{}
+	go func() {
+		for _, c := range cd2.All() {
+			if r := s.Sync(ctx, c.Name); r.Error != "" || len(r.Failed) > 0 {
+				logger.Ctx(ctx).Warn("推送备份策略失败", zap.String("instance", r.Instance), zap.String("error", r.Error), zap.Strings("failed", r.Failed))
+			}
+		}
+
+		for {
+			heartbeat(ctx, "backup_monitor", interval)
+			collectBackupStatus(ctx)
+			pruneBackupHistory()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// collectBackupStatus 采集所有策略的备份状态
+func collectBackupStatus(ctx context.Context) {
+	var policies []model.BackupPolicy
+	if err := database.Ctx(ctx).Find(&policies).Error; err != nil {
+		logger.Ctx(ctx).Error("查询备份策略失败", zap.Error(err))
+		return
+	}
+
+	byInstance := make(map[string][]model.BackupPolicy)
+	for _, p := range policies {
+		byInstance[p.Instance] = append(byInstance[p.Instance], p)
+	}
+
+	for instance, list := range byInstance {
+		client, err := cd2.Get(instance)
+		if err != nil {
+			continue
+		}
+
+		remote, err := client.BackupGetAll(ctx, &emptypb.Empty{})
+		if err != nil {
+			logger.Ctx(ctx).Warn("获取CD2备份状态失败", zap.String("instance", instance), zap.Error(err))
+			continue
+		}
+
+		statuses := make(map[string]*pb.BackupStatus, len(remote.Backups))
+		for _, b := range remote.Backups {
+			if b.Backup != nil {
+				statuses[b.Backup.SourcePath] = b
+			}
+		}
+
+		for _, p := range list {
+			entry := model.BackupStatusLog{PolicyID: p.ID}
+			if st, ok := statuses[p.SourcePath]; ok {
+				entry.Status = strings.ToLower(st.Status.String())
+				entry.StatusMessage = truncate(st.StatusMessage, 512)
+				entry.WatcherStatus = strings.ToLower(st.WatcherStatus.String())
+				entry.WatcherMessage = truncate(st.WatcherStatusMessage, 512)
+				entry.ErrorCount = len(st.Errors)
+				if n := len(st.Errors); n > 0 {
+					entry.LastError = truncate(st.Errors[n-1].Message, 512)
+				}
+			} else {
+				entry.Status = "missing"
+				entry.StatusMessage = "CD2中不存在该备份"
+			}
+			recordBackupStatus(&p, &entry)
+		}
+	}
+}
+
+// recordBackupStatus 与上一条记录不同时写入历史，进入错误状态时发送通知
+func recordBackupStatus(policy *model.BackupPolicy, entry *model.BackupStatusLog) {
+	var last model.BackupStatusLog
+	err := database.DB.Where("policy_id = ?", policy.ID).Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		logger.Error("查询备份状态历史失败", zap.Error(err))
+		return
+	}
+
+	if last.ID != 0 && last.Status == entry.Status && last.StatusMessage == entry.StatusMessage &&
+		last.WatcherStatus == entry.WatcherStatus && last.ErrorCount == entry.ErrorCount && last.LastError == entry.LastError {
+		return
+	}
+
+	if err := database.DB.Create(entry).Error; err != nil {
+		logger.Error("记录备份状态失败", zap.Error(err))
+		return
+	}
+
+	failed := entry.Status == "error" || entry.Status == "missing"
+	if failed && last.Status != entry.Status {
+		notify.Send(notify.LevelWarn, "备份异常", fmt.Sprintf("备份策略 %s（%s）状态为 %s: %s %s",
+			policy.Name, policy.SourcePath, entry.Status, entry.StatusMessage, entry.LastError))
+	}
+}
+
+// pruneBackupHistory 删除过期的状态历史
+func pruneBackupHistory() {
+	days := config.Get().Backup.HistoryDays
+	if days <= 0 {
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	if err := database.DB.Where("created_at < ?", before).Delete(&model.BackupStatusLog{}).Error; err != nil {
+		logger.Error("清理备份状态历史失败", zap.Error(err))
+	}
+}
+
+// pushBackup 查询CD2中的备份后推送策略
+func pushBackup(ctx context.Context, client *cd2.Client, policy *model.BackupPolicy) (string, error) {
+	st, err := client.BackupGetStatus(ctx, &pb.StringValue{Value: policy.SourcePath})
+	if err != nil && !isNotFound(err) {
+		return "", recordSyncError(policy, err)
+	}
+
+	var current *pb.Backup
+	if st != nil {
+		current = st.Backup
+	}
+	return pushBackupWith(ctx, client, policy, current)
+}
+
+// pushBackupWith 根据CD2中当前的备份添加或更新，配置一致时不做修改
+func pushBackupWith(ctx context.Context, client *cd2.Client, policy *model.BackupPolicy, current *pb.Backup) (string, error) {
+	desired, err := buildBackup(policy)
+	if err != nil {
+		return "", recordSyncError(policy, err)
+	}
+
+	action := "unchanged"
+	switch {
+	case current == nil || current.SourcePath == "":
+		action = "add"
+		_, err = client.BackupAdd(ctx, desired)
+	case !backupEqual(current, desired):
+		action = "update"
+		_, err = client.BackupUpdate(ctx, desired)
+	}
+	if err != nil {
+		return action, recordSyncError(policy, err)
+	}
+	return action, recordSyncSuccess(policy)
+}
+
+// buildBackup 将策略转换为CD2备份配置
+func buildBackup(p *model.BackupPolicy) (*pb.Backup, error) {
+	b := &pb.Backup{
+		SourcePath:                 p.SourcePath,
+		FileReplaceRule:            replaceRules[p.ReplaceRule],
+		FileDeleteRule:             deleteRules[p.DeleteRule],
+		FileCompletionRule:         completionRules[p.CompletionRule],
+		IsEnabled:                  p.Enabled,
+		FileSystemWatchEnabled:     p.FileSystemWatch,
+		WalkingThroughIntervalSecs: p.WalkIntervalSecs,
+		ForceWalkingThroughOnStart: p.ForceWalkOnStart,
+		IsTimeSchedulesEnabled:     p.TimeSchedulesEnabled,
+	}
+
+	for _, d := range p.Destinations {
+		b.Destinations = append(b.Destinations, &pb.BackupDestination{DestinationPath: d.Path, IsEnabled: d.Enabled})
+	}
+
+	for _, r := range p.Rules {
+		rule := &pb.FileBackupRule{IsEnabled: r.Enabled, IsBlackList: r.BlackList, ApplyToFolder: r.ApplyToFolder}
+		switch r.Type {
+		case model.BackupRuleExtensions:
+			rule.Rule = &pb.FileBackupRule_Extensions{Extensions: r.Value}
+		case model.BackupRuleFileNames:
+			rule.Rule = &pb.FileBackupRule_FileNames{FileNames: r.Value}
+		case model.BackupRuleRegex:
+			rule.Rule = &pb.FileBackupRule_Regex{Regex: r.Value}
+		case model.BackupRuleMinSize:
+			size, err := strconv.ParseUint(r.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("最小文件大小必须是整数: %s", r.Value)
+			}
+			rule.Rule = &pb.FileBackupRule_MinSize{MinSize: size}
+		default:
+			return nil, fmt.Errorf("不支持的规则类型: %s", r.Type)
+		}
+		b.FileBackupRules = append(b.FileBackupRules, rule)
+	}
+
+	for _, t := range p.TimeSchedules {
+		schedule := &pb.TimeSchedule{IsEnabled: t.Enabled, Hour: t.Hour, Minute: t.Minute, Second: t.Second}
+		if len(t.DaysOfWeek) > 0 {
+			schedule.DaysOfWeek = &pb.DaysOfWeek{DaysOfWeek: t.DaysOfWeek}
+		}
+		b.TimeSchedules = append(b.TimeSchedules, schedule)
+	}
+
+	return b, nil
+}
+
+// backupEqual 比较备份配置，忽略CD2维护的目标完成时间
+func backupEqual(current, desired *pb.Backup) bool {
+	c := proto.Clone(current).(*pb.Backup)
+	for _, d := range c.Destinations {
+		d.LastFinishTime = nil
+	}
+	return proto.Equal(c, desired)
+}
+
+// applyBackupRequest 校验请求并写入策略
+func applyBackupRequest(p *model.BackupPolicy, req *BackupPolicyRequest) error {
+	source := cleanPath(req.SourcePath)
+	if source == "/" {
+		return errors.New("源目录不能是根目录")
+	}
+	if len(req.Destinations) == 0 {
+		return errors.New("至少需要一个备份目标")
+	}
+
+	destinations := make([]model.BackupTarget, 0, len(req.Destinations))
+	for _, d := range req.Destinations {
+		dest := cleanPath(d.Path)
+		if dest == source || strings.HasPrefix(dest, source+"/") {
+			return errors.New("备份目标不能是源目录或其子目录")
+		}
+		destinations = append(destinations, model.BackupTarget{Path: dest, Enabled: d.Enabled})
+	}
+
+	for _, r := range req.Rules {
+		switch r.Type {
+		case model.BackupRuleExtensions, model.BackupRuleFileNames:
+		case model.BackupRuleRegex:
+			if _, err := regexp.Compile(r.Value); err != nil {
+				return fmt.Errorf("正则表达式错误: %w", err)
+			}
+		case model.BackupRuleMinSize:
+			if _, err := strconv.ParseUint(r.Value, 10, 64); err != nil {
+				return fmt.Errorf("最小文件大小必须是整数: %s", r.Value)
+			}
+		default:
+			return fmt.Errorf("不支持的规则类型: %s", r.Type)
+		}
+	}
+
+	for _, t := range req.TimeSchedules {
+		if t.Hour > 23 || t.Minute > 59 || t.Second > 59 {
+			return errors.New("定时备份时间错误")
+		}
+		for _, d := range t.DaysOfWeek {
+			if d > 6 {
+				return errors.New("星期取值为0到6，周日为0")
+			}
+		}
+	}
+
+	replaceRule := defaultString(req.ReplaceRule, "skip")
+	if _, ok := replaceRules[replaceRule]; !ok {
+		return fmt.Errorf("不支持的冲突规则: %s", replaceRule)
+	}
+	deleteRule := defaultString(req.DeleteRule, "keep")
+	if _, ok := deleteRules[deleteRule]; !ok {
+		return fmt.Errorf("不支持的删除规则: %s", deleteRule)
+	}
+	completionRule := defaultString(req.CompletionRule, "none")
+	if _, ok := completionRules[completionRule]; !ok {
+		return fmt.Errorf("不支持的完成规则: %s", completionRule)
+	}
+	if req.WalkIntervalSecs < 0 {
+		return errors.New("扫描间隔不能为负数")
+	}
+
+	p.Name = req.Name
+	if p.Name == "" {
+		p.Name = source
+	}
+	p.SourcePath = source
+	p.Destinations = destinations
+	p.Rules = req.Rules
+	p.ReplaceRule = replaceRule
+	p.DeleteRule = deleteRule
+	p.CompletionRule = completionRule
+	p.Enabled = req.Enabled
+	p.FileSystemWatch = req.FileSystemWatch
+	p.WalkIntervalSecs = req.WalkIntervalSecs
+	p.ForceWalkOnStart = req.ForceWalkOnStart
+	p.TimeSchedules = req.TimeSchedules
+	p.TimeSchedulesEnabled = req.TimeSchedulesEnabled
+	return nil
+}
+
+// toBackupState 转换CD2备份状态
+func toBackupState(st *pb.BackupStatus, desired *pb.Backup) *BackupState {
+	state := &BackupState{
+		Status:         strings.ToLower(st.Status.String()),
+		StatusMessage:  st.StatusMessage,
+		WatcherStatus:  strings.ToLower(st.WatcherStatus.String()),
+		WatcherMessage: st.WatcherStatusMessage,
+		Errors:         make([]CopyTaskError, 0, len(st.Errors)),
+		LastFinish:     make(map[string]any),
+		InSync:         st.Backup != nil && backupEqual(st.Backup, desired),
+	}
+
+	for _, e := range st.Errors {
+		item := CopyTaskError{Message: e.Message}
+		if e.Time != nil {
+			t := e.Time.AsTime()
+			item.Time = &t
+		}
+		state.Errors = append(state.Errors, item)
+	}
+
+	if st.Backup != nil {
+		for _, d := range st.Backup.Destinations {
+			if d.LastFinishTime != nil {
+				state.LastFinish[d.DestinationPath] = d.LastFinishTime.AsTime()
+			} else {
+				state.LastFinish[d.DestinationPath] = nil
+			}
+		}
+	}
+	return state
+}
+
+// recordSyncSuccess 记录推送成功
+func recordSyncSuccess(p *model.BackupPolicy) error {
+	now := time.Now()
+	p.SyncedAt = &now
+	p.SyncError = ""
+	return database.DB.Model(p).Select("synced_at", "sync_error").Updates(p).Error
+}
+
+// recordSyncError 记录推送失败并返回原错误
+func recordSyncError(p *model.BackupPolicy, err error) error {
+	p.SyncError = truncate(err.Error(), 512)
+	if p.ID != 0 {
+		database.DB.Model(p).Update("sync_error", p.SyncError)
+	}
+	return err
+}
+
+// isNotFound 判断CD2返回的错误是否为不存在
+func isNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// defaultString 为空时返回默认值
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
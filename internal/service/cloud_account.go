@@ -0,0 +1,512 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/internal/cd2"
+	"cinexus/pkg/pb"
+)
+
+// CloudAccountService 云盘账号管理服务
+type CloudAccountService struct{}
+
+// CloudAccount 云盘账号信息
+type CloudAccount struct {
+	Instance                    string `json:"instance"`
+	CloudName                   string `json:"cloud_name"`
+	UserName                    string `json:"user_name"`
+	NickName                    string `json:"nick_name"`
+	IsLocked                    bool   `json:"is_locked"` // 会员问题导致无法打开文件
+	SupportMultiThreadUploading bool   `json:"support_multi_thread_uploading"`
+	SupportQpsLimit             bool   `json:"support_qps_limit"`
+	EventListenerRunning        bool   `json:"event_listener_running"`
+}
+
+// CloudAccountRef 云盘账号标识
+type CloudAccountRef struct {
+	CloudName string `json:"cloud_name" form:"cloud_name" binding:"required"`
+	UserName  string `json:"user_name" form:"user_name" binding:"required"`
+}
+
+// CloudProxyConfig 代理设置
+type CloudProxyConfig struct {
+	Type        string `json:"type"` // system, none, http, socks5
+	Host        string `json:"host"`
+	Port        uint32 `json:"port"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty" redact:"true"` // 查询时不返回，修改时为空表示保持不变
+	PasswordSet bool   `json:"password_set"`
+}
+
+// CloudAccountConfig 云盘账号设置
+type CloudAccountConfig struct {
+	MaxDownloadThreads  uint32            `json:"max_download_threads"`
+	MaxUploadThreads    *uint32           `json:"max_upload_threads"`
+	MinReadLengthKB     uint64            `json:"min_read_length_kb"`
+	MaxReadLengthKB     uint64            `json:"max_read_length_kb"`
+	DefaultReadLengthKB uint64            `json:"default_read_length_kb"`
+	MaxBufferPoolSizeMB uint64            `json:"max_buffer_pool_size_mb"`
+	MaxQueriesPerSecond float64           `json:"max_queries_per_second"`
+	ForceIPv4           bool              `json:"force_ipv4"`
+	APIProxy            *CloudProxyConfig `json:"api_proxy"`
+	DataProxy           *CloudProxyConfig `json:"data_proxy"`
+	CustomUserAgent     *string           `json:"custom_user_agent"`
+}
+
+// SetCloudConfigRequest 修改云盘账号设置请求
+type SetCloudConfigRequest struct {
+	CloudAccountRef
+	Config CloudAccountConfig `json:"config" binding:"required"`
+}
+
+// RemoveCloudRequest 删除云盘账号请求
+type RemoveCloudRequest struct {
+	CloudAccountRef
+	Permanent bool `json:"permanent" form:"permanent"` // 同时删除CD2保存的登录信息
+}
+
+// WebDavLoginRequest 添加WebDAV账号请求
+type WebDavLoginRequest struct {
+	ServerURL string `json:"server_url" binding:"required"`
+	UserName  string `json:"user_name" binding:"required"`
+	Password  string `json:"password" redact:"true"`
+}
+
+// LocalFolderRequest 添加本地目录请求
+type LocalFolderRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// TokenLoginRequest 使用令牌登录云盘，不同云盘使用的字段不同
+type TokenLoginRequest struct {
+	RefreshToken string `json:"refresh_token" redact:"true"`
+	AccessToken  string `json:"access_token" redact:"true"`
+	ExpiresIn    uint64 `json:"expires_in"`
+	ClientID     string `json:"client_id"`                   // google_refresh
+	ClientSecret string `json:"client_secret" redact:"true"` // google_refresh
+	Cookie       string `json:"cookie" redact:"true"`        // 115，EditThisCookie 导出的字符串
+	UseOpenAPI   bool   `json:"use_open_api"`                // aliyun
+}
+
+// QRCodeLoginRequest 扫码登录请求
+type QRCodeLoginRequest struct {
+	UseOpenAPI bool   `json:"use_open_api"` // aliyun
+	Platform   string `json:"platform"`     // 115，模拟登录的客户端
+}
+
+// QRCodeMessage 扫码登录过程中的消息
+type QRCodeMessage struct {
+	Type    string `json:"type"` // image, image_content, status, close, error
+	Message string `json:"message"`
+}
+
+// 扫码消息类型
+var qrCodeMessageTypes = map[pb.QRCodeScanMessageType]string{
+	pb.QRCodeScanMessageType_SHOW_IMAGE:         "image",
+	pb.QRCodeScanMessageType_SHOW_IMAGE_CONTENT: "image_content",
+	pb.QRCodeScanMessageType_CHANGE_STATUS:      "status",
+	pb.QRCodeScanMessageType_CLOSE:              "close",
+	pb.QRCodeScanMessageType_ERROR:              "error",
+}
+
+// 代理类型
+var proxyTypes = map[string]pb.ProxyType{
+	"system": pb.ProxyType_SYSTEM,
+	"none":   pb.ProxyType_NOPROXY,
+	"http":   pb.ProxyType_HTTP,
+	"socks5": pb.ProxyType_SOCKS5,
+}
+
+// ErrUnsupportedProvider 不支持的云盘登录方式
+var ErrUnsupportedProvider = errors.New("不支持的登录方式")
+
+// List 获取已添加的云盘账号
+func (s *CloudAccountService) List(ctx context.Context, instance string) ([]CloudAccount, error) {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.GetAllCloudApis(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]CloudAccount, 0, len(result.Apis))
+	for _, api := range result.Apis {
+		accounts = append(accounts, CloudAccount{
+			Instance:                    client.Name,
+			CloudName:                   api.Name,
+			UserName:                    api.UserName,
+			NickName:                    api.NickName,
+			IsLocked:                    api.IsLocked,
+			SupportMultiThreadUploading: api.SupportMultiThreadUploading,
+			SupportQpsLimit:             api.SupportQpsLimit,
+			EventListenerRunning:        api.IsCloudEventListenerRunning,
+		})
+	}
+
+	return accounts, nil
+}
+
+// CanAddMore 检查是否还能添加云盘账号
+func (s *CloudAccountService) CanAddMore(ctx context.Context, instance string) (bool, string, error) {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return false, "", err
+	}
+
+	result, err := client.CanAddMoreCloudApis(ctx, &emptypb.Empty{})
+	if err != nil {
+		return false, "", err
+	}
+	return result.Success, result.ErrorMessage, nil
+}
+
+// GetConfig 获取云盘账号设置，代理密码不返回
+func (s *CloudAccountService) GetConfig(ctx context.Context, instance string, ref *CloudAccountRef) (*CloudAccountConfig, error) {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.GetCloudAPIConfig(ctx, &pb.GetCloudAPIConfigRequest{
+		CloudName: ref.CloudName,
+		UserName:  ref.UserName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudAccountConfig{
+		MaxDownloadThreads:  result.MaxDownloadThreads,
+		MaxUploadThreads:    result.MaxUploadThreads,
+		MinReadLengthKB:     result.MinReadLengthKB,
+		MaxReadLengthKB:     result.MaxReadLengthKB,
+		DefaultReadLengthKB: result.DefaultReadLengthKB,
+		MaxBufferPoolSizeMB: result.MaxBufferPoolSizeMB,
+		MaxQueriesPerSecond: result.MaxQueriesPerSecond,
+		ForceIPv4:           result.ForceIpv4,
+		APIProxy:            proxyFromProto(result.ApiProxy),
+		DataProxy:           proxyFromProto(result.DataProxy),
+		CustomUserAgent:     result.CustomUserAgent,
+	}, nil
+}
+
+// SetConfig 修改云盘账号设置，代理密码为空时沿用原密码
+func (s *CloudAccountService) SetConfig(ctx context.Context, instance string, req *SetCloudConfigRequest) error {
+	cfg := &req.Config
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	current, err := client.GetCloudAPIConfig(ctx, &pb.GetCloudAPIConfigRequest{
+		CloudName: req.CloudName,
+		UserName:  req.UserName,
+	})
+	if err != nil {
+		return err
+	}
+
+	apiProxy, err := proxyToProto(cfg.APIProxy, current.ApiProxy)
+	if err != nil {
+		return err
+	}
+	dataProxy, err := proxyToProto(cfg.DataProxy, current.DataProxy)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.SetCloudAPIConfig(ctx, &pb.SetCloudAPIConfigRequest{
+		CloudName: req.CloudName,
+		UserName:  req.UserName,
+		Config: &pb.CloudAPIConfig{
+			MaxDownloadThreads:  cfg.MaxDownloadThreads,
+			MaxUploadThreads:    cfg.MaxUploadThreads,
+			MinReadLengthKB:     cfg.MinReadLengthKB,
+			MaxReadLengthKB:     cfg.MaxReadLengthKB,
+			DefaultReadLengthKB: cfg.DefaultReadLengthKB,
+			MaxBufferPoolSizeMB: cfg.MaxBufferPoolSizeMB,
+			MaxQueriesPerSecond: cfg.MaxQueriesPerSecond,
+			ForceIpv4:           cfg.ForceIPv4,
+			ApiProxy:            apiProxy,
+			DataProxy:           dataProxy,
+			CustomUserAgent:     cfg.CustomUserAgent,
+		},
+	})
+	return err
+}
+
+// Remove 删除云盘账号
+func (s *CloudAccountService) Remove(ctx context.Context, instance string, req *RemoveCloudRequest) error {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.RemoveCloudAPI(ctx, &pb.RemoveCloudAPIRequest{
+		CloudName:       req.CloudName,
+		UserName:        req.UserName,
+		PermanentRemove: req.Permanent,
+	})
+	if err != nil {
+		return err
+	}
+	return checkResult("删除云盘账号", result)
+}
+
+// LoginWebDav 添加WebDAV账号
+func (s *CloudAccountService) LoginWebDav(ctx context.Context, instance string, req *WebDavLoginRequest) error {
+	u, err := url.Parse(req.ServerURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("WebDAV地址必须是 http 或 https 地址")
+	}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.APILoginWebDav(ctx, &pb.LoginWebDavRequest{
+		ServerUrl: req.ServerURL,
+		UserName:  req.UserName,
+		Password:  req.Password,
+	})
+	if err != nil {
+		return err
+	}
+	return checkLoginResult("添加WebDAV账号", result)
+}
+
+// AddLocalFolder 添加本地目录
+func (s *CloudAccountService) AddLocalFolder(ctx context.Context, instance string, req *LocalFolderRequest) error {
+	// Windows 下的CD2使用盘符路径，如 D:\data
+	if !strings.HasPrefix(req.Path, "/") && !(len(req.Path) >= 2 && req.Path[1] == ':') {
+		return errors.New("本地目录必须是绝对路径")
+	}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.APIAddLocalFolder(ctx, &pb.AddLocalFolderRequest{LocalFolderPath: req.Path})
+	if err != nil {
+		return err
+	}
+	return checkLoginResult("添加本地目录", result)
+}
+
+// LoginToken 使用令牌或Cookie登录云盘
+func (s *CloudAccountService) LoginToken(ctx context.Context, instance, provider string, req *TokenLoginRequest) error {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	if provider == "115" {
+		if req.Cookie == "" {
+			return errors.New("Cookie不能为空")
+		}
+	} else if req.RefreshToken == "" {
+		return errors.New("refresh_token不能为空")
+	}
+
+	var result *pb.APILoginResult
+	switch provider {
+	case "aliyun":
+		result, err = client.APILoginAliyundriveRefreshtoken(ctx, &pb.LoginAliyundriveRefreshtokenRequest{
+			RefreshToken: req.RefreshToken,
+			UseOpenAPI:   req.UseOpenAPI,
+		})
+	case "aliyun_oauth":
+		result, err = client.APILoginAliyundriveOAuth(ctx, &pb.LoginAliyundriveOAuthRequest{
+			RefreshToken: req.RefreshToken, AccessToken: req.AccessToken, ExpiresIn: req.ExpiresIn,
+		})
+	case "baidu":
+		result, err = client.APILoginBaiduPanOAuth(ctx, &pb.LoginBaiduPanOAuthRequest{
+			RefreshToken: req.RefreshToken, AccessToken: req.AccessToken, ExpiresIn: req.ExpiresIn,
+		})
+	case "onedrive":
+		result, err = client.APILoginOneDriveOAuth(ctx, &pb.LoginOneDriveOAuthRequest{
+			RefreshToken: req.RefreshToken, AccessToken: req.AccessToken, ExpiresIn: req.ExpiresIn,
+		})
+	case "google":
+		result, err = client.ApiLoginGoogleDriveOAuth(ctx, &pb.LoginGoogleDriveOAuthRequest{
+			RefreshToken: req.RefreshToken, AccessToken: req.AccessToken, ExpiresIn: req.ExpiresIn,
+		})
+	case "google_refresh":
+		if req.ClientID == "" || req.ClientSecret == "" {
+			return errors.New("client_id和client_secret不能为空")
+		}
+		result, err = client.ApiLoginGoogleDriveRefreshToken(ctx, &pb.LoginGoogleDriveRefreshTokenRequest{
+			ClientId: req.ClientID, ClientSecret: req.ClientSecret, RefreshToken: req.RefreshToken,
+		})
+	case "xunlei":
+		result, err = client.ApiLoginXunleiOAuth(ctx, &pb.LoginXunleiOAuthRequest{
+			RefreshToken: req.RefreshToken, AccessToken: req.AccessToken, ExpiresIn: req.ExpiresIn,
+		})
+	case "123pan":
+		result, err = client.ApiLogin123PanOAuth(ctx, &pb.Login123PanOAuthRequest{
+			RefreshToken: req.RefreshToken, AccessToken: req.AccessToken, ExpiresIn: req.ExpiresIn,
+		})
+	case "115":
+		result, err = client.APILogin115Editthiscookie(ctx, &pb.Login115EditthiscookieRequest{
+			EditThiscookieString: req.Cookie,
+		})
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedProvider, provider)
+	}
+	if err != nil {
+		return err
+	}
+	return checkLoginResult("登录云盘", result)
+}
+
+// QRCodeLogin 扫码登录，将CD2推送的二维码和扫码状态逐条交给 fn，直到登录结束或 ctx 取消
+func (s *CloudAccountService) QRCodeLogin(ctx context.Context, instance, provider string, req *QRCodeLoginRequest, fn func(QRCodeMessage)) error {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	// 收到 CLOSE 后不再读取到流末尾，返回时取消上下文以结束流
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stream interface {
+		Recv() (*pb.QRCodeScanMessage, error)
+	}
+	switch provider {
+	case "aliyun":
+		stream, err = client.APILoginAliyunDriveQRCode(ctx, &pb.LoginAliyundriveQRCodeRequest{UseOpenAPI: req.UseOpenAPI})
+	case "115":
+		r := &pb.Login115QrCodeRequest{}
+		if req.Platform != "" {
+			r.PlatformString = &req.Platform
+		}
+		stream, err = client.APILogin115QRCode(ctx, r)
+	case "115open":
+		stream, err = client.APILogin115OpenQRCode(ctx, &emptypb.Empty{})
+	case "189":
+		stream, err = client.APILogin189QRCode(ctx, &emptypb.Empty{})
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedProvider, provider)
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		typ, ok := qrCodeMessageTypes[msg.MessageType]
+		if !ok {
+			typ = "status"
+		}
+		fn(QRCodeMessage{Type: typ, Message: msg.Message})
+
+		switch msg.MessageType {
+		case pb.QRCodeScanMessageType_CLOSE:
+			return nil
+		case pb.QRCodeScanMessageType_ERROR:
+			return &FileOperationError{Operation: "扫码登录", Message: msg.Message}
+		}
+	}
+}
+
+// Validate 校验云盘账号设置
+func (c *CloudAccountConfig) Validate() error {
+	if c.MaxReadLengthKB > 0 && c.MinReadLengthKB > c.MaxReadLengthKB {
+		return errors.New("最小读取长度不能大于最大读取长度")
+	}
+	if c.DefaultReadLengthKB > 0 && (c.DefaultReadLengthKB < c.MinReadLengthKB ||
+		(c.MaxReadLengthKB > 0 && c.DefaultReadLengthKB > c.MaxReadLengthKB)) {
+		return errors.New("默认读取长度必须在最小和最大读取长度之间")
+	}
+	if c.MaxQueriesPerSecond < 0 {
+		return errors.New("每秒最大请求数不能为负数")
+	}
+	for _, p := range []*CloudProxyConfig{c.APIProxy, c.DataProxy} {
+		if p == nil {
+			continue
+		}
+		if _, ok := proxyTypes[p.Type]; !ok {
+			return fmt.Errorf("代理类型错误: %s", p.Type)
+		}
+		if (p.Type == "http" || p.Type == "socks5") && (p.Host == "" || p.Port == 0 || p.Port > 65535) {
+			return errors.New("代理地址和端口不能为空")
+		}
+	}
+	return nil
+}
+
+// proxyFromProto 转换CD2代理设置，不返回密码
+func proxyFromProto(p *pb.ProxyInfo) *CloudProxyConfig {
+	if p == nil {
+		return nil
+	}
+
+	proxy := &CloudProxyConfig{
+		Host:        p.Host,
+		Port:        p.Port,
+		Username:    p.GetUsername(),
+		PasswordSet: p.GetPassword() != "",
+	}
+	for name, typ := range proxyTypes {
+		if typ == p.ProxyType {
+			proxy.Type = name
+		}
+	}
+	return proxy
+}
+
+// proxyToProto 转换为CD2代理设置，密码为空时沿用 current 中的密码
+func proxyToProto(p *CloudProxyConfig, current *pb.ProxyInfo) (*pb.ProxyInfo, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	typ, ok := proxyTypes[p.Type]
+	if !ok {
+		return nil, fmt.Errorf("代理类型错误: %s", p.Type)
+	}
+
+	proxy := &pb.ProxyInfo{ProxyType: typ, Host: p.Host, Port: p.Port}
+	if p.Username != "" {
+		proxy.Username = &p.Username
+	}
+	switch {
+	case p.Password != "":
+		proxy.Password = &p.Password
+	case current != nil && current.Password != nil && current.GetUsername() == p.Username:
+		proxy.Password = current.Password
+	}
+	return proxy, nil
+}
+
+// checkLoginResult 检查CD2登录结果
+func checkLoginResult(op string, result *pb.APILoginResult) error {
+	if result == nil || result.Success {
+		return nil
+	}
+	return &FileOperationError{Operation: op, Message: result.ErrorMessage}
+}
@@ -0,0 +1,652 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+)
+
+// DedupeService 重复文件查找服务
+type DedupeService struct{}
+
+// CreateDedupeScanRequest 创建重复文件扫描请求
+type CreateDedupeScanRequest struct {
+	Instance   string             `json:"instance"`
+	Roots      []model.DedupeRoot `json:"roots" binding:"required,min=1"` // 按优先级排列，默认保留靠前目录中的文件
+	MinSize    int64              `json:"min_size" binding:"min=0"`       // 忽略小于该大小的文件
+	Extensions []string           `json:"extensions"`                     // 只扫描这些扩展名，为空表示全部
+}
+
+// DedupeScanQuery 扫描任务查询条件
+type DedupeScanQuery struct {
+	PageRequest
+	Instance string `form:"instance"`
+	Status   string `form:"status"`
+}
+
+// DedupeGroupQuery 重复分组查询条件
+type DedupeGroupQuery struct {
+	PageRequest
+	Status  string `form:"status"`
+	MatchBy string `form:"match_by"`
+}
+
+// ReviewDedupeGroupRequest 确认重复分组请求
+type ReviewDedupeGroupRequest struct {
+	KeepFileID uint `json:"keep_file_id"`
+	Ignore     bool `json:"ignore"` // 不处理该分组
+}
+
+// ApplyDedupeRequest 清理重复文件请求
+type ApplyDedupeRequest struct {
+	GroupIDs []uint `json:"group_ids"` // 为空表示扫描任务中所有已确认的分组
+}
+
+// DedupeApplyResult 清理结果
+type DedupeApplyResult struct {
+	Groups     int      `json:"groups"`
+	Trashed    int      `json:"trashed"`
+	FreedBytes int64    `json:"freed_bytes"`
+	Failed     []string `json:"failed"`
+}
+
+// dedupeCandidate 扫描到的文件
+type dedupeCandidate struct {
+	FileInfo
+	Library  string
+	priority int
+}
+
+// 正在执行的扫描任务
+var dedupeScans = struct {
+	sync.Mutex
+	ctx     context.Context
+	cancels map[uint]context.CancelFunc
+}{
+	ctx:     context.Background(),
+	cancels: make(map[uint]context.CancelFunc),
+}
+
+// StartDedupe 设置扫描任务的根上下文，并将上次服务退出时未完成的扫描标记为中断
+func StartDedupe(ctx context.Context) {
+	dedupeScans.Lock()
+	dedupeScans.ctx = ctx
+	dedupeScans.Unlock()
+
+	err := database.Ctx(ctx).Model(&model.DedupeScan{}).Where("status = ?", model.DedupeStatusScanning).
+		Updates(map[string]interface{}{
+			"status": model.DedupeStatusCancelled,
+			"error":  "服务重启导致扫描中断",
+		}).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("标记中断的重复文件扫描失败", zap.Error(err))
+	}
+}
+
+// Create 创建扫描任务并在后台执行
+func (s *DedupeService) Create(ctx context.Context, req *CreateDedupeScanRequest) (*model.DedupeScan, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]model.DedupeRoot, 0, len(req.Roots))
+	for _, r := range req.Roots {
+		p := cleanPath(r.Path)
+		for _, other := range roots {
+			if p == other.Path || strings.HasPrefix(p, strings.TrimSuffix(other.Path, "/")+"/") ||
+				strings.HasPrefix(other.Path, strings.TrimSuffix(p, "/")+"/") {
+				return nil, fmt.Errorf("扫描目录不能相同或互相包含: %s, %s", other.Path, p)
+			}
+		}
+		if _, exists, err := statFile(ctx, client, p); err != nil {
+			return nil, err
+		} else if !exists {
+			return nil, fmt.Errorf("目录不存在: %s", p)
+		}
+
+		name := strings.TrimSpace(r.Name)
+		if name == "" {
+			name = path.Base(p)
+		}
+		roots = append(roots, model.DedupeRoot{Name: name, Path: p})
+	}
+
+	exts := make([]string, 0, len(req.Extensions))
+	for _, e := range req.Extensions {
+		if e = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(e), ".")); e != "" {
+			exts = append(exts, e)
+		}
+	}
+
+	scan := &model.DedupeScan{
+		UserID:     actor.FromContext(ctx).UserID,
+		Instance:   client.Name,
+		Roots:      roots,
+		MinSize:    req.MinSize,
+		Extensions: exts,
+		Status:     model.DedupeStatusScanning,
+	}
+	if err := database.Ctx(ctx).Create(scan).Error; err != nil {
+		return nil, err
+	}
+
+	dedupeScans.Lock()
+	runCtx, cancel := context.WithCancel(dedupeScans.ctx)
+	dedupeScans.cancels[scan.ID] = cancel
+	dedupeScans.Unlock()
+
+	go func(scan model.DedupeScan) {
+		defer func() {
+			dedupeScans.Lock()
+			delete(dedupeScans.cancels, scan.ID)
+			dedupeScans.Unlock()
+			cancel()
+		}()
+
+		if err := runDedupeScan(runCtx, client, &scan); err != nil {
+			logger.Ctx(ctx).Error("重复文件扫描失败", zap.Uint("scan_id", scan.ID), zap.Error(err))
+		}
+	}(*scan)
+
+	return scan, nil
+}
+
+// List 查询扫描任务
+func (s *DedupeService) List(ctx context.Context, query *DedupeScanQuery) ([]model.DedupeScan, int64, error) {
+	var scans []model.DedupeScan
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.DedupeScan{})
+	if query.Instance != "" {
+		db = db.Where("instance = ?", query.Instance)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&scans).Error
+	return scans, total, err
+}
+
+// Get 获取扫描任务
+func (s *DedupeService) Get(ctx context.Context, id uint) (*model.DedupeScan, error) {
+	var scan model.DedupeScan
+	if err := database.Ctx(ctx).First(&scan, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("扫描任务不存在")
+		}
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// ListGroups 查询扫描任务的重复分组，可释放空间大的分组在前
+func (s *DedupeService) ListGroups(ctx context.Context, id uint, query *DedupeGroupQuery) ([]model.DedupeGroup, int64, error) {
+	var groups []model.DedupeGroup
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.DedupeGroup{}).Where("scan_id = ?", id)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.MatchBy != "" {
+		db = db.Where("match_by = ?", query.MatchBy)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Preload("Files", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("keep DESC, id ASC")
+	}).Order("size * (count - 1) DESC, id ASC").Offset(query.Offset()).Limit(query.PageSize).Find(&groups).Error
+	return groups, total, err
+}
+
+// Cancel 取消扫描
+func (s *DedupeService) Cancel(id uint) error {
+	dedupeScans.Lock()
+	cancel, ok := dedupeScans.cancels[id]
+	dedupeScans.Unlock()
+
+	if !ok {
+		return errors.New("扫描任务未在执行")
+	}
+	cancel()
+	return nil
+}
+
+// Review 确认分组中保留的文件，或标记为不处理
+func (s *DedupeService) Review(ctx context.Context, groupID uint, req *ReviewDedupeGroupRequest) (*model.DedupeGroup, error) {
+	var group model.DedupeGroup
+	if err := database.Ctx(ctx).Preload("Files").First(&group, groupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("分组不存在")
+		}
+		return nil, err
+	}
+	if group.Status == model.DedupeGroupApplied {
+		return nil, errors.New("该分组已清理")
+	}
+
+	if req.Ignore {
+		group.Status = model.DedupeGroupIgnored
+		return &group, database.Ctx(ctx).Model(&group).Update("status", group.Status).Error
+	}
+
+	keepID := req.KeepFileID
+	if keepID == 0 {
+		keepID = group.KeepFileID
+	}
+	found := false
+	for i := range group.Files {
+		group.Files[i].Keep = group.Files[i].ID == keepID
+		if group.Files[i].Keep && group.Files[i].Trashed {
+			return nil, errors.New("保留的文件已移入回收站")
+		}
+		found = found || group.Files[i].Keep
+	}
+	if !found {
+		return nil, errors.New("保留的文件不在该分组中")
+	}
+
+	group.KeepFileID = keepID
+	group.Status = model.DedupeGroupReviewed
+	err := database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.DedupeFile{}).Where("group_id = ?", group.ID).
+			Update("keep", gorm.Expr("id = ?", keepID)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&group).Select("keep_file_id", "status").Updates(&group).Error
+	})
+	return &group, err
+}
+
+// Apply 将已确认分组中保留文件以外的副本移入云盘回收站，执行前重新检查文件
+func (s *DedupeService) Apply(ctx context.Context, id uint, req *ApplyDedupeRequest) (*DedupeApplyResult, error) {
+	scan, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if scan.Status != model.DedupeStatusCompleted {
+		return nil, errors.New("扫描尚未完成")
+	}
+
+	client, err := cd2.Get(scan.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// 部分失败的分组可以再次执行，已移入回收站的文件会跳过
+	db := database.Ctx(ctx).Preload("Files").Where("scan_id = ? AND status IN ?", id, []string{model.DedupeGroupReviewed, model.DedupeGroupPartial})
+	if len(req.GroupIDs) > 0 {
+		db = db.Where("id IN ?", req.GroupIDs)
+	}
+	var groups []model.DedupeGroup
+	if err := db.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("没有已确认的分组，请先确认每组保留的文件")
+	}
+
+	result := &DedupeApplyResult{Failed: []string{}}
+	for i := range groups {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		trashed, freed, failed := applyDedupeGroup(ctx, client, &groups[i])
+		result.Groups++
+		result.Trashed += trashed
+		result.FreedBytes += freed
+		result.Failed = append(result.Failed, failed...)
+	}
+	return result, nil
+}
+
+// applyDedupeGroup 清理单个分组，保留的文件不存在或大小变化时整组跳过
+func applyDedupeGroup(ctx context.Context, client *cd2.Client, group *model.DedupeGroup) (int, int64, []string) {
+	var keep *model.DedupeFile
+	for i := range group.Files {
+		if group.Files[i].Keep {
+			keep = &group.Files[i]
+		}
+	}
+
+	fail := func(msg string) (int, int64, []string) {
+		database.Ctx(ctx).Model(group).Update("status", model.DedupeGroupPartial)
+		return 0, 0, []string{msg}
+	}
+	if keep == nil {
+		return fail(fmt.Sprintf("分组 #%d 没有保留的文件", group.ID))
+	}
+	if f, exists, err := statFile(ctx, client, keep.Path); err != nil {
+		return fail(fmt.Sprintf("%s: %v", keep.Path, err))
+	} else if !exists || f.Size != keep.Size {
+		return fail(fmt.Sprintf("分组 #%d 保留的文件 %s 已不存在或已变化，跳过该分组", group.ID, keep.Path))
+	}
+
+	trashed := 0
+	var freed int64
+	var failed []string
+	for i := range group.Files {
+		file := &group.Files[i]
+		if file.Keep || file.Trashed {
+			continue
+		}
+
+		err := trashDuplicate(ctx, client, file)
+		if err != nil {
+			file.Error = truncate(err.Error(), 512)
+			failed = append(failed, file.Path+": "+err.Error())
+		} else {
+			file.Trashed = true
+			file.Error = ""
+			trashed++
+			freed += file.Size
+		}
+		if err := database.Ctx(ctx).Model(file).Select("trashed", "error").Updates(file).Error; err != nil {
+			logger.Ctx(ctx).Error("保存重复文件状态失败", zap.Uint("file_id", file.ID), zap.Error(err))
+		}
+	}
+
+	group.Status = model.DedupeGroupApplied
+	if len(failed) > 0 {
+		group.Status = model.DedupeGroupPartial
+	}
+	database.Ctx(ctx).Model(group).Update("status", group.Status)
+	return trashed, freed, failed
+}
+
+// trashDuplicate 确认文件未变化后移入回收站
+func trashDuplicate(ctx context.Context, client *cd2.Client, file *model.DedupeFile) error {
+	f, exists, err := statFile(ctx, client, file.Path)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("文件已不存在")
+	}
+	if f.Size != file.Size {
+		return errors.New("文件大小已变化")
+	}
+
+	_, err = moveToTrash(ctx, client, file.Path, model.TrashSourceDedupe, actor.FromContext(ctx).UserID)
+	return err
+}
+
+// runDedupeScan 扫描所有目录并保存重复分组
+func runDedupeScan(ctx context.Context, client *cd2.Client, scan *model.DedupeScan) error {
+	exts := make(map[string]bool, len(scan.Extensions))
+	for _, e := range scan.Extensions {
+		exts[e] = true
+	}
+
+	var files []dedupeCandidate
+	var err error
+	for i, root := range scan.Roots {
+		if files, err = walkDedupeRoot(ctx, client, root, i, scan.MinSize, exts, files); err != nil {
+			break
+		}
+	}
+
+	var groups []model.DedupeGroup
+	if err == nil {
+		groups = groupDuplicates(files)
+		err = database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+			for i := range groups {
+				groups[i].ScanID = scan.ID
+				if err := tx.Omit("Files").Create(&groups[i]).Error; err != nil {
+					return err
+				}
+				for j := range groups[i].Files {
+					groups[i].Files[j].GroupID = groups[i].ID
+				}
+				if err := tx.CreateInBatches(groups[i].Files, 100).Error; err != nil {
+					return err
+				}
+				keep := groups[i].Files[0].ID
+				for _, f := range groups[i].Files {
+					if f.Keep {
+						keep = f.ID
+					}
+				}
+				if err := tx.Model(&groups[i]).Update("keep_file_id", keep).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	now := time.Now()
+	scan.FinishedAt = &now
+	scan.Files = len(files)
+	scan.Groups = len(groups)
+	scan.WastedBytes = 0
+	for _, g := range groups {
+		scan.WastedBytes += g.Size * int64(g.Count-1)
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		scan.Status = model.DedupeStatusCancelled
+		scan.Error = "扫描已取消"
+	case err != nil:
+		scan.Status = model.DedupeStatusFailed
+		scan.Error = truncate(err.Error(), 512)
+	default:
+		scan.Status = model.DedupeStatusCompleted
+	}
+
+	if saveErr := database.Ctx(ctx).Model(scan).Select("status", "files", "groups", "wasted_bytes", "error", "finished_at").Updates(scan).Error; saveErr != nil {
+		return saveErr
+	}
+	metrics.ObserveJob("dedupe_scan", scan.Status, scan.CreatedAt)
+	return err
+}
+
+// walkDedupeRoot 递归读取目录下的文件
+func walkDedupeRoot(ctx context.Context, client *cd2.Client, root model.DedupeRoot, priority int, minSize int64, exts map[string]bool, files []dedupeCandidate) ([]dedupeCandidate, error) {
+	dirs := []string{root.Path}
+	for len(dirs) > 0 {
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
+
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		list, err := listSubFiles(ctx, client, dir, false)
+		if err != nil {
+			return files, fmt.Errorf("读取目录 %s 失败: %w", dir, err)
+		}
+
+		for _, f := range list {
+			if f.IsDir {
+				if !inTrash(f.Path) {
+					dirs = append(dirs, f.Path)
+				}
+				continue
+			}
+			if f.Size <= 0 || f.Size < minSize {
+				continue
+			}
+			if len(exts) > 0 && !exts[strings.ToLower(strings.TrimPrefix(path.Ext(f.Name), "."))] {
+				continue
+			}
+			files = append(files, dedupeCandidate{FileInfo: f, Library: root.Name, priority: priority})
+		}
+	}
+	return files, nil
+}
+
+// groupDuplicates 按大小分桶后合并重复文件：双方有共同的哈希类型时按哈希判断，否则按文件名（忽略大小写）判断
+// 先合并哈希一致的文件，再按文件名合并；合并后同一分组中同一哈希类型出现不同的值时不合并，
+// 避免经由没有哈希的同名文件把确定不同的文件放进同一分组
+func groupDuplicates(files []dedupeCandidate) []model.DedupeGroup {
+	bySize := make(map[int64][]int)
+	for i, f := range files {
+		bySize[f.Size] = append(bySize[f.Size], i)
+	}
+
+	var groups []model.DedupeGroup
+	for size, idx := range bySize {
+		if len(idx) < 2 {
+			continue
+		}
+
+		parent := make([]int, len(idx))
+		weak := make([]bool, len(idx))                // 分组中是否有按文件名判断的文件
+		hashes := make([]map[string]string, len(idx)) // 分组中每种哈希类型的值
+		for i := range parent {
+			parent[i] = i
+			hashes[i] = make(map[string]string, len(files[idx[i]].Hashes))
+			for t, v := range files[idx[i]].Hashes {
+				if v != "" {
+					hashes[i][t] = v
+				}
+			}
+		}
+		var find func(int) int
+		find = func(i int) int {
+			for parent[i] != i {
+				parent[i] = parent[parent[i]]
+				i = parent[i]
+			}
+			return i
+		}
+
+		for _, byName := range []bool{false, true} {
+			for i := 0; i < len(idx); i++ {
+				for j := i + 1; j < len(idx); j++ {
+					a, b := &files[idx[i]], &files[idx[j]]
+					hashType, same := compareHashes(a.Hashes, b.Hashes)
+					if byName {
+						same = hashType == "" && strings.EqualFold(a.Name, b.Name)
+					}
+					if !same {
+						continue
+					}
+
+					ri, rj := find(i), find(j)
+					if ri == rj {
+						continue
+					}
+					if hashConflict(hashes[ri], hashes[rj]) {
+						continue
+					}
+					parent[rj] = ri
+					weak[ri] = weak[ri] || weak[rj] || byName
+					for t, v := range hashes[rj] {
+						hashes[ri][t] = v
+					}
+				}
+			}
+		}
+
+		members := make(map[int][]int)
+		for i := range idx {
+			r := find(i)
+			members[r] = append(members[r], idx[i])
+		}
+		for r, list := range members {
+			if len(list) < 2 {
+				continue
+			}
+			groups = append(groups, buildDedupeGroup(files, list, size, weak[r]))
+		}
+	}
+
+	// 可释放空间大的分组在前
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Size*int64(groups[i].Count-1) > groups[j].Size*int64(groups[j].Count-1)
+	})
+	return groups
+}
+
+// hashConflict 判断两个分组是否有同一哈希类型的不同值
+func hashConflict(a, b map[string]string) bool {
+	for t, v := range a {
+		if w, ok := b[t]; ok && w != v {
+			return true
+		}
+	}
+	return false
+}
+
+// buildDedupeGroup 生成重复分组并推荐保留的文件
+func buildDedupeGroup(files []dedupeCandidate, list []int, size int64, weak bool) model.DedupeGroup {
+	group := model.DedupeGroup{
+		MatchBy: model.DedupeMatchHash,
+		Size:    size,
+		Count:   len(list),
+		Status:  model.DedupeGroupPending,
+	}
+	if weak {
+		group.MatchBy = model.DedupeMatchNameSize
+	}
+
+	candidates := make([]*dedupeCandidate, 0, len(list))
+	for _, i := range list {
+		candidates = append(candidates, &files[i])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return betterCopy(candidates[i], candidates[j])
+	})
+
+	for _, t := range []string{"sha1", "md5", "pikpak_sha1"} {
+		if candidates[0].Hashes[t] != "" {
+			group.HashType = t
+			break
+		}
+	}
+
+	for i, c := range candidates {
+		group.Files = append(group.Files, model.DedupeFile{
+			Path:         c.Path,
+			Library:      c.Library,
+			Cloud:        c.Cloud,
+			CloudAccount: c.CloudAccount,
+			Size:         c.Size,
+			Hash:         c.Hashes[group.HashType],
+			ModifiedAt:   c.ModifiedAt,
+			Keep:         i == 0,
+		})
+	}
+	return group
+}
+
+// betterCopy 推荐保留的副本：扫描目录优先级高、有哈希、修改时间早、路径短
+func betterCopy(a, b *dedupeCandidate) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	if (len(a.Hashes) > 0) != (len(b.Hashes) > 0) {
+		return len(a.Hashes) > 0
+	}
+	if a.ModifiedAt != nil && b.ModifiedAt != nil && !a.ModifiedAt.Equal(*b.ModifiedAt) {
+		return a.ModifiedAt.Before(*b.ModifiedAt)
+	}
+	return len(a.Path) < len(b.Path)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"cinexus/internal/model"
+)
+
+func TestGroupDuplicates(t *testing.T) {
+	const size = 4 << 30
+
+	// file 生成候选文件，hashes 按 类型=值 给出
+	file := func(p string, size int64, hashes ...string) dedupeCandidate {
+		f := dedupeCandidate{FileInfo: FileInfo{Name: path.Base(p), Path: p, Size: size}}
+		if len(hashes) > 0 {
+			f.Hashes = make(map[string]string, len(hashes))
+			for _, h := range hashes {
+				t, v, _ := strings.Cut(h, "=")
+				f.Hashes[t] = v
+			}
+		}
+		return f
+	}
+
+	// group 期望的分组：匹配方式和排序后的路径
+	type group struct {
+		MatchBy string
+		Paths   []string
+	}
+
+	tests := []struct {
+		name  string
+		files []dedupeCandidate
+		want  []group
+	}{
+		{
+			name: "哈希一致",
+			files: []dedupeCandidate{
+				file("/115/Movie.mkv", size, "sha1=aaa"),
+				file("/aliyun/Film.mkv", size, "sha1=aaa"),
+			},
+			want: []group{{model.DedupeMatchHash, []string{"/115/Movie.mkv", "/aliyun/Film.mkv"}}},
+		},
+		{
+			name: "同大小同名但哈希冲突不分组",
+			files: []dedupeCandidate{
+				file("/115/Movie.mkv", size, "sha1=aaa"),
+				file("/aliyun/Movie.mkv", size, "sha1=bbb"),
+			},
+		},
+		{
+			name: "没有哈希时按文件名且不区分大小写",
+			files: []dedupeCandidate{
+				file("/a/Movie.mkv", size),
+				file("/b/movie.MKV", size),
+				file("/c/Other.mkv", size),
+			},
+			want: []group{{model.DedupeMatchNameSize, []string{"/a/Movie.mkv", "/b/movie.MKV"}}},
+		},
+		{
+			name: "大小不同不分组",
+			files: []dedupeCandidate{
+				file("/a/Movie.mkv", size),
+				file("/b/Movie.mkv", size+1),
+			},
+		},
+		{
+			name: "按第一个共同的哈希类型判断",
+			files: []dedupeCandidate{
+				file("/115/Movie.mkv", size, "sha1=aaa", "md5=mmm"),
+				file("/pikpak/Movie.mkv", size, "md5=mmm"),
+			},
+			want: []group{{model.DedupeMatchHash, []string{"/115/Movie.mkv", "/pikpak/Movie.mkv"}}},
+		},
+		{
+			name: "没有共同哈希类型时按文件名",
+			files: []dedupeCandidate{
+				file("/115/Movie.mkv", size, "sha1=aaa"),
+				file("/pikpak/Movie.mkv", size, "md5=mmm"),
+			},
+			want: []group{{model.DedupeMatchNameSize, []string{"/115/Movie.mkv", "/pikpak/Movie.mkv"}}},
+		},
+		{
+			name: "混合云盘只有部分文件有哈希",
+			files: []dedupeCandidate{
+				file("/115/Movie.mkv", size, "sha1=aaa"),
+				file("/aliyun/Movie.mkv", size, "sha1=aaa"),
+				file("/local/Movie.mkv", size),
+				file("/pikpak/Movie.mkv", size, "sha1=bbb"),
+			},
+			// 没有哈希的同名文件并入哈希分组，哈希不同的文件不会经由它并入
+			want: []group{{model.DedupeMatchNameSize, []string{"/115/Movie.mkv", "/aliyun/Movie.mkv", "/local/Movie.mkv"}}},
+		},
+		{
+			name: "没有哈希的文件不能连接两个哈希不同的分组",
+			files: []dedupeCandidate{
+				file("/local/Movie.mkv", size),
+				file("/115/Movie.mkv", size, "sha1=aaa"),
+				file("/pikpak/Movie.mkv", size, "sha1=bbb"),
+				file("/aliyun/Movie.mkv", size, "sha1=bbb"),
+			},
+			want: []group{
+				{model.DedupeMatchNameSize, []string{"/115/Movie.mkv", "/local/Movie.mkv"}},
+				{model.DedupeMatchHash, []string{"/aliyun/Movie.mkv", "/pikpak/Movie.mkv"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []group
+			for _, g := range groupDuplicates(tt.files) {
+				paths := make([]string, len(g.Files))
+				for i, f := range g.Files {
+					paths[i] = f.Path
+				}
+				sort.Strings(paths)
+				got = append(got, group{g.MatchBy, paths})
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].Paths[0] < got[j].Paths[0] })
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupDuplicates\n got  %v\n want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package service
+
+// NotFoundError 查找的记录或资源不存在，控制器统一返回404，各业务不需要单独导出哨兵错误供控制器判断
+type NotFoundError struct {
+	Resource string
+}
+
+// Error 实现error接口
+func (e *NotFoundError) Error() string {
+	return e.Resource + "不存在"
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"cinexus/internal/cd2"
+	"cinexus/pkg/pb"
+)
+
+// FileService CD2文件浏览服务
+type FileService struct{}
+
+// FileInfo 文件信息，对外屏蔽CD2的protobuf结构
+type FileInfo struct {
+	Name                 string            `json:"name"`
+	Path                 string            `json:"path"`
+	Size                 int64             `json:"size"`
+	IsDir                bool              `json:"is_dir"`
+	IsCloudRoot          bool              `json:"is_cloud_root"`
+	Cloud                string            `json:"cloud,omitempty"`         // 所属云盘类型，如 115、阿里云盘
+	CloudAccount         string            `json:"cloud_account,omitempty"` // 所属云盘账号
+	Hashes               map[string]string `json:"hashes,omitempty"`
+	CreatedAt            *time.Time        `json:"created_at,omitempty"`
+	ModifiedAt           *time.Time        `json:"modified_at,omitempty"`
+	CanDeletePermanently bool              `json:"can_delete_permanently"`
+}
+
+// FileDetail 文件详情
+type FileDetail struct {
+	FileInfo
+	TotalFileCount   int64  `json:"total_file_count"`
+	TotalFolderCount int64  `json:"total_folder_count"`
+	TotalSize        int64  `json:"total_size"`
+	OriginalPath     string `json:"original_path,omitempty"`
+}
+
+// SpaceInfo 空间信息
+type SpaceInfo struct {
+	Path       string `json:"path"`
+	TotalSpace int64  `json:"total_space"`
+	UsedSpace  int64  `json:"used_space"`
+	FreeSpace  int64  `json:"free_space"`
+}
+
+// CD2Instance CD2实例信息
+type CD2Instance struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+}
+
+// ListFilesRequest 列目录请求
+type ListFilesRequest struct {
+	PageRequest
+	Instance string `form:"instance"`
+	Path     string `form:"path"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=name size modified created"`
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Refresh  bool   `form:"refresh"`
+}
+
+// StatFileRequest 获取文件详情请求
+type StatFileRequest struct {
+	Instance string `form:"instance"`
+	Path     string `form:"path" binding:"required"`
+}
+
+// SearchFilesRequest 搜索请求
+type SearchFilesRequest struct {
+	PageRequest
+	Instance string `form:"instance"`
+	Path     string `form:"path"`
+	Keyword  string `form:"keyword" binding:"required"`
+	Fuzzy    bool   `form:"fuzzy"`
+	Refresh  bool   `form:"refresh"`
+}
+
+// SpaceInfoRequest 空间信息请求
+type SpaceInfoRequest struct {
+	Instance string `form:"instance"`
+	Path     string `form:"path"`
+}
+
+// 文件哈希类型名称
+var hashTypeNames = map[uint32]string{
+	uint32(pb.CloudDriveFile_Md5):        "md5",
+	uint32(pb.CloudDriveFile_Sha1):       "sha1",
+	uint32(pb.CloudDriveFile_PikPakSha1): "pikpak_sha1",
+}
+
+// ListInstances 获取已配置的CD2实例
+func (s *FileService) ListInstances() []CD2Instance {
+	clients := cd2.All()
+	instances := make([]CD2Instance, 0, len(clients))
+	for i, c := range clients {
+		instances = append(instances, CD2Instance{Name: c.Name, Default: i == 0})
+	}
+	return instances
+}
+
+// List 列出目录内容，CD2以流的形式分批返回，这里汇总后统一排序分页
+func (s *FileService) List(ctx context.Context, req *ListFilesRequest) ([]FileInfo, int64, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files, err := listSubFiles(ctx, client, cleanPath(req.Path), req.Refresh)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortFiles(files, req.Sort, req.Order)
+	return paginate(files, &req.PageRequest), int64(len(files)), nil
+}
+
+// Stat 获取文件详情
+func (s *FileService) Stat(ctx context.Context, req *StatFileRequest) (*FileDetail, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := findFile(ctx, client, cleanPath(req.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &FileDetail{FileInfo: toFileInfo(file)}
+	if !file.HasDetailProperties {
+		return detail, nil
+	}
+
+	props, err := client.GetFileDetailProperties(ctx, &pb.FileRequest{Path: file.FullPathName})
+	if err != nil {
+		return nil, err
+	}
+
+	detail.TotalFileCount = props.TotalFileCount
+	detail.TotalFolderCount = props.TotalFolderCount
+	detail.TotalSize = props.TotalSize
+	detail.OriginalPath = props.OriginalPath
+	return detail, nil
+}
+
+// Search 在指定目录下搜索文件
+func (s *FileService) Search(ctx context.Context, req *SearchFilesRequest) ([]FileInfo, int64, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stream, err := client.GetSearchResults(ctx, &pb.SearchRequest{
+		Path:         cleanPath(req.Path),
+		SearchFor:    req.Keyword,
+		ForceRefresh: req.Refresh,
+		FuzzyMatch:   req.Fuzzy,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files, err := recvFiles(stream.Recv)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(files, &req.PageRequest), int64(len(files)), nil
+}
+
+// Space 获取路径所在云盘的空间信息
+func (s *FileService) Space(ctx context.Context, req *SpaceInfoRequest) (*SpaceInfo, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	p := cleanPath(req.Path)
+	info, err := client.GetSpaceInfo(ctx, &pb.FileRequest{Path: p})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpaceInfo{
+		Path:       p,
+		TotalSpace: info.TotalSpace,
+		UsedSpace:  info.UsedSpace,
+		FreeSpace:  info.FreeSpace,
+	}, nil
+}
+
+// listSubFiles 获取目录下的全部文件
+func listSubFiles(ctx context.Context, client *cd2.Client, dir string, refresh bool) ([]FileInfo, error) {
+	stream, err := client.GetSubFiles(ctx, &pb.ListSubFileRequest{
+		Path:         dir,
+		ForceRefresh: refresh,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recvFiles(stream.Recv)
+}
+
+// findFile 根据完整路径查找文件
+func findFile(ctx context.Context, client *cd2.Client, p string) (*pb.CloudDriveFile, error) {
+	if p == "/" {
+		return &pb.CloudDriveFile{Name: "/", FullPathName: "/", IsDirectory: true, IsRoot: true}, nil
+	}
+
+	return client.FindFileByPath(ctx, &pb.FindFileByPathRequest{
+		ParentPath: path.Dir(p),
+		Path:       p,
+	})
+}
+
+// recvFiles 读取CD2文件流直到结束
+func recvFiles(recv func() (*pb.SubFilesReply, error)) ([]FileInfo, error) {
+	var files []FileInfo
+	for {
+		reply, err := recv()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range reply.SubFiles {
+			files = append(files, toFileInfo(f))
+		}
+	}
+}
+
+// toFileInfo 将CD2文件转换为对外的文件信息
+func toFileInfo(f *pb.CloudDriveFile) FileInfo {
+	info := FileInfo{
+		Name:                 f.Name,
+		Path:                 f.FullPathName,
+		Size:                 f.Size,
+		IsDir:                f.IsDirectory,
+		IsCloudRoot:          f.IsCloudRoot,
+		CanDeletePermanently: f.CanDeletePermanently,
+	}
+
+	if f.CloudAPI != nil {
+		info.Cloud = f.CloudAPI.Name
+		info.CloudAccount = f.CloudAPI.UserName
+	}
+	if f.CreateTime != nil {
+		t := f.CreateTime.AsTime()
+		info.CreatedAt = &t
+	}
+	if f.WriteTime != nil {
+		t := f.WriteTime.AsTime()
+		info.ModifiedAt = &t
+	}
+	if len(f.FileHashes) > 0 {
+		info.Hashes = make(map[string]string, len(f.FileHashes))
+		for k, v := range f.FileHashes {
+			if name, ok := hashTypeNames[k]; ok {
+				info.Hashes[name] = strings.ToLower(v)
+			}
+		}
+	}
+
+	return info
+}
+
+// sortFiles 排序文件列表，目录始终排在文件之前
+func sortFiles(files []FileInfo, field, order string) {
+	desc := order == "desc"
+
+	less := func(a, b *FileInfo) bool {
+		switch field {
+		case "size":
+			return a.Size < b.Size
+		case "modified":
+			return timeBefore(a.ModifiedAt, b.ModifiedAt)
+		case "created":
+			return timeBefore(a.CreatedAt, b.CreatedAt)
+		default:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := &files[i], &files[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+// timeBefore 比较可能为空的时间，空值视为最早
+func timeBefore(a, b *time.Time) bool {
+	if a == nil {
+		return b != nil
+	}
+	if b == nil {
+		return false
+	}
+	return a.Before(*b)
+}
+
+// cleanPath 规范化CD2路径，始终以 / 开头
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return path.Clean("/" + p)
+}
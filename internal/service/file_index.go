@@ -0,0 +1,643 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/pb"
+)
+
+// 文件索引扫描状态
+const (
+	indexStatusScanning  = "scanning"
+	indexStatusCompleted = "completed"
+	indexStatusFailed    = "failed"
+)
+
+// 搜索引擎
+const (
+	indexEngineFTS  = "fts5"
+	indexEngineLike = "like"
+)
+
+// trigram 分词的全文索引只能匹配不少于3个字符的关键字，更短的关键字使用 LIKE
+const ftsMinTermLength = 3
+
+// FileIndexService 本地文件索引服务
+type FileIndexService struct{}
+
+// IndexSearchRequest 索引搜索条件
+type IndexSearchRequest struct {
+	PageRequest
+	Instance       string     `form:"instance"`
+	Query          string     `form:"q"`        // 文件名关键字，空格分隔的多个关键字需同时匹配
+	Wildcard       string     `form:"wildcard"` // 文件名通配符，支持 * 和 ?
+	Path           string     `form:"path"`     // 只搜索该目录下的文件
+	Ext            string     `form:"ext"`      // 扩展名，逗号分隔
+	Type           string     `form:"type"`     // file, dir
+	MinSize        int64      `form:"min_size"`
+	MaxSize        int64      `form:"max_size"`
+	ModifiedAfter  *time.Time `form:"modified_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	ModifiedBefore *time.Time `form:"modified_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort           string     `form:"sort"`  // name, size, modified_at
+	Order          string     `form:"order"` // asc, desc
+}
+
+// IndexSearchResult 索引搜索结果
+type IndexSearchResult struct {
+	List     []model.FileIndex `json:"list"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+	Engine   string            `json:"engine"`
+	TookMs   int64             `json:"took_ms"`
+}
+
+// IndexRootRequest 索引目录请求
+type IndexRootRequest struct {
+	Instance string `json:"instance" form:"instance"`
+	Path     string `json:"path" form:"path" binding:"required"`
+}
+
+// 可排序的字段
+var indexSortFields = map[string]string{
+	"name":        "name",
+	"size":        "size",
+	"modified_at": "modified_at",
+}
+
+var (
+	// indexFTS SQLite 是否启用了全文索引
+	indexFTS bool
+
+	// indexRoots 各实例已建立索引的目录，用于过滤推送消息
+	indexRootsMu sync.RWMutex
+	indexRoots   = make(map[string][]string)
+
+	// indexScanning 正在扫描的目录
+	indexScanning sync.Map
+
+	// indexRun 扫描任务的根上下文，启动文件索引后设置，服务关闭时取消
+	indexRun = struct {
+		sync.RWMutex
+		ctx context.Context
+	}{}
+)
+
+// errIndexDisabled 未启用文件索引
+var errIndexDisabled = errors.New("文件索引未启用，请在配置中开启 index.enabled 后重启")
+
+// indexContext 返回扫描任务的根上下文，未启动文件索引时返回 false
+func indexContext() (context.Context, bool) {
+	indexRun.RLock()
+	defer indexRun.RUnlock()
+	return indexRun.ctx, indexRun.ctx != nil
+}
+
+// StartFileIndex 初始化全文索引，订阅CD2文件变化推送，并在后台扫描配置的目录
+func StartFileIndex(ctx context.Context) {
+	cfg := config.Get().Index
+	if !cfg.Enabled {
+		return
+	}
+	indexRun.Lock()
+	indexRun.ctx = ctx
+	indexRun.Unlock()
+
+	if config.Get().Database.Type == "sqlite" {
+		if err := setupIndexFTS(); err != nil {
+			logger.Ctx(ctx).Warn("SQLite不支持FTS5，文件索引搜索使用LIKE查询，可使用 -tags sqlite_fts5 重新编译", zap.Error(err))
+		} else {
+			indexFTS = true
+		}
+	}
+
+	// 标记上次退出时未完成的扫描
+	database.Ctx(ctx).Model(&model.FileIndexRoot{}).Where("status = ?", indexStatusScanning).
+		Updates(map[string]interface{}{"status": indexStatusFailed, "error": "服务重启导致扫描中断"})
+
+	for _, r := range cfg.Roots {
+		client, err := cd2.Get(r.Instance)
+		if err != nil {
+			logger.Ctx(ctx).Warn("索引目录的CD2实例不存在", zap.String("instance", r.Instance), zap.String("path", r.Path))
+			continue
+		}
+		root := model.FileIndexRoot{Instance: client.Name, Path: cleanPath(r.Path)}
+		if err := database.Ctx(ctx).Where(root).FirstOrCreate(&root).Error; err != nil {
+			logger.Ctx(ctx).Error("保存索引目录失败", zap.Error(err))
+		}
+	}
+	if err := loadIndexRoots(); err != nil {
+		logger.Ctx(ctx).Error("加载索引目录失败", zap.Error(err))
+	}
+
+	cd2.Subscribe(func(instance string, msg *pb.CloudDrivePushMessage) {
+		if change := msg.GetFileSystemChange(); change != nil {
+			applyIndexChange(ctx, instance, change)
+		}
+	})
+
+	go func() {
+		interval := time.Duration(cfg.Interval) * time.Second
+		for {
+			var roots []model.FileIndexRoot
+			if err := database.Ctx(ctx).Find(&roots).Error; err != nil {
+				logger.Ctx(ctx).Error("查询索引目录失败", zap.Error(err))
+			}
+			for _, r := range roots {
+				due := r.Status != indexStatusCompleted || r.FinishedAt == nil ||
+					(interval > 0 && time.Since(*r.FinishedAt) >= interval)
+				if !due {
+					continue
+				}
+				if err := scanIndexRoot(ctx, r.Instance, r.Path); err != nil && ctx.Err() == nil {
+					logger.Ctx(ctx).Warn("扫描索引目录失败", zap.String("instance", r.Instance), zap.String("path", r.Path), zap.Error(err))
+				}
+			}
+
+			// 未设置间隔时仍定期检查，以便补扫失败的目录
+			wait := interval
+			if wait <= 0 {
+				wait = time.Hour
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// Search 在本地索引中搜索文件
+func (s *FileIndexService) Search(ctx context.Context, req *IndexSearchRequest) (*IndexSearchResult, error) {
+	start := time.Now()
+	req.Normalize()
+
+	db := database.Ctx(ctx).Model(&model.FileIndex{})
+	if req.Instance != "" {
+		client, err := cd2.Get(req.Instance)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("instance = ?", client.Name)
+	}
+	if p := cleanPath(req.Path); req.Path != "" && p != "/" {
+		db = db.Where("path LIKE ? ESCAPE '!'", escapeLike(p)+"/%")
+	}
+
+	engine := indexEngineLike
+	for _, term := range strings.Fields(req.Query) {
+		if indexFTS && utf8.RuneCountInString(term) >= ftsMinTermLength {
+			engine = indexEngineFTS
+			db = db.Where("id IN (SELECT rowid FROM file_index_fts WHERE file_index_fts MATCH ?)",
+				`"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		} else {
+			db = db.Where("name LIKE ? ESCAPE '!'", "%"+escapeLike(term)+"%")
+		}
+	}
+	if req.Wildcard != "" {
+		pattern := escapeLike(req.Wildcard)
+		pattern = strings.NewReplacer("*", "%", "?", "_").Replace(pattern)
+		db = db.Where("name LIKE ? ESCAPE '!'", pattern)
+	}
+
+	if req.Ext != "" {
+		var exts []string
+		for _, e := range strings.Split(req.Ext, ",") {
+			if e = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(e), ".")); e != "" {
+				exts = append(exts, e)
+			}
+		}
+		if len(exts) > 0 {
+			db = db.Where("ext IN ?", exts)
+		}
+	}
+	switch req.Type {
+	case "file":
+		db = db.Where("is_dir = ?", false)
+	case "dir":
+		db = db.Where("is_dir = ?", true)
+	}
+	if req.MinSize > 0 {
+		db = db.Where("size >= ?", req.MinSize)
+	}
+	if req.MaxSize > 0 {
+		db = db.Where("size <= ?", req.MaxSize)
+	}
+	if req.ModifiedAfter != nil {
+		db = db.Where("modified_at >= ?", *req.ModifiedAfter)
+	}
+	if req.ModifiedBefore != nil {
+		db = db.Where("modified_at < ?", *req.ModifiedBefore)
+	}
+
+	result := &IndexSearchResult{Page: req.Page, PageSize: req.PageSize, Engine: engine}
+	if err := db.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	order := "name ASC"
+	if field, ok := indexSortFields[req.Sort]; ok {
+		order = field + " ASC"
+		if req.Order == "desc" {
+			order = field + " DESC"
+		}
+	}
+	if err := db.Order(order).Order("id ASC").Offset(req.Offset()).Limit(req.PageSize).Find(&result.List).Error; err != nil {
+		return nil, err
+	}
+
+	result.TookMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// Roots 获取已建立索引的目录
+func (s *FileIndexService) Roots(ctx context.Context) ([]model.FileIndexRoot, error) {
+	var roots []model.FileIndexRoot
+	err := database.Ctx(ctx).Order("instance, path").Find(&roots).Error
+	return roots, err
+}
+
+// Scan 添加索引目录并在后台扫描，已有的目录重新扫描，未启用文件索引时拒绝
+func (s *FileIndexService) Scan(ctx context.Context, req *IndexRootRequest) (*model.FileIndexRoot, error) {
+	runCtx, ok := indexContext()
+	if !ok {
+		return nil, errIndexDisabled
+	}
+
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	p := cleanPath(req.Path)
+	if _, exists, err := statFile(ctx, client, p); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, &NotFoundError{Resource: "目录"}
+	}
+	if _, running := indexScanning.Load(client.Name + ":" + p); running {
+		return nil, errors.New("该目录正在扫描")
+	}
+
+	root := model.FileIndexRoot{Instance: client.Name, Path: p}
+	if err := database.Ctx(ctx).Where(root).FirstOrCreate(&root).Error; err != nil {
+		return nil, err
+	}
+	if err := loadIndexRoots(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := scanIndexRoot(runCtx, root.Instance, root.Path); err != nil && runCtx.Err() == nil {
+			logger.Ctx(ctx).Warn("扫描索引目录失败", zap.String("instance", root.Instance), zap.String("path", root.Path), zap.Error(err))
+		}
+	}()
+	return &root, nil
+}
+
+// RemoveRoot 删除索引目录及其下的索引
+func (s *FileIndexService) RemoveRoot(ctx context.Context, req *IndexRootRequest) error {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return err
+	}
+
+	p := cleanPath(req.Path)
+	result := database.Ctx(ctx).Where("instance = ? AND path = ?", client.Name, p).Delete(&model.FileIndexRoot{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{Resource: "索引目录"}
+	}
+
+	if err := deleteIndexed(database.Ctx(ctx), client.Name, p, true); err != nil {
+		return err
+	}
+	return loadIndexRoots()
+}
+
+// setupIndexFTS 创建文件名的FTS5全文索引及同步触发器，首次创建时从已有数据重建
+func setupIndexFTS() error {
+	var exists int64
+	if err := database.DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'file_index_fts'").Scan(&exists).Error; err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS file_index_fts USING fts5(name, content='file_index', content_rowid='id', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS file_index_ai AFTER INSERT ON file_index BEGIN
+			INSERT INTO file_index_fts(rowid, name) VALUES (new.id, new.name);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS file_index_ad AFTER DELETE ON file_index BEGIN
+			INSERT INTO file_index_fts(file_index_fts, rowid, name) VALUES ('delete', old.id, old.name);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS file_index_au AFTER UPDATE OF name ON file_index BEGIN
+			INSERT INTO file_index_fts(file_index_fts, rowid, name) VALUES ('delete', old.id, old.name);
+			INSERT INTO file_index_fts(rowid, name) VALUES (new.id, new.name);
+		END`,
+	}
+	if exists == 0 {
+		statements = append(statements, `INSERT INTO file_index_fts(file_index_fts) VALUES ('rebuild')`)
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadIndexRoots 从数据库加载索引目录
+func loadIndexRoots() error {
+	var roots []model.FileIndexRoot
+	if err := database.DB.Find(&roots).Error; err != nil {
+		return err
+	}
+
+	m := make(map[string][]string)
+	for _, r := range roots {
+		m[r.Instance] = append(m[r.Instance], r.Path)
+	}
+
+	indexRootsMu.Lock()
+	indexRoots = m
+	indexRootsMu.Unlock()
+	return nil
+}
+
+// indexed 路径是否位于实例的索引目录下，回收站中的文件不建立索引
+func indexed(instance, p string) bool {
+	if inTrash(p) {
+		return false
+	}
+
+	indexRootsMu.RLock()
+	defer indexRootsMu.RUnlock()
+
+	for _, root := range indexRoots[instance] {
+		if p == root || root == "/" || strings.HasPrefix(p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scanIndexRoot 全量扫描目录，扫描完成后删除本次没有出现的旧索引
+func scanIndexRoot(ctx context.Context, instance, root string) error {
+	key := instance + ":" + root
+	if _, running := indexScanning.LoadOrStore(key, true); running {
+		return nil
+	}
+	defer indexScanning.Delete(key)
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	state := model.FileIndexRoot{Status: indexStatusScanning, StartedAt: &started}
+	rootQuery := database.Ctx(ctx).Model(&model.FileIndexRoot{}).Where("instance = ? AND path = ?", instance, root)
+	rootQuery.Select("status", "started_at", "error").Updates(&state)
+
+	files, dirs, err := walkIndex(ctx, client, root, started)
+	if err == nil {
+		err = database.Ctx(ctx).Where("instance = ? AND path LIKE ? ESCAPE '!' AND indexed_at < ?",
+			instance, escapeLike(strings.TrimSuffix(root, "/"))+"/%", started).Delete(&model.FileIndex{}).Error
+	}
+
+	finished := time.Now()
+	state = model.FileIndexRoot{Status: indexStatusCompleted, Files: files, Dirs: dirs, FinishedAt: &finished}
+	if err != nil {
+		state.Status = indexStatusFailed
+		state.Error = truncate(err.Error(), 512)
+	}
+	database.Ctx(ctx).Model(&model.FileIndexRoot{}).Where("instance = ? AND path = ?", instance, root).
+		Select("status", "files", "dirs", "error", "finished_at").Updates(&state)
+	metrics.ObserveJob("file_index_scan", state.Status, started)
+
+	logger.Ctx(ctx).Info("文件索引扫描结束",
+		zap.String("instance", instance),
+		zap.String("path", root),
+		zap.Int("files", files),
+		zap.Int("dirs", dirs),
+		zap.Duration("took", finished.Sub(started)),
+		zap.Error(err),
+	)
+	return err
+}
+
+// walkIndex 逐个目录读取并写入索引
+func walkIndex(ctx context.Context, client *cd2.Client, root string, now time.Time) (int, int, error) {
+	files, dirs := 0, 0
+	queue := []string{root}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return files, dirs, err
+		}
+
+		dir := queue[0]
+		queue = queue[1:]
+
+		list, err := listSubFiles(ctx, client, dir, false)
+		if err != nil {
+			return files, dirs, fmt.Errorf("读取目录 %s 失败: %w", dir, err)
+		}
+
+		rows := make([]model.FileIndex, 0, len(list))
+		for _, f := range list {
+			if inTrash(f.Path) {
+				continue
+			}
+			if f.IsDir {
+				queue = append(queue, f.Path)
+				dirs++
+			} else {
+				files++
+			}
+			rows = append(rows, indexRow(client.Name, f, now))
+		}
+		if err := upsertIndexed(database.Ctx(ctx), rows); err != nil {
+			return files, dirs, err
+		}
+	}
+	return files, dirs, nil
+}
+
+// applyIndexChange 根据CD2推送的文件变化更新索引
+func applyIndexChange(ctx context.Context, instance string, change *pb.FileSystemChange) {
+	var err error
+	switch change.ChangeType {
+	case pb.FileSystemChange_CREATE:
+		if change.TheFile != nil && indexed(instance, change.TheFile.FullPathName) {
+			err = upsertIndexed(database.Ctx(ctx), []model.FileIndex{indexRow(instance, toFileInfo(change.TheFile), time.Now())})
+		}
+	case pb.FileSystemChange_DELETE:
+		if indexed(instance, change.Path) {
+			err = deleteIndexed(database.Ctx(ctx), instance, change.Path, change.IsDirectory)
+		}
+	case pb.FileSystemChange_RENAME:
+		err = renameIndexed(ctx, instance, change)
+	}
+	if err != nil {
+		logger.Ctx(ctx).Warn("更新文件索引失败", zap.String("instance", instance), zap.String("path", change.Path), zap.Error(err))
+	}
+}
+
+// renameIndexed 处理重命名或移动：移出索引目录时删除，移入时新增，目录移动时同时更新其下的所有索引
+func renameIndexed(ctx context.Context, instance string, change *pb.FileSystemChange) error {
+	oldPath, newPath := change.Path, change.GetNewPath()
+	if newPath == "" && change.TheFile != nil {
+		newPath = change.TheFile.FullPathName
+	}
+
+	from, to := indexed(instance, oldPath), newPath != "" && indexed(instance, newPath)
+	switch {
+	case from && !to:
+		return deleteIndexed(database.Ctx(ctx), instance, oldPath, change.IsDirectory)
+	case !from && to:
+		if change.TheFile != nil {
+			if err := upsertIndexed(database.Ctx(ctx), []model.FileIndex{indexRow(instance, toFileInfo(change.TheFile), time.Now())}); err != nil {
+				return err
+			}
+		}
+		// 从索引目录外移入的目录需要扫描其内容
+		if change.IsDirectory {
+			go func() {
+				client, err := cd2.Get(instance)
+				if err != nil {
+					return
+				}
+				if _, _, err := walkIndex(ctx, client, newPath, time.Now()); err != nil {
+					logger.Ctx(ctx).Warn("扫描移入的目录失败", zap.String("path", newPath), zap.Error(err))
+				}
+			}()
+		}
+		return nil
+	case !from && !to:
+		return nil
+	}
+
+	return database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []model.FileIndex
+		db := tx.Where("instance = ? AND path_hash = ?", instance, pathHash(oldPath))
+		if change.IsDirectory {
+			db = tx.Where("instance = ? AND (path_hash = ? OR path LIKE ? ESCAPE '!')", instance, pathHash(oldPath), escapeLike(oldPath)+"/%")
+		}
+		if err := db.Find(&rows).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range rows {
+			row := &rows[i]
+			row.Path = newPath + strings.TrimPrefix(row.Path, oldPath)
+			row.Parent = path.Dir(row.Path)
+			row.Name = path.Base(row.Path)
+			row.Ext = indexExt(row.Name, row.IsDir)
+			row.PathHash = pathHash(row.Path)
+			row.ParentHash = pathHash(row.Parent)
+			row.IndexedAt = now
+			if row.Path == newPath && change.TheFile != nil {
+				fresh := indexRow(instance, toFileInfo(change.TheFile), now)
+				fresh.ID = row.ID
+				*row = fresh
+			}
+		}
+
+		// 目标路径上可能已有旧索引，先删除再更新，避免唯一索引冲突
+		if err := deleteIndexed(tx, instance, newPath, change.IsDirectory); err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := tx.Save(&rows[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// upsertIndexed 写入索引，已存在的路径更新信息
+func upsertIndexed(db *gorm.DB, rows []model.FileIndex) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "instance"}, {Name: "path_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "ext", "size", "is_dir", "modified_at", "hashes", "cloud", "indexed_at"}),
+	}).CreateInBatches(rows, 200).Error
+}
+
+// deleteIndexed 删除路径的索引，目录同时删除其下的所有索引
+func deleteIndexed(db *gorm.DB, instance, p string, dir bool) error {
+	if dir {
+		prefix := escapeLike(strings.TrimSuffix(p, "/")) + "/%"
+		return db.Where("instance = ? AND (path_hash = ? OR path LIKE ? ESCAPE '!')", instance, pathHash(p), prefix).
+			Delete(&model.FileIndex{}).Error
+	}
+	return db.Where("instance = ? AND path_hash = ?", instance, pathHash(p)).Delete(&model.FileIndex{}).Error
+}
+
+// indexRow 生成索引记录
+func indexRow(instance string, f FileInfo, now time.Time) model.FileIndex {
+	parent := path.Dir(f.Path)
+	return model.FileIndex{
+		Instance:   instance,
+		PathHash:   pathHash(f.Path),
+		ParentHash: pathHash(parent),
+		Path:       f.Path,
+		Parent:     parent,
+		Name:       f.Name,
+		Ext:        indexExt(f.Name, f.IsDir),
+		Size:       f.Size,
+		IsDir:      f.IsDir,
+		ModifiedAt: f.ModifiedAt,
+		Hashes:     f.Hashes,
+		Cloud:      f.Cloud,
+		IndexedAt:  now,
+	}
+}
+
+// indexExt 小写的扩展名，目录没有扩展名
+func indexExt(name string, dir bool) string {
+	if dir {
+		return ""
+	}
+	return truncate(strings.ToLower(strings.TrimPrefix(path.Ext(name), ".")), 16)
+}
+
+// pathHash 计算路径的SHA1
+func pathHash(p string) string {
+	sum := sha1.Sum([]byte(p))
+	return hex.EncodeToString(sum[:])
+}
+
+// escapeLike 转义 LIKE 中的通配符，使用 ! 作为转义字符以兼容 MySQL 和 SQLite
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestScanRejectedWhenIndexDisabled(t *testing.T) {
+	s := &FileIndexService{}
+	_, err := s.Scan(context.Background(), &IndexRootRequest{Path: "/115/movies"})
+	if !errors.Is(err, errIndexDisabled) {
+		t.Fatalf("err = %v，应为 %v", err, errIndexDisabled)
+	}
+}
@@ -0,0 +1,501 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/pb"
+)
+
+// ErrConfirmRequired 永久删除缺少有效的确认令牌
+var ErrConfirmRequired = errors.New("永久删除需要确认令牌，请先以 dry_run 方式调用获取")
+
+// 永久删除确认令牌有效期
+const confirmTokenTTL = 5 * time.Minute
+
+// FileOperationError CD2返回的文件操作失败
+type FileOperationError struct {
+	Operation string
+	Message   string
+}
+
+// Error 实现error接口
+func (e *FileOperationError) Error() string {
+	if e.Message == "" {
+		return e.Operation + "失败"
+	}
+	return e.Operation + "失败: " + e.Message
+}
+
+// FileOperationBase 文件操作公共参数
+type FileOperationBase struct {
+	Instance string `json:"instance"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+// CreateFolderRequest 创建目录请求
+type CreateFolderRequest struct {
+	FileOperationBase
+	ParentPath string `json:"parent_path" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+}
+
+// RenameItem 重命名项
+type RenameItem struct {
+	Path    string `json:"path" binding:"required"`
+	NewName string `json:"new_name" binding:"required"`
+}
+
+// RenameFileRequest 重命名请求
+type RenameFileRequest struct {
+	FileOperationBase
+	RenameItem
+}
+
+// BatchRenameRequest 批量重命名请求
+type BatchRenameRequest struct {
+	FileOperationBase
+	Items []RenameItem `json:"items" binding:"required,min=1,dive"`
+}
+
+// MoveFilesRequest 移动请求
+type MoveFilesRequest struct {
+	FileOperationBase
+	Paths          []string `json:"paths" binding:"required,min=1"`
+	DestPath       string   `json:"dest_path" binding:"required"`
+	ConflictPolicy string   `json:"conflict_policy" binding:"omitempty,oneof=overwrite rename skip"`
+}
+
+// CopyFilesRequest 复制请求
+type CopyFilesRequest struct {
+	FileOperationBase
+	Paths    []string `json:"paths" binding:"required,min=1"`
+	DestPath string   `json:"dest_path" binding:"required"`
+}
+
+// DeleteFilesRequest 删除请求
+type DeleteFilesRequest struct {
+	FileOperationBase
+	Paths        []string `json:"paths" binding:"required,min=1"`
+	Permanent    bool     `json:"permanent"`
+	ConfirmToken string   `json:"confirm_token"`
+}
+
+// FilePlanItem 试运行时每个文件的预期操作
+type FilePlanItem struct {
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Exists   bool   `json:"exists"`   // 源文件是否存在
+	IsDir    bool   `json:"is_dir"`   // 源文件是否为目录
+	Size     int64  `json:"size"`     // 源文件大小
+	Conflict bool   `json:"conflict"` // 目标位置是否已存在同名文件
+	Error    string `json:"error,omitempty"`
+}
+
+// FileOperationResult 文件操作结果
+type FileOperationResult struct {
+	Operation        string         `json:"operation"`
+	DryRun           bool           `json:"dry_run"`
+	Items            []FilePlanItem `json:"items,omitempty"`
+	ResultPaths      []string       `json:"result_paths,omitempty"`
+	ConfirmToken     string         `json:"confirm_token,omitempty"`
+	ConfirmExpiresAt *time.Time     `json:"confirm_expires_at,omitempty"`
+}
+
+// 移动冲突策略
+var conflictPolicies = map[string]pb.MoveFileRequest_ConflictPolicy{
+	"overwrite": pb.MoveFileRequest_Overwrite,
+	"rename":    pb.MoveFileRequest_Rename,
+	"skip":      pb.MoveFileRequest_Skip,
+}
+
+// CreateFolder 创建目录
+func (s *FileService) CreateFolder(ctx context.Context, req *CreateFolderRequest) (*FileOperationResult, error) {
+	if err := validateName(req.Name); err != nil {
+		return nil, err
+	}
+
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := cleanPath(req.ParentPath)
+	result := &FileOperationResult{Operation: "create_folder", DryRun: req.DryRun}
+
+	if req.DryRun {
+		item := planItem(ctx, client, parent, path.Join(parent, req.Name))
+		if !item.Exists && item.Error == "" {
+			item.Error = "父目录不存在"
+		}
+		result.Items = []FilePlanItem{item}
+		return result, nil
+	}
+
+	reply, err := client.CreateFolder(ctx, &pb.CreateFolderRequest{
+		ParentPath: parent,
+		FolderName: req.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("创建目录", reply.Result); err != nil {
+		return nil, err
+	}
+
+	if reply.FolderCreated != nil {
+		result.ResultPaths = []string{reply.FolderCreated.FullPathName}
+	}
+	return result, nil
+}
+
+// Rename 重命名单个文件
+func (s *FileService) Rename(ctx context.Context, req *RenameFileRequest) (*FileOperationResult, error) {
+	return s.BatchRename(ctx, &BatchRenameRequest{
+		FileOperationBase: req.FileOperationBase,
+		Items:             []RenameItem{req.RenameItem},
+	})
+}
+
+// BatchRename 批量重命名
+func (s *FileService) BatchRename(ctx context.Context, req *BatchRenameRequest) (*FileOperationResult, error) {
+	for _, item := range req.Items {
+		if err := validateName(item.NewName); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FileOperationResult{Operation: "rename", DryRun: req.DryRun}
+	if req.DryRun {
+		for _, item := range req.Items {
+			p := cleanPath(item.Path)
+			result.Items = append(result.Items, planItem(ctx, client, p, path.Join(path.Dir(p), item.NewName)))
+		}
+		return result, nil
+	}
+
+	renames := make([]*pb.RenameFileRequest, 0, len(req.Items))
+	for _, item := range req.Items {
+		renames = append(renames, &pb.RenameFileRequest{
+			TheFilePath: cleanPath(item.Path),
+			NewName:     item.NewName,
+		})
+	}
+
+	var reply *pb.FileOperationResult
+	if len(renames) == 1 {
+		reply, err = client.RenameFile(ctx, renames[0])
+	} else {
+		reply, err = client.RenameFiles(ctx, &pb.RenameFilesRequest{RenameFiles: renames})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("重命名", reply); err != nil {
+		return nil, err
+	}
+
+	result.ResultPaths = reply.ResultFilePaths
+	return result, nil
+}
+
+// Move 移动文件
+func (s *FileService) Move(ctx context.Context, req *MoveFilesRequest) (*FileOperationResult, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := cleanPaths(req.Paths)
+	dest := cleanPath(req.DestPath)
+	result := &FileOperationResult{Operation: "move", DryRun: req.DryRun}
+
+	if req.DryRun {
+		result.Items = planTransfer(ctx, client, paths, dest)
+		return result, nil
+	}
+
+	if err := checkTransferTarget(paths, dest); err != nil {
+		return nil, err
+	}
+
+	moveReq := &pb.MoveFileRequest{TheFilePaths: paths, DestPath: dest}
+	if policy, ok := conflictPolicies[req.ConflictPolicy]; ok {
+		moveReq.ConflictPolicy = &policy
+	}
+
+	reply, err := client.MoveFile(ctx, moveReq)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("移动", reply); err != nil {
+		return nil, err
+	}
+
+	result.ResultPaths = reply.ResultFilePaths
+	return result, nil
+}
+
+// Copy 复制文件，CD2会创建后台复制任务
+func (s *FileService) Copy(ctx context.Context, req *CopyFilesRequest) (*FileOperationResult, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := cleanPaths(req.Paths)
+	dest := cleanPath(req.DestPath)
+	result := &FileOperationResult{Operation: "copy", DryRun: req.DryRun}
+
+	if req.DryRun {
+		result.Items = planTransfer(ctx, client, paths, dest)
+		return result, nil
+	}
+
+	if err := checkTransferTarget(paths, dest); err != nil {
+		return nil, err
+	}
+
+	reply, err := client.CopyFile(ctx, &pb.CopyFileRequest{TheFilePaths: paths, DestPath: dest})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("复制", reply); err != nil {
+		return nil, err
+	}
+
+	result.ResultPaths = reply.ResultFilePaths
+	return result, nil
+}
+
+// Delete 删除文件到回收站，或在确认后永久删除
+func (s *FileService) Delete(ctx context.Context, req *DeleteFilesRequest) (*FileOperationResult, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := cleanPaths(req.Paths)
+	result := &FileOperationResult{Operation: "delete", DryRun: req.DryRun}
+	if req.Permanent {
+		result.Operation = "delete_permanently"
+	}
+
+	operator := actor.FromContext(ctx)
+	if req.DryRun {
+		for _, p := range paths {
+			result.Items = append(result.Items, planItem(ctx, client, p, ""))
+		}
+		if req.Permanent {
+			expire := time.Now().Add(confirmTokenTTL)
+			result.ConfirmToken = confirmToken(operator.UserID, client.Name, paths, expire)
+			result.ConfirmExpiresAt = &expire
+		}
+		return result, nil
+	}
+
+	// 逐个移入回收站，部分文件失败时返回已完成的结果，失败原因记录在 items 中，全部失败时返回第一个错误
+	if !req.Permanent && config.Get().Trash.Enabled {
+		var firstErr error
+		succeeded := 0
+		for _, p := range paths {
+			item, err := moveToTrash(ctx, client, p, model.TrashSourceAPI, operator.UserID)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				result.Items = append(result.Items, FilePlanItem{Source: p, Error: err.Error()})
+				continue
+			}
+			succeeded++
+			// 期间关闭了回收站时文件已删除到云盘自带的回收站，没有回收站记录
+			planned := FilePlanItem{Source: p, Exists: true}
+			if item != nil {
+				planned.Target, planned.IsDir, planned.Size = item.TrashPath, item.IsDir, item.Size
+				result.ResultPaths = append(result.ResultPaths, item.TrashPath)
+			}
+			result.Items = append(result.Items, planned)
+		}
+		if succeeded == 0 {
+			return nil, firstErr
+		}
+		return result, nil
+	}
+
+	var reply *pb.FileOperationResult
+	if req.Permanent {
+		if !verifyConfirmToken(req.ConfirmToken, operator.UserID, client.Name, paths) {
+			return nil, ErrConfirmRequired
+		}
+		reply, err = client.DeleteFilesPermanently(ctx, &pb.MultiFileRequest{Path: paths})
+	} else {
+		reply, err = client.DeleteFiles(ctx, &pb.MultiFileRequest{Path: paths})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("删除", reply); err != nil {
+		return nil, err
+	}
+
+	result.ResultPaths = reply.ResultFilePaths
+	return result, nil
+}
+
+// planTransfer 生成移动或复制的试运行计划
+func planTransfer(ctx context.Context, client *cd2.Client, paths []string, dest string) []FilePlanItem {
+	items := make([]FilePlanItem, 0, len(paths))
+	for _, p := range paths {
+		item := planItem(ctx, client, p, path.Join(dest, path.Base(p)))
+		if item.Error == "" && isSelfOrChild(dest, p) {
+			item.Error = "不能移动或复制到自身或其子目录"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// checkTransferTarget 检查目标目录不是任何源文件自身或其子目录
+func checkTransferTarget(paths []string, dest string) error {
+	for _, p := range paths {
+		if isSelfOrChild(dest, p) {
+			return fmt.Errorf("不能将 %s 移动或复制到自身或其子目录", p)
+		}
+	}
+	return nil
+}
+
+// isSelfOrChild 判断 p 是否为 dir 自身或其下的路径，两者都需已规范化
+func isSelfOrChild(p, dir string) bool {
+	return p == dir || dir == "/" || strings.HasPrefix(p, dir+"/")
+}
+
+// planItem 检查源文件和目标位置，生成单个文件的试运行结果
+func planItem(ctx context.Context, client *cd2.Client, source, target string) FilePlanItem {
+	item := FilePlanItem{Source: source, Target: target}
+
+	file, exists, err := statFile(ctx, client, source)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	if exists {
+		item.Exists = true
+		item.IsDir = file.IsDirectory
+		item.Size = file.Size
+	} else {
+		item.Error = "源文件不存在"
+	}
+
+	if target != "" && target != source {
+		_, conflict, err := statFile(ctx, client, target)
+		if err != nil {
+			item.Error = err.Error()
+			return item
+		}
+		item.Conflict = conflict
+	}
+
+	return item
+}
+
+// statFile 查询文件，文件不存在时不视为错误
+func statFile(ctx context.Context, client *cd2.Client, p string) (*pb.CloudDriveFile, bool, error) {
+	file, err := findFile(ctx, client, p)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if file == nil || file.FullPathName == "" {
+		return nil, false, nil
+	}
+	return file, true, nil
+}
+
+// checkResult 将CD2的操作结果转换为错误
+func checkResult(op string, result *pb.FileOperationResult) error {
+	if result == nil || result.Success {
+		return nil
+	}
+	return &FileOperationError{Operation: op, Message: result.ErrorMessage}
+}
+
+// validateName 校验文件名
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return errors.New("文件名无效")
+	}
+	if strings.ContainsAny(name, "/\\") {
+		return errors.New("文件名不能包含路径分隔符")
+	}
+	return nil
+}
+
+// cleanPaths 规范化并去重路径
+func cleanPaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = cleanPath(p)
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// confirmToken 生成绑定用户、实例和路径的永久删除确认令牌
+func confirmToken(userID uint, instance string, paths []string, expire time.Time) string {
+	ts := strconv.FormatInt(expire.Unix(), 10)
+	return ts + "." + confirmSignature(userID, instance, paths, ts)
+}
+
+// verifyConfirmToken 校验永久删除确认令牌
+func verifyConfirmToken(token string, userID uint, instance string, paths []string) bool {
+	ts, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	expire, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Now().Unix() > expire {
+		return false
+	}
+
+	expected := confirmSignature(userID, instance, paths, ts)
+	return hmac.Equal([]byte(sig), []byte(expected))
+}
+
+// confirmSignature 计算确认令牌签名
+func confirmSignature(userID uint, instance string, paths []string, ts string) string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	mac := hmac.New(sha256.New, []byte(config.Get().JWT.Secret))
+	fmt.Fprintf(mac, "delete_permanently|%d|%s|%s|%s", userID, instance, ts, strings.Join(sorted, "\n"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+)
+
+// 健康状态
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // 非关键组件异常，服务仍可使用
+	HealthDown     = "down"     // 关键组件异常
+	HealthSkipped  = "skipped"  // 未启用，不参与判断
+)
+
+// 单个组件的检查超时时间
+const healthCheckTimeout = 3 * time.Second
+
+// HealthService 健康检查服务
+type HealthService struct{}
+
+// ComponentHealth 单个组件的检查结果
+type ComponentHealth struct {
+	Name      string         `json:"name"`
+	Status    string         `json:"status"`
+	Critical  bool           `json:"critical"` // 关键组件异常时整体为 down
+	LatencyMs int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Detail    map[string]any `json:"detail,omitempty"`
+}
+
+// HealthReport 健康检查结果
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Uptime     string            `json:"uptime"`
+	Components []ComponentHealth `json:"components,omitempty"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// loopState 后台循环任务的心跳
+type loopState struct {
+	Interval time.Duration
+	LastRun  time.Time
+}
+
+var (
+	startedAt = time.Now()
+
+	loopsMu sync.RWMutex
+	loops   = make(map[string]loopState)
+)
+
+// Live 存活检查，只要进程能处理请求即为正常，不检查依赖
+func (s *HealthService) Live() *HealthReport {
+	return &HealthReport{
+		Status:    HealthOK,
+		Uptime:    time.Since(startedAt).Round(time.Second).String(),
+		CheckedAt: time.Now(),
+	}
+}
+
+// Ready 就绪检查，并发检查数据库、所有CD2实例、挂载点和后台任务
+func (s *HealthService) Ready(ctx context.Context) *HealthReport {
+	checks := []func(context.Context) ComponentHealth{checkDatabase, checkMounts, checkScheduler}
+	for _, c := range cd2.All() {
+		client := c
+		checks = append(checks, func(ctx context.Context) ComponentHealth {
+			return checkCD2(ctx, client)
+		})
+	}
+
+	components := make([]ComponentHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check func(context.Context) ComponentHealth) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			components[i] = check(ctx)
+			components[i].LatencyMs = time.Since(start).Milliseconds()
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &HealthReport{
+		Status:     HealthOK,
+		Uptime:     time.Since(startedAt).Round(time.Second).String(),
+		Components: components,
+		CheckedAt:  time.Now(),
+	}
+	for _, c := range components {
+		if c.Status != HealthOK && c.Status != HealthSkipped {
+			if c.Critical {
+				report.Status = HealthDown
+				break
+			}
+			report.Status = HealthDegraded
+		}
+	}
+	return report
+}
+
+// heartbeat 后台循环任务每轮执行时调用，超过两个周期未执行时就绪检查报告异常
+// 在锁内检查 ctx，任务先取消再清除心跳，已取消的旧循环不会在清除后重新写入心跳
+func heartbeat(ctx context.Context, name string, interval time.Duration) {
+	loopsMu.Lock()
+	defer loopsMu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	loops[name] = loopState{Interval: interval, LastRun: time.Now()}
+}
+
+// clearHeartbeat 任务停止或重新启动时清除心跳，停止的任务不再参与检查
+func clearHeartbeat(name string) {
+	loopsMu.Lock()
+	delete(loops, name)
+	loopsMu.Unlock()
+}
+
+// checkDatabase 检查数据库连接
+func checkDatabase(ctx context.Context) ComponentHealth {
+	h := ComponentHealth{Name: "database", Critical: true, Status: HealthOK}
+
+	sqlDB, err := database.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		h.Status = HealthDown
+		h.Error = err.Error()
+		return h
+	}
+
+	stats := sqlDB.Stats()
+	h.Detail = map[string]any{
+		"type":             config.Get().Database.Type,
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+	}
+	return h
+}
+
+// checkCD2 调用 GetSystemInfo 检查CD2实例是否可用
+func checkCD2(ctx context.Context, client *cd2.Client) ComponentHealth {
+	h := ComponentHealth{Name: "cd2:" + client.Name, Critical: true, Status: HealthOK}
+
+	info, err := client.GetSystemInfo(ctx, &emptypb.Empty{})
+	if err != nil {
+		h.Status = HealthDown
+		h.Error = err.Error()
+		return h
+	}
+
+	h.Detail = map[string]any{
+		"is_login":     info.IsLogin,
+		"system_ready": info.SystemReady,
+	}
+	switch {
+	case info.GetHasError():
+		h.Status = HealthDown
+		h.Error = info.GetSystemMessage()
+	case !info.SystemReady:
+		h.Status = HealthDown
+		h.Error = "CD2尚未就绪"
+		if msg := info.GetSystemMessage(); msg != "" {
+			h.Error += ": " + msg
+		}
+	}
+	return h
+}
+
+// checkMounts 汇总挂载点健康检查结果，未开启健康检查时跳过
+func checkMounts(context.Context) ComponentHealth {
+	h := ComponentHealth{Name: "mounts", Status: HealthOK}
+	if !config.Get().Watchdog.Enabled {
+		h.Status = HealthSkipped
+		return h
+	}
+
+	list := MountHealthList()
+	var failed []string
+	for _, m := range list {
+		if !m.Healthy {
+			failed = append(failed, fmt.Sprintf("%s:%s", m.Instance, m.MountPoint))
+		}
+	}
+	h.Detail = map[string]any{"total": len(list), "unhealthy": len(failed)}
+	if len(failed) > 0 {
+		h.Status = HealthDegraded
+		h.Error = "挂载点不可用: " + strings.Join(failed, ", ")
+	}
+	return h
+}
+
+// checkScheduler 检查后台循环任务是否按周期执行
+func checkScheduler(context.Context) ComponentHealth {
+	h := ComponentHealth{Name: "scheduler", Status: HealthOK}
+
+	loopsMu.RLock()
+	now := time.Now()
+	detail := make(map[string]any, len(loops))
+	var stale []string
+	for name, l := range loops {
+		detail[name] = l.LastRun
+		if now.Sub(l.LastRun) > 2*l.Interval+time.Minute {
+			stale = append(stale, name)
+		}
+	}
+	loopsMu.RUnlock()
+
+	h.Detail = detail
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		h.Status = HealthDegraded
+		h.Error = "后台任务未按时执行: " + strings.Join(stale, ", ")
+	}
+	return h
+}
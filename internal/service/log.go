@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/pkg/logger"
+)
+
+// 日志查询返回的条数
+const (
+	defaultLogLimit = 200
+	maxLogLimit     = 2000
+)
+
+// 日志文件中的时间格式，与 pkg/logger 的时间编码器一致
+const logTimeLayout = "2006-01-02 15:04:05"
+
+// SensitiveRequests 包含敏感字段的请求结构体，标记了 redact:"true" 的字段在请求日志中始终脱敏
+var SensitiveRequests = []any{
+	LoginRequest{}, RegisterRequest{}, UpdatePasswordRequest{},
+	WebDavLoginRequest{}, TokenLoginRequest{}, CloudProxyConfig{},
+}
+
+// LogService 运行日志服务
+type LogService struct{}
+
+// LogEntry 一条运行日志
+type LogEntry struct {
+	Time   string         `json:"time"`
+	Level  string         `json:"level"`
+	Caller string         `json:"caller,omitempty"`
+	Msg    string         `json:"msg"`
+	Fields map[string]any `json:"fields,omitempty"`
+
+	at    time.Time
+	level zapcore.Level
+}
+
+// LogQuery 日志查询条件，Start、End 和 Limit 只用于查询历史日志
+type LogQuery struct {
+	Level     string    `form:"level" binding:"omitempty,oneof=debug info warn error"` // 最低级别
+	RequestID string    `form:"request_id"`
+	Keyword   string    `form:"keyword"` // 在整行中查找，不区分大小写
+	Start     time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	End       time.Time `form:"end" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit"` // 返回最新的多少条，默认200
+}
+
+// CD2LogFile CD2的日志文件
+type CD2LogFile struct {
+	Name       string    `json:"name"`
+	Size       uint64    `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// GetLevel 获取当前日志级别
+func (s *LogService) GetLevel() string {
+	return logger.Level()
+}
+
+// SetLevel 修改日志级别，立即生效，重启后恢复为配置文件中的级别
+func (s *LogService) SetLevel(level string) error {
+	return logger.SetLevel(level)
+}
+
+// Query 按条件查找日志文件（包括轮转和压缩后的文件），返回最新的 Limit 条，按时间正序
+func (s *LogService) Query(query *LogQuery) ([]LogEntry, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLogLimit
+	}
+	if limit > maxLogLimit {
+		limit = maxLogLimit
+	}
+	filter := newLogFilter(query)
+
+	files, err := logFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 从最新的文件开始读取，够数后不再读取更早的文件
+	var entries []LogEntry
+	for _, f := range files {
+		if !query.Start.IsZero() && f.modTime.Before(query.Start) {
+			break
+		}
+		matched, err := readLogFile(f.path, filter, limit)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(matched, entries...)
+		if len(entries) >= limit {
+			break
+		}
+	}
+
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// Stream 实时推送新写入的日志，直到 ctx 结束
+func (s *LogService) Stream(ctx context.Context, query *LogQuery, send func(LogEntry)) {
+	filter := newLogFilter(query)
+	lines, cancel := logger.Subscribe(256)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-lines:
+			if entry, ok := filter.match(line); ok {
+				send(entry)
+			}
+		}
+	}
+}
+
+// CD2LogFiles 列出CD2实例的日志文件
+func (s *LogService) CD2LogFiles(ctx context.Context, instance string) ([]CD2LogFile, error) {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.ListLogFiles(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]CD2LogFile, 0, len(result.GetLogFiles()))
+	for _, f := range result.GetLogFiles() {
+		files = append(files, CD2LogFile{
+			Name:       f.GetFileName(),
+			Size:       f.GetFileSize(),
+			ModifiedAt: f.GetLastModifiedTime().AsTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModifiedAt.After(files[j].ModifiedAt) })
+	return files, nil
+}
+
+// logFilter 日志过滤条件
+type logFilter struct {
+	level     zapcore.Level
+	requestID string
+	keyword   []byte
+	start     time.Time
+	end       time.Time
+}
+
+func newLogFilter(query *LogQuery) *logFilter {
+	f := &logFilter{level: zapcore.DebugLevel, requestID: query.RequestID, start: query.Start, end: query.End}
+	if query.Level != "" {
+		_ = f.level.UnmarshalText([]byte(query.Level))
+	}
+	if query.Keyword != "" {
+		f.keyword = bytes.ToLower([]byte(query.Keyword))
+	}
+	return f
+}
+
+// match 解析一行日志并判断是否符合条件，关键字先在原始内容中匹配，避免解析所有行
+func (f *logFilter) match(line []byte) (LogEntry, bool) {
+	if f.keyword != nil && !bytes.Contains(bytes.ToLower(line), f.keyword) {
+		return LogEntry{}, false
+	}
+	if f.requestID != "" && !bytes.Contains(line, []byte(f.requestID)) {
+		return LogEntry{}, false
+	}
+
+	entry, ok := parseLogLine(line)
+	if !ok || entry.level < f.level {
+		return LogEntry{}, false
+	}
+	if f.requestID != "" && entry.Fields["request_id"] != f.requestID {
+		return LogEntry{}, false
+	}
+	if !f.start.IsZero() && entry.at.Before(f.start) {
+		return LogEntry{}, false
+	}
+	if !f.end.IsZero() && entry.at.After(f.end) {
+		return LogEntry{}, false
+	}
+	return entry, true
+}
+
+// parseLogLine 解析一行JSON格式的日志
+func parseLogLine(line []byte) (LogEntry, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(line), &fields); err != nil {
+		return LogEntry{}, false
+	}
+
+	take := func(key string) string {
+		v, _ := fields[key].(string)
+		delete(fields, key)
+		return v
+	}
+	entry := LogEntry{
+		Time:   take("time"),
+		Level:  take("level"),
+		Caller: take("caller"),
+		Msg:    take("msg"),
+	}
+	if len(fields) > 0 {
+		entry.Fields = fields
+	}
+	if err := entry.level.UnmarshalText([]byte(entry.Level)); err != nil {
+		return LogEntry{}, false
+	}
+	entry.at, _ = time.ParseInLocation(logTimeLayout, entry.Time, time.Local)
+	return entry, true
+}
+
+type logFile struct {
+	path    string
+	modTime time.Time
+}
+
+// logFiles 列出所有日志文件，按修改时间倒序
+// 日志文件按 <文件名>.<日期><扩展名> 命名，lumberjack 轮转后的文件在日期后附加时间戳，压缩后以 .gz 结尾
+func logFiles() ([]logFile, error) {
+	name := config.Get().Log.Filename
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext)
+
+	var paths []string
+	for _, pattern := range []string{prefix + ".*" + ext, prefix + ".*" + ext + ".gz"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	files := make([]logFile, 0, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, logFile{path: p, modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	return files, nil
+}
+
+// readLogFile 读取一个日志文件中符合条件的最新 limit 条日志
+func readLogFile(path string, filter *logFilter, limit int) ([]LogEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if entry, ok := filter.match(scanner.Bytes()); ok {
+			entries = append(entries, entry)
+			// 只保留最新的部分，避免大文件全部读入内存
+			if len(entries) >= 2*limit {
+				entries = append(entries[:0], entries[len(entries)-limit:]...)
+			}
+		}
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cinexus/config"
+	"cinexus/internal/database"
+	"cinexus/pkg/logger"
+)
+
+// testSMTP 测试使用的本地SMTP服务
+var testSMTP *smtpStub
+
+// TestMain 使用临时目录中的SQLite数据库和本地SMTP服务初始化配置、日志和数据库
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "cinexus-service-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	testSMTP, err = newSMTPStub()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer testSMTP.Close()
+
+	file := filepath.Join(dir, "config.toml")
+	content := fmt.Sprintf(`
+[database]
+type = "sqlite"
+sqlite_path = %q
+
+[jwt]
+secret = "test-secret-0123456789abcdef0123456789"
+
+[log]
+level = "error"
+filename = %q
+
+[register]
+mode = "open"
+email_verification = true
+verify_url = "http://localhost/verify"
+
+[smtp]
+host = "127.0.0.1"
+port = %d
+from = "cinexus@example.com"
+ssl = false
+`, filepath.Join(dir, "test.db"), filepath.Join(dir, "logs", "test.log"), testSMTP.Port())
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := config.Init(file); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := logger.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := database.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+	if _, err := database.Migrate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return m.Run()
+}
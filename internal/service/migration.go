@@ -0,0 +1,821 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/notify"
+	"cinexus/pkg/pb"
+)
+
+// 迁移任务默认参数
+const (
+	defaultMigrationConcurrent = 3
+	maxMigrationConcurrent     = 20
+	defaultMigrationRetries    = 2
+	maxMigrationRetries        = 10
+	migrationPollInterval      = 5 * time.Second
+	migrationRetryDelay        = 10 * time.Second
+	migrationPlanPreview       = 100 // 试运行时返回的文件数
+)
+
+// errDestExists 目标位置已有不是本任务复制的文件，重试不会改变结果
+var errDestExists = errors.New("目标位置已存在其他文件，不会覆盖")
+
+// MigrationService 跨云盘迁移服务
+type MigrationService struct{}
+
+// CreateMigrationRequest 创建迁移任务请求
+type CreateMigrationRequest struct {
+	Instance      string `json:"instance"`
+	SourcePath    string `json:"source_path" binding:"required"`
+	DestPath      string `json:"dest_path" binding:"required"`
+	VerifyHash    bool   `json:"verify_hash"`
+	DeleteSource  bool   `json:"delete_source"`
+	MaxConcurrent int    `json:"max_concurrent"`
+	MaxRetries    *int   `json:"max_retries"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+// MigrationPlan 迁移计划，试运行时返回
+type MigrationPlan struct {
+	Total      int                   `json:"total"`       // 需要复制的文件数
+	TotalBytes int64                 `json:"total_bytes"` // 需要复制的总大小
+	Skipped    int                   `json:"skipped"`     // 目标已存在且一致的文件数
+	Items      []model.MigrationItem `json:"items"`       // 前 100 个文件
+}
+
+// MigrationQuery 迁移任务查询条件
+type MigrationQuery struct {
+	PageRequest
+	Instance string `form:"instance"`
+	Status   string `form:"status"`
+}
+
+// MigrationItemQuery 迁移文件查询条件
+type MigrationItemQuery struct {
+	PageRequest
+	Status string `form:"status"`
+}
+
+// MigrationReport 迁移报告
+type MigrationReport struct {
+	Job           *model.MigrationJob   `json:"job"`
+	Duration      string                `json:"duration"`
+	VerifiedBytes int64                 `json:"verified_bytes"` // 复制成功的大小，包括只比较了大小的文件
+	HashVerified  int                   `json:"hash_verified"`  // 通过哈希校验的文件数，其余只比较了大小
+	ByStatus      map[string]int        `json:"by_status"`
+	FailedItems   []model.MigrationItem `json:"failed_items"`
+}
+
+// 正在执行的迁移任务
+var migrations = struct {
+	sync.Mutex
+	ctx     context.Context
+	cancels map[uint]context.CancelFunc
+}{
+	ctx:     context.Background(),
+	cancels: make(map[uint]context.CancelFunc),
+}
+
+// StartMigrations 设置迁移任务的根上下文，并将上次服务退出时未完成的任务标记为中断
+func StartMigrations(ctx context.Context) {
+	migrations.Lock()
+	migrations.ctx = ctx
+	migrations.Unlock()
+
+	err := database.Ctx(ctx).Model(&model.MigrationJob{}).
+		Where("status IN ?", []string{model.MigrationStatusPending, model.MigrationStatusPlanning, model.MigrationStatusRunning}).
+		Updates(map[string]interface{}{
+			"status": model.MigrationStatusCancelled,
+			"error":  "服务重启导致任务中断，可调用恢复接口继续",
+		}).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("标记中断的迁移任务失败", zap.Error(err))
+	}
+}
+
+// Create 创建迁移任务并在后台执行，试运行时只返回迁移计划
+func (s *MigrationService) Create(ctx context.Context, req *CreateMigrationRequest) (*model.MigrationJob, *MigrationPlan, error) {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := cleanPath(req.SourcePath)
+	dest := cleanPath(req.DestPath)
+	if source == "/" {
+		return nil, nil, errors.New("不能迁移根目录")
+	}
+	if source == dest || strings.HasPrefix(dest, source+"/") || strings.HasPrefix(source, dest+"/") {
+		return nil, nil, errors.New("源目录和目标目录不能相同或互相包含")
+	}
+	if req.DeleteSource && !req.VerifyHash {
+		return nil, nil, errors.New("删除源文件需要开启 verify_hash，只比较大小无法确认文件一致")
+	}
+
+	job := &model.MigrationJob{
+		UserID:        actor.FromContext(ctx).UserID,
+		Instance:      client.Name,
+		SourcePath:    source,
+		DestPath:      dest,
+		VerifyHash:    req.VerifyHash,
+		DeleteSource:  req.DeleteSource,
+		MaxConcurrent: clampInt(req.MaxConcurrent, defaultMigrationConcurrent, maxMigrationConcurrent),
+		MaxRetries:    defaultMigrationRetries,
+		Status:        model.MigrationStatusPending,
+	}
+	if req.MaxRetries != nil {
+		job.MaxRetries = clampInt(*req.MaxRetries, 0, maxMigrationRetries)
+	}
+
+	if req.DryRun {
+		items, skipped, err := planMigration(ctx, client, job)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plan := &MigrationPlan{Total: len(items), Skipped: skipped, Items: items}
+		for _, item := range items {
+			plan.TotalBytes += item.Size
+		}
+		if len(plan.Items) > migrationPlanPreview {
+			plan.Items = plan.Items[:migrationPlanPreview]
+		}
+		return nil, plan, nil
+	}
+
+	if err := database.Ctx(ctx).Create(job).Error; err != nil {
+		return nil, nil, err
+	}
+
+	startMigration(ctx, job.ID)
+	return job, nil, nil
+}
+
+// List 分页查询迁移任务
+func (s *MigrationService) List(ctx context.Context, query *MigrationQuery) ([]model.MigrationJob, int64, error) {
+	var jobs []model.MigrationJob
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.MigrationJob{})
+	if query.Instance != "" {
+		db = db.Where("instance = ?", query.Instance)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// Get 获取迁移任务
+func (s *MigrationService) Get(ctx context.Context, id uint) (*model.MigrationJob, error) {
+	var job model.MigrationJob
+	if err := database.Ctx(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("迁移任务不存在")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListItems 分页查询迁移任务的文件
+func (s *MigrationService) ListItems(ctx context.Context, id uint, query *MigrationItemQuery) ([]model.MigrationItem, int64, error) {
+	var items []model.MigrationItem
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.MigrationItem{}).Where("job_id = ?", id)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id").Offset(query.Offset()).Limit(query.PageSize).Find(&items).Error
+	return items, total, err
+}
+
+// Report 生成迁移报告
+func (s *MigrationService) Report(ctx context.Context, id uint) (*MigrationReport, error) {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{Job: job, ByStatus: make(map[string]int)}
+	if job.StartedAt != nil {
+		end := time.Now()
+		if job.FinishedAt != nil {
+			end = *job.FinishedAt
+		}
+		report.Duration = end.Sub(*job.StartedAt).Round(time.Second).String()
+	}
+
+	var counts []struct {
+		Status string
+		Count  int
+		Bytes  int64
+	}
+	err = database.Ctx(ctx).Model(&model.MigrationItem{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(size), 0) AS bytes").
+		Where("job_id = ?", id).Group("status").Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		report.ByStatus[c.Status] = c.Count
+		if c.Status == model.MigrationItemVerified || c.Status == model.MigrationItemSizeOnly {
+			report.VerifiedBytes += c.Bytes
+		}
+	}
+
+	var hashVerified int64
+	err = database.Ctx(ctx).Model(&model.MigrationItem{}).
+		Where("job_id = ? AND status = ? AND hash_type <> ''", id, model.MigrationItemVerified).
+		Count(&hashVerified).Error
+	if err != nil {
+		return nil, err
+	}
+	report.HashVerified = int(hashVerified)
+
+	err = database.Ctx(ctx).Where("job_id = ? AND status = ?", id, model.MigrationItemFailed).
+		Order("id").Find(&report.FailedItems).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Cancel 取消正在执行的迁移任务，已提交给CD2的复制任务不会被取消
+func (s *MigrationService) Cancel(id uint) error {
+	migrations.Lock()
+	cancel, ok := migrations.cancels[id]
+	migrations.Unlock()
+
+	if !ok {
+		return errors.New("迁移任务未在执行")
+	}
+	cancel()
+	return nil
+}
+
+// Resume 恢复已结束的迁移任务，重新处理未完成和失败的文件
+func (s *MigrationService) Resume(ctx context.Context, id uint) (*model.MigrationJob, error) {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations.Lock()
+	_, running := migrations.cancels[id]
+	migrations.Unlock()
+	if running {
+		return nil, errors.New("迁移任务正在执行")
+	}
+	if job.Status == model.MigrationStatusCompleted {
+		return nil, errors.New("迁移任务已完成")
+	}
+
+	err = database.Ctx(ctx).Model(&model.MigrationItem{}).
+		Where("job_id = ? AND status IN ?", id, []string{model.MigrationItemFailed, model.MigrationItemCopying}).
+		Updates(map[string]interface{}{"status": model.MigrationItemPending, "attempts": 0}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	startMigration(ctx, id)
+	return job, nil
+}
+
+// startMigration 在后台执行迁移任务，保留发起人用于审计
+func startMigration(ctx context.Context, id uint) {
+	migrations.Lock()
+	runCtx, cancel := context.WithCancel(actor.With(migrations.ctx, actor.FromContext(ctx)))
+	migrations.cancels[id] = cancel
+	migrations.Unlock()
+
+	go func() {
+		defer func() {
+			migrations.Lock()
+			delete(migrations.cancels, id)
+			migrations.Unlock()
+			cancel()
+		}()
+
+		if err := runMigration(runCtx, id); err != nil {
+			logger.Ctx(ctx).Error("迁移任务执行失败", zap.Uint("job_id", id), zap.Error(err))
+		}
+	}()
+}
+
+// runMigration 执行迁移任务：首次执行时扫描源目录生成文件列表，然后限制并发复制并校验
+func runMigration(ctx context.Context, id uint) error {
+	var job model.MigrationJob
+	if err := database.Ctx(ctx).First(&job, id).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.StartedAt = &now
+	job.FinishedAt = nil
+	job.Error = ""
+
+	client, err := cd2.Get(job.Instance)
+	if err != nil {
+		return finishMigration(&job, err)
+	}
+
+	var planned int64
+	if err := database.Ctx(ctx).Model(&model.MigrationItem{}).Where("job_id = ?", id).Count(&planned).Error; err != nil {
+		return finishMigration(&job, err)
+	}
+
+	if planned == 0 {
+		job.Status = model.MigrationStatusPlanning
+		if err := saveMigrationJob(&job); err != nil {
+			return err
+		}
+
+		items, skipped, err := planMigration(ctx, client, &job)
+		if err != nil {
+			return finishMigration(&job, err)
+		}
+
+		job.Total = len(items)
+		job.Skipped = skipped
+		for _, item := range items {
+			job.TotalBytes += item.Size
+		}
+		if len(items) > 0 {
+			if err := database.Ctx(ctx).CreateInBatches(items, 200).Error; err != nil {
+				return finishMigration(&job, err)
+			}
+		}
+	}
+
+	job.Status = model.MigrationStatusRunning
+	if err := saveMigrationJob(&job); err != nil {
+		return err
+	}
+
+	var pending []model.MigrationItem
+	if err := database.Ctx(ctx).Where("job_id = ? AND status = ?", id, model.MigrationItemPending).Order("id").Find(&pending).Error; err != nil {
+		return finishMigration(&job, err)
+	}
+
+	m := &migrator{client: client, job: &job, dirs: make(map[string]bool)}
+	queue := make(chan *model.MigrationItem)
+	var wg sync.WaitGroup
+	for i := 0; i < job.MaxConcurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				m.migrate(ctx, item)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range pending {
+		select {
+		case queue <- &pending[i]:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return finishMigration(&job, ctx.Err())
+}
+
+// planMigration 递归扫描源目录，目标已存在且一致的文件计为跳过
+func planMigration(ctx context.Context, client *cd2.Client, job *model.MigrationJob) ([]model.MigrationItem, int, error) {
+	if _, exists, err := statFile(ctx, client, job.SourcePath); err != nil {
+		return nil, 0, err
+	} else if !exists {
+		return nil, 0, errors.New("源目录不存在")
+	}
+
+	var items []model.MigrationItem
+	skipped := 0
+
+	dirs := []string{job.SourcePath}
+	for len(dirs) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		files, err := listSubFiles(ctx, client, dir, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("读取目录 %s 失败: %w", dir, err)
+		}
+
+		for _, f := range files {
+			if f.IsDir {
+				dirs = append(dirs, f.Path)
+				continue
+			}
+
+			dest := path.Join(job.DestPath, strings.TrimPrefix(f.Path, job.SourcePath))
+			target, exists, err := statFile(ctx, client, dest)
+			if err != nil {
+				return nil, 0, err
+			}
+			// 要求校验哈希时只有哈希一致才跳过，没有共同哈希类型的文件交给迁移流程记录为只比较了大小
+			if exists && target.Size == f.Size {
+				if _, ok := compareHashes(f.Hashes, toFileInfo(target).Hashes); ok || !job.VerifyHash {
+					skipped++
+					continue
+				}
+			}
+
+			items = append(items, model.MigrationItem{
+				JobID:      job.ID,
+				SourcePath: f.Path,
+				DestPath:   dest,
+				Size:       f.Size,
+				Status:     model.MigrationItemPending,
+			})
+		}
+	}
+
+	return items, skipped, nil
+}
+
+// migrator 单个迁移任务的执行状态
+type migrator struct {
+	client *cd2.Client
+	job    *model.MigrationJob
+
+	mu   sync.Mutex
+	dirs map[string]bool // 已确认存在的目标目录
+}
+
+// migrate 复制并校验单个文件，失败时按任务设置重试
+func (m *migrator) migrate(ctx context.Context, item *model.MigrationItem) {
+	for {
+		item.Attempts++
+		item.Status = model.MigrationItemCopying
+		item.Error = ""
+		saveMigrationItem(item)
+
+		err := m.copyAndVerify(ctx, item)
+		if err == nil {
+			// 只有共同的哈希一致时才删除源文件
+			item.Status = model.MigrationItemVerified
+			if item.HashType == "" {
+				item.Status = model.MigrationItemSizeOnly
+			} else if m.job.DeleteSource {
+				m.deleteSource(ctx, item)
+			}
+			saveMigrationItem(item)
+			return
+		}
+
+		item.Error = truncate(err.Error(), 512)
+		if ctx.Err() != nil {
+			// 取消时保持待处理状态，恢复后重新复制
+			item.Status = model.MigrationItemPending
+			item.Attempts--
+			saveMigrationItem(item)
+			return
+		}
+		if item.Attempts > m.job.MaxRetries || errors.Is(err, errDestExists) {
+			item.Status = model.MigrationItemFailed
+			saveMigrationItem(item)
+			return
+		}
+
+		logger.Ctx(ctx).Warn("迁移文件失败，稍后重试",
+			zap.Uint("job_id", m.job.ID),
+			zap.String("path", item.SourcePath),
+			zap.Int("attempt", item.Attempts),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+		case <-time.After(migrationRetryDelay * time.Duration(item.Attempts)):
+		}
+	}
+}
+
+// copyAndVerify 提交复制并等待目标文件出现，然后校验大小和哈希
+// 目标已存在时只替换本任务复制创建的文件，其他文件一致时视为已迁移，否则按冲突失败
+func (m *migrator) copyAndVerify(ctx context.Context, item *model.MigrationItem) error {
+	source, exists, err := statFile(ctx, m.client, item.SourcePath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("源文件不存在")
+	}
+
+	destDir := path.Dir(item.DestPath)
+	if err := m.mkdirAll(ctx, destDir); err != nil {
+		return err
+	}
+
+	// 上次提交的复制仍在进行（如等待超时），继续等待而不是重复提交
+	if task := m.findCopyTask(ctx, item.SourcePath, destDir); task != nil && !copyTaskDone(task) {
+		return m.waitAndVerify(ctx, item, source)
+	}
+
+	target, exists, err := statFile(ctx, m.client, item.DestPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if target.Size == source.Size {
+			// 大小相同时先校验，哈希不一致的文件不能靠重新校验恢复
+			err := m.verify(item, source, target)
+			if err == nil {
+				return nil
+			}
+			if !item.DestCreated {
+				return fmt.Errorf("%w: %v", errDestExists, err)
+			}
+		} else if !item.DestCreated {
+			return errDestExists
+		}
+
+		// 本任务复制的不完整或不一致的文件先移入回收站，避免复制时冲突
+		if _, err := moveToTrash(ctx, m.client, item.DestPath, model.TrashSourceMigration, m.job.UserID); err != nil {
+			return fmt.Errorf("删除本任务复制的目标文件失败: %w", err)
+		}
+	}
+
+	reply, err := m.client.CopyFile(ctx, &pb.CopyFileRequest{TheFilePaths: []string{item.SourcePath}, DestPath: destDir})
+	if err != nil {
+		return err
+	}
+	if err := checkResult("复制", reply); err != nil {
+		return err
+	}
+	item.DestCreated = true
+	saveMigrationItem(item)
+
+	return m.waitAndVerify(ctx, item, source)
+}
+
+// waitAndVerify 等待复制完成后校验目标文件
+func (m *migrator) waitAndVerify(ctx context.Context, item *model.MigrationItem, source *pb.CloudDriveFile) error {
+	target, err := m.waitCopied(ctx, item, source.Size)
+	if err != nil {
+		return err
+	}
+	return m.verify(item, source, target)
+}
+
+// waitCopied 轮询等待CD2完成复制，复制任务失败或超时时返回错误
+func (m *migrator) waitCopied(ctx context.Context, item *model.MigrationItem, size int64) (*pb.CloudDriveFile, error) {
+	// 超时时间按 1MB/s 的最低速度估算，至少10分钟
+	deadline := time.Now().Add(10*time.Minute + time.Duration(size>>20)*time.Second)
+	destDir := path.Dir(item.DestPath)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(migrationPollInterval):
+		}
+
+		target, exists, err := statFile(ctx, m.client, item.DestPath)
+		if err != nil {
+			return nil, err
+		}
+		if exists && target.Size == size {
+			return target, nil
+		}
+
+		if task := m.findCopyTask(ctx, item.SourcePath, destDir); task != nil && task.Status == pb.CopyTask_Failed {
+			msg := "CD2复制任务失败"
+			if n := len(task.Errors); n > 0 {
+				msg += ": " + task.Errors[n-1].Message
+			}
+			return nil, errors.New(msg)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("等待复制完成超时")
+		}
+	}
+}
+
+// findCopyTask 查找文件对应的CD2复制任务，有多个时优先返回未结束的任务
+func (m *migrator) findCopyTask(ctx context.Context, source, destDir string) *pb.CopyTask {
+	result, err := m.client.GetCopyTasks(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil
+	}
+	var found *pb.CopyTask
+	for _, t := range result.CopyTasks {
+		if t.SourcePath != source || t.DestPath != destDir {
+			continue
+		}
+		if !copyTaskDone(t) {
+			return t
+		}
+		found = t
+	}
+	return found
+}
+
+// copyTaskDone 复制任务是否已结束
+func copyTaskDone(task *pb.CopyTask) bool {
+	return task.Status == pb.CopyTask_Completed || task.Status == pb.CopyTask_Failed
+}
+
+// verify 比较大小，要求校验哈希时比较双方都有的哈希类型，比较过的哈希类型记录在 HashType 中
+func (m *migrator) verify(item *model.MigrationItem, source, target *pb.CloudDriveFile) error {
+	item.HashType = ""
+	if source.Size != target.Size {
+		return fmt.Errorf("大小不一致: 源 %d，目标 %d", source.Size, target.Size)
+	}
+	if !m.job.VerifyHash {
+		return nil
+	}
+
+	hashType, ok := compareHashes(toFileInfo(source).Hashes, toFileInfo(target).Hashes)
+	if hashType == "" {
+		// 部分云盘不提供哈希，只能以大小为准，HashType 为空时不删除源文件
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("%s 哈希不一致", hashType)
+	}
+	item.HashType = hashType
+	return nil
+}
+
+// deleteSource 校验通过后将源文件移入回收站，删除失败不影响迁移结果
+func (m *migrator) deleteSource(ctx context.Context, item *model.MigrationItem) {
+	if _, err := moveToTrash(ctx, m.client, item.SourcePath, model.TrashSourceMigration, m.job.UserID); err != nil {
+		item.Error = truncate("删除源文件失败: "+err.Error(), 512)
+		return
+	}
+	item.SourceDeleted = true
+}
+
+// mkdirAll 逐级创建目标目录
+func (m *migrator) mkdirAll(ctx context.Context, dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.mkdir(ctx, dir)
+}
+
+// mkdir 创建目录，调用时需持有锁
+func (m *migrator) mkdir(ctx context.Context, dir string) error {
+	if dir == "/" || m.dirs[dir] {
+		return nil
+	}
+
+	_, exists, err := statFile(ctx, m.client, dir)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := m.mkdir(ctx, path.Dir(dir)); err != nil {
+			return err
+		}
+		reply, err := m.client.CreateFolder(ctx, &pb.CreateFolderRequest{ParentPath: path.Dir(dir), FolderName: path.Base(dir)})
+		if err != nil {
+			return err
+		}
+		if err := checkResult("创建目录", reply.Result); err != nil {
+			return err
+		}
+	}
+
+	m.dirs[dir] = true
+	return nil
+}
+
+// compareHashes 返回双方都有的哈希类型及是否一致，没有共同的哈希类型时返回空字符串
+func compareHashes(a, b map[string]string) (string, bool) {
+	for _, t := range []string{"sha1", "md5", "pikpak_sha1"} {
+		if a[t] != "" && b[t] != "" {
+			return t, a[t] == b[t]
+		}
+	}
+	return "", false
+}
+
+// finishMigration 统计结果并结束任务
+func finishMigration(job *model.MigrationJob, runErr error) error {
+	now := time.Now()
+	job.FinishedAt = &now
+
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	err := database.DB.Model(&model.MigrationItem{}).Select("status, COUNT(*) AS count").
+		Where("job_id = ?", job.ID).Group("status").Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	job.Verified, job.Failed = 0, 0
+	for _, c := range counts {
+		switch c.Status {
+		case model.MigrationItemVerified, model.MigrationItemSizeOnly:
+			job.Verified += c.Count
+		case model.MigrationItemFailed:
+			job.Failed = c.Count
+		}
+	}
+
+	var deleted int64
+	if err := database.DB.Model(&model.MigrationItem{}).Where("job_id = ? AND source_deleted = ?", job.ID, true).Count(&deleted).Error; err != nil {
+		return err
+	}
+	job.SourceDeleted = int(deleted)
+
+	switch {
+	case errors.Is(runErr, context.Canceled):
+		job.Status = model.MigrationStatusCancelled
+		job.Error = "任务已取消"
+	case runErr != nil:
+		job.Status = model.MigrationStatusFailed
+		job.Error = truncate(runErr.Error(), 512)
+	case job.Failed == 0 && job.Verified == job.Total:
+		job.Status = model.MigrationStatusCompleted
+	case job.Verified == 0 && job.Total > 0:
+		job.Status = model.MigrationStatusFailed
+	default:
+		job.Status = model.MigrationStatusPartial
+	}
+
+	if err := saveMigrationJob(job); err != nil {
+		return err
+	}
+	if job.StartedAt != nil {
+		metrics.ObserveJob("migration", job.Status, *job.StartedAt)
+	}
+
+	level := notify.LevelInfo
+	if job.Status != model.MigrationStatusCompleted {
+		level = notify.LevelWarn
+	}
+	notify.Send(level, "迁移任务结束", fmt.Sprintf("迁移任务 #%d（%s -> %s）%s：共 %d 个文件，校验通过 %d，跳过 %d，失败 %d，已删除源文件 %d。%s",
+		job.ID, job.SourcePath, job.DestPath, job.Status, job.Total, job.Verified, job.Skipped, job.Failed, job.SourceDeleted, job.Error))
+
+	return runErr
+}
+
+// saveMigrationJob 保存任务进度
+func saveMigrationJob(job *model.MigrationJob) error {
+	return database.DB.Model(job).Select(
+		"status", "total", "verified", "skipped", "failed", "source_deleted", "total_bytes", "error", "started_at", "finished_at",
+	).Updates(job).Error
+}
+
+// saveMigrationItem 保存文件迁移状态
+func saveMigrationItem(item *model.MigrationItem) {
+	err := database.DB.Model(item).Select("status", "attempts", "hash_type", "source_deleted", "error").Updates(item).Error
+	if err != nil {
+		logger.Error("保存迁移记录失败", zap.Uint("item_id", item.ID), zap.Error(err))
+	}
+}
+
+// clampInt 限制取值范围，不大于0时使用默认值
+func clampInt(v, def, limit int) int {
+	if v <= 0 {
+		return def
+	}
+	if v > limit {
+		return limit
+	}
+	return v
+}
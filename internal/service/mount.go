@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/pb"
+)
+
+// MountService 挂载点管理服务
+type MountService struct{}
+
+// MountOptionRequest 挂载选项
+type MountOptionRequest struct {
+	MountPoint  string `json:"mount_point" binding:"required"`
+	SourceDir   string `json:"source_dir" binding:"required"`
+	Name        string `json:"name"`
+	LocalMount  bool   `json:"local_mount"`
+	ReadOnly    bool   `json:"read_only"`
+	AutoMount   bool   `json:"auto_mount"`
+	UID         uint32 `json:"uid"`
+	GID         uint32 `json:"gid"`
+	Permissions string `json:"permissions"`
+}
+
+// UpdateMountRequest 更新挂载点请求
+type UpdateMountRequest struct {
+	MountPoint string             `json:"mount_point" binding:"required"`
+	Option     MountOptionRequest `json:"option" binding:"required"`
+}
+
+// MountPointRequest 挂载点操作请求
+type MountPointRequest struct {
+	MountPoint string `json:"mount_point" form:"mount_point" binding:"required"`
+}
+
+// MountInfo 挂载点信息
+type MountInfo struct {
+	MountOptionRequest
+	IsMounted  bool   `json:"is_mounted"`
+	FailReason string `json:"fail_reason,omitempty"`
+	Declared   bool   `json:"declared"` // 是否在配置文件中声明
+}
+
+// ReconcileAction 调整挂载点时执行的单个操作
+type ReconcileAction struct {
+	MountPoint string `json:"mount_point"`
+	Action     string `json:"action"` // add, update, mount
+	Error      string `json:"error,omitempty"`
+}
+
+// ReconcileResult 单个实例的挂载点调整结果
+type ReconcileResult struct {
+	Instance string            `json:"instance"`
+	Actions  []ReconcileAction `json:"actions"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// 同一实例的调整操作串行执行
+var reconcileLocks sync.Map
+
+// Validate 校验挂载选项，与配置文件中声明的挂载点使用相同的规则
+func (r *MountOptionRequest) Validate() error {
+	return config.MountConfig{
+		MountPoint:  r.MountPoint,
+		SourceDir:   r.SourceDir,
+		LocalMount:  r.LocalMount,
+		ReadOnly:    r.ReadOnly,
+		Permissions: r.Permissions,
+	}.Validate()
+}
+
+// toProto 转换为CD2挂载选项
+func (r *MountOptionRequest) toProto() *pb.MountOption {
+	return &pb.MountOption{
+		MountPoint:  strings.TrimSpace(r.MountPoint),
+		SourceDir:   r.SourceDir,
+		LocalMount:  r.LocalMount,
+		ReadOnly:    r.ReadOnly,
+		AutoMount:   r.AutoMount,
+		Uid:         r.UID,
+		Gid:         r.GID,
+		Permissions: r.Permissions,
+		Name:        r.Name,
+	}
+}
+
+// List 获取挂载点列表，并标记配置文件中声明的挂载点
+func (s *MountService) List(ctx context.Context, instance string) ([]MountInfo, error) {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.GetMountPoints(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]bool)
+	for _, m := range client.Config().Mounts {
+		declared[m.MountPoint] = true
+	}
+
+	mounts := make([]MountInfo, 0, len(result.MountPoints))
+	for _, m := range result.MountPoints {
+		mounts = append(mounts, MountInfo{
+			MountOptionRequest: MountOptionRequest{
+				MountPoint:  m.MountPoint,
+				SourceDir:   m.SourceDir,
+				LocalMount:  m.LocalMount,
+				ReadOnly:    m.ReadOnly,
+				AutoMount:   m.AutoMount,
+				UID:         m.Uid,
+				GID:         m.Gid,
+				Permissions: m.Permissions,
+			},
+			IsMounted:  m.IsMounted,
+			FailReason: m.FailReason,
+			Declared:   declared[m.MountPoint],
+		})
+	}
+
+	return mounts, nil
+}
+
+// CanAddMore 检查是否还能添加挂载点
+func (s *MountService) CanAddMore(ctx context.Context, instance string) (bool, string, error) {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return false, "", err
+	}
+
+	result, err := client.CanAddMoreMountPoints(ctx, &emptypb.Empty{})
+	if err != nil {
+		return false, "", err
+	}
+	return result.Success, result.ErrorMessage, nil
+}
+
+// Add 添加挂载点
+func (s *MountService) Add(ctx context.Context, instance string, req *MountOptionRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	if can, reason, err := s.CanAddMore(ctx, instance); err != nil {
+		return err
+	} else if !can {
+		return &FileOperationError{Operation: "添加挂载点", Message: reason}
+	}
+
+	result, err := client.AddMountPoint(ctx, req.toProto())
+	if err != nil {
+		return err
+	}
+	return checkMountResult("添加挂载点", result)
+}
+
+// Update 更新挂载点选项
+func (s *MountService) Update(ctx context.Context, instance string, req *UpdateMountRequest) error {
+	if err := req.Option.Validate(); err != nil {
+		return err
+	}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.UpdateMountPoint(ctx, &pb.UpdateMountPointRequest{
+		MountPoint:     req.MountPoint,
+		NewMountOption: req.Option.toProto(),
+	})
+	if err != nil {
+		return err
+	}
+	return checkMountResult("更新挂载点", result)
+}
+
+// Mount 挂载
+func (s *MountService) Mount(ctx context.Context, instance, mountPoint string) error {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.Mount(ctx, &pb.MountPointRequest{MountPoint: mountPoint})
+	if err != nil {
+		return err
+	}
+	return checkMountResult("挂载", result)
+}
+
+// Unmount 卸载
+func (s *MountService) Unmount(ctx context.Context, instance, mountPoint string) error {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.Unmount(ctx, &pb.MountPointRequest{MountPoint: mountPoint})
+	if err != nil {
+		return err
+	}
+	return checkMountResult("卸载", result)
+}
+
+// Remove 删除挂载点，配置文件中声明的挂载点会在下次调整时重新创建
+func (s *MountService) Remove(ctx context.Context, instance, mountPoint string) error {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.RemoveMountPoint(ctx, &pb.MountPointRequest{MountPoint: mountPoint})
+	if err != nil {
+		return err
+	}
+	return checkMountResult("删除挂载点", result)
+}
+
+// ReconcileAll 按配置调整所有实例的挂载点
+func (s *MountService) ReconcileAll(ctx context.Context) []ReconcileResult {
+	var results []ReconcileResult
+	for _, c := range cd2.All() {
+		if len(c.Config().Mounts) == 0 {
+			continue
+		}
+		results = append(results, s.Reconcile(ctx, c.Name))
+	}
+	return results
+}
+
+// Reconcile 按配置调整单个实例的挂载点：缺少的添加，选项不一致的更新，未挂载的挂载
+func (s *MountService) Reconcile(ctx context.Context, instance string) ReconcileResult {
+	result := ReconcileResult{Instance: instance, Actions: []ReconcileAction{}}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Instance = client.Name
+
+	lock, _ := reconcileLocks.LoadOrStore(client.Name, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	current, err := client.GetMountPoints(ctx, &emptypb.Empty{})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	existing := make(map[string]*pb.MountPoint, len(current.MountPoints))
+	for _, m := range current.MountPoints {
+		existing[m.MountPoint] = m
+	}
+
+	for _, conf := range client.Config().Mounts {
+		desired := mountOptionFromConfig(conf)
+		action := ReconcileAction{MountPoint: desired.MountPoint}
+
+		if err := desired.Validate(); err != nil {
+			action.Action = "validate"
+			action.Error = err.Error()
+			result.Actions = append(result.Actions, action)
+			continue
+		}
+
+		m, ok := existing[desired.MountPoint]
+		switch {
+		case !ok:
+			action.Action = "add"
+			err = s.Add(ctx, client.Name, &desired)
+		case mountDiffers(m, &desired):
+			action.Action = "update"
+			err = s.Update(ctx, client.Name, &UpdateMountRequest{MountPoint: desired.MountPoint, Option: desired})
+		case !m.IsMounted:
+			action.Action = "mount"
+			err = s.Mount(ctx, client.Name, desired.MountPoint)
+		default:
+			continue
+		}
+
+		// 新增或更新后CD2不一定自动挂载，这里统一确保挂载
+		if err == nil && action.Action != "mount" {
+			err = s.ensureMounted(ctx, client, desired.MountPoint)
+		}
+		if err != nil {
+			action.Error = err.Error()
+		}
+		result.Actions = append(result.Actions, action)
+	}
+
+	return result
+}
+
+// StartMountReconciler 启动时调整一次挂载点，之后在CD2推送挂载失败或被卸载时重新调整
+func StartMountReconciler(ctx context.Context) {
+	s := &MountService{}
+
+	var mu sync.Mutex
+	pending := make(map[string]*time.Timer)
+
+	cd2.Subscribe(func(instance string, msg *pb.CloudDrivePushMessage) {
+		change := msg.GetMountPointChange()
+		if change == nil || (change.ActionType == pb.MountPointChange_MOUNT && change.Success) {
+			return
+		}
+		if !isDeclaredMount(instance, change.MountPoint) {
+			return
+		}
+
+		logger.Ctx(ctx).Warn("声明的挂载点状态变化，准备重新调整",
+			zap.String("instance", instance),
+			zap.String("mount_point", change.MountPoint),
+			zap.String("fail_reason", change.FailReason),
+		)
+
+		// 短时间内的多条消息合并为一次调整
+		mu.Lock()
+		defer mu.Unlock()
+		if t, ok := pending[instance]; ok {
+			t.Stop()
+		}
+		pending[instance] = time.AfterFunc(5*time.Second, func() {
+			if ctx.Err() != nil {
+				return
+			}
+			logReconcile(s.Reconcile(ctx, instance))
+		})
+	})
+
+	reconcileAll := func() {
+		for _, result := range s.ReconcileAll(ctx) {
+			logReconcile(result)
+		}
+	}
+	go reconcileAll()
+
+	// CD2实例或声明的挂载点修改后重新调整
+	config.OnChange("cd2", func(_, _ *config.Config) {
+		if ctx.Err() == nil {
+			go reconcileAll()
+		}
+	})
+}
+
+// ensureMounted 如果挂载点未挂载则执行挂载
+func (s *MountService) ensureMounted(ctx context.Context, client *cd2.Client, mountPoint string) error {
+	current, err := client.GetMountPoints(ctx, &emptypb.Empty{})
+	if err != nil {
+		return err
+	}
+	for _, m := range current.MountPoints {
+		if m.MountPoint == mountPoint && !m.IsMounted {
+			return s.Mount(ctx, client.Name, mountPoint)
+		}
+	}
+	return nil
+}
+
+// mountDiffers 比较当前挂载点与期望的选项是否一致
+func mountDiffers(m *pb.MountPoint, desired *MountOptionRequest) bool {
+	return m.SourceDir != desired.SourceDir ||
+		m.LocalMount != desired.LocalMount ||
+		m.ReadOnly != desired.ReadOnly ||
+		m.AutoMount != desired.AutoMount ||
+		m.Uid != desired.UID ||
+		m.Gid != desired.GID ||
+		(desired.Permissions != "" && m.Permissions != desired.Permissions)
+}
+
+// mountOptionFromConfig 将配置转换为挂载选项
+func mountOptionFromConfig(conf config.MountConfig) MountOptionRequest {
+	return MountOptionRequest{
+		MountPoint:  conf.MountPoint,
+		SourceDir:   conf.SourceDir,
+		Name:        conf.Name,
+		LocalMount:  conf.LocalMount,
+		ReadOnly:    conf.ReadOnly,
+		AutoMount:   conf.AutoMount,
+		UID:         conf.UID,
+		GID:         conf.GID,
+		Permissions: conf.Permissions,
+	}
+}
+
+// isDeclaredMount 检查挂载点是否在配置中声明
+func isDeclaredMount(instance, mountPoint string) bool {
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return false
+	}
+	for _, m := range client.Config().Mounts {
+		if m.MountPoint == mountPoint {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMountResult 将CD2的挂载操作结果转换为错误
+func checkMountResult(op string, result *pb.MountPointResult) error {
+	if result == nil || result.Success {
+		return nil
+	}
+	return &FileOperationError{Operation: op, Message: result.FailReason}
+}
+
+// logReconcile 记录挂载点调整结果
+func logReconcile(result ReconcileResult) {
+	if result.Error != "" {
+		logger.Error("调整挂载点失败", zap.String("instance", result.Instance), zap.String("error", result.Error))
+		return
+	}
+	for _, a := range result.Actions {
+		if a.Error != "" {
+			logger.Error("调整挂载点失败",
+				zap.String("instance", result.Instance),
+				zap.String("mount_point", a.MountPoint),
+				zap.String("action", a.Action),
+				zap.String("error", a.Error),
+			)
+			continue
+		}
+		logger.Info("已调整挂载点",
+			zap.String("instance", result.Instance),
+			zap.String("mount_point", a.MountPoint),
+			zap.String("action", a.Action),
+		)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+// 分页默认值
+const (
+	defaultPageSize = 20
+	maxPageSize     = 500
+)
+
+// PageRequest 分页请求
+type PageRequest struct {
+	Page     int `form:"page" json:"page"`
+	PageSize int `form:"page_size" json:"page_size"`
+}
+
+// Normalize 修正分页参数
+func (p *PageRequest) Normalize() {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.PageSize < 1 {
+		p.PageSize = defaultPageSize
+	}
+	if p.PageSize > maxPageSize {
+		p.PageSize = maxPageSize
+	}
+}
+
+// Offset 计算偏移量
+func (p *PageRequest) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// paginate 对内存中的列表分页
+func paginate[T any](items []T, page *PageRequest) []T {
+	page.Normalize()
+
+	start := page.Offset()
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + page.PageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/notify"
+	"cinexus/pkg/pb"
+)
+
+// QuotaService 云盘空间监控服务
+type QuotaService struct{}
+
+// QuotaStatus 云盘当前空间及预测
+type QuotaStatus struct {
+	Instance     string    `json:"instance"`
+	CloudRoot    string    `json:"cloud_root"`
+	TotalSpace   int64     `json:"total_space"`
+	UsedSpace    int64     `json:"used_space"`
+	FreeSpace    int64     `json:"free_space"`
+	FreePercent  float64   `json:"free_percent"`
+	GrowthPerDay int64     `json:"growth_per_day"`      // 最近的日均增长（字节），可能为负
+	DaysLeft     *float64  `json:"days_left,omitempty"` // 按增长速度预计的剩余天数，不增长时为空
+	Alerts       []string  `json:"alerts"`
+	SampledAt    time.Time `json:"sampled_at"`
+
+	alertKinds []string // 告警类型，内容中的数值每次采样都会变化，只按类型判断是否需要重新通知
+}
+
+// SpaceHistoryQuery 空间历史查询参数
+type SpaceHistoryQuery struct {
+	Instance string     `form:"instance"`
+	Path     string     `form:"path" binding:"required"`
+	Start    *time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	End      *time.Time `form:"end" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// 单次返回的最多采样点数，超出时均匀抽样
+const maxSpacePoints = 2000
+
+const gb = 1 << 30
+
+// 已发送告警的云盘，恢复后再次告警
+var (
+	quotaAlertsMu sync.Mutex
+	quotaAlerts   = make(map[string]string)
+)
+
+// Status 获取所有云盘最近一次采样的空间及告警
+func (s *QuotaService) Status(ctx context.Context) ([]QuotaStatus, error) {
+	var ids []uint
+	err := database.Ctx(ctx).Model(&model.SpaceSample{}).
+		Select("MAX(id)").Group("instance, cloud_root").Pluck("MAX(id)", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []model.SpaceSample
+	if len(ids) > 0 {
+		if err := database.Ctx(ctx).Where("id IN ?", ids).Order("instance, cloud_root").Find(&samples).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]QuotaStatus, 0, len(samples))
+	for i := range samples {
+		st, err := evaluateQuota(&samples[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *st)
+	}
+	return result, nil
+}
+
+// History 获取云盘的空间采样，默认最近7天
+func (s *QuotaService) History(ctx context.Context, query *SpaceHistoryQuery) ([]model.SpaceSample, error) {
+	client, err := cd2.Get(query.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now().AddDate(0, 0, -7)
+	if query.Start != nil {
+		start = *query.Start
+	}
+
+	db := database.Ctx(ctx).Where("instance = ? AND cloud_root = ? AND created_at >= ?", client.Name, cleanPath(query.Path), start)
+	if query.End != nil {
+		db = db.Where("created_at < ?", *query.End)
+	}
+
+	var samples []model.SpaceSample
+	if err := db.Order("created_at ASC").Find(&samples).Error; err != nil {
+		return nil, err
+	}
+
+	if len(samples) <= maxSpacePoints {
+		return samples, nil
+	}
+	step := float64(len(samples)) / maxSpacePoints
+	sampled := make([]model.SpaceSample, 0, maxSpacePoints)
+	for i := 0; i < maxSpacePoints; i++ {
+		sampled = append(sampled, samples[int(float64(i)*step)])
+	}
+	return sampled, nil
+}
+
+// Collect 立即采集空间，不指定实例时采集所有实例
+func (s *QuotaService) Collect(ctx context.Context, instance string) ([]QuotaStatus, error) {
+	clients, err := transferClients(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]QuotaStatus, 0)
+	for _, client := range clients {
+		samples, err := collectSpace(ctx, client)
+		if err != nil && instance != "" {
+			return nil, err
+		}
+		if err != nil {
+			logger.Ctx(ctx).Warn("采集云盘空间失败", zap.Error(err))
+			continue
+		}
+		for i := range samples {
+			st, err := evaluateQuota(&samples[i])
+			if err != nil {
+				return nil, err
+			}
+			alertQuota(st)
+			result = append(result, *st)
+		}
+	}
+	return result, nil
+}
+
+// StartQuotaMonitor 定期采集所有云盘的空间，空间不足或预计即将用尽时发送通知
+func StartQuotaMonitor(ctx context.Context) {
+	interval := time.Duration(config.Get().Quota.Interval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	s := &QuotaService{}
+	go func() {
+		for {
+			heartbeat(ctx, "quota_monitor", interval)
+			start := time.Now()
+			result := "completed"
+			if _, err := s.Collect(ctx, ""); err != nil && ctx.Err() == nil {
+				logger.Ctx(ctx).Warn("采集云盘空间失败", zap.Error(err))
+				result = "failed"
+			}
+			metrics.ObserveJob("quota_collect", result, start)
+			pruneSpaceSamples()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// collectSpace 采集实例下每个云盘根目录的空间并保存
+func collectSpace(ctx context.Context, client *cd2.Client) ([]model.SpaceSample, error) {
+	roots, err := listSubFiles(ctx, client, "/", false)
+	if err != nil {
+		return nil, fmt.Errorf("实例 %s: %w", client.Name, err)
+	}
+
+	now := time.Now()
+	var samples []model.SpaceSample
+	for _, root := range roots {
+		if !root.IsDir {
+			continue
+		}
+
+		info, err := client.GetSpaceInfo(ctx, &pb.FileRequest{Path: root.Path})
+		if err != nil {
+			logger.Ctx(ctx).Warn("获取云盘空间失败", zap.String("instance", client.Name), zap.String("path", root.Path), zap.Error(err))
+			continue
+		}
+		// 不支持查询空间的云盘（如WebDAV）总空间为0
+		if info.TotalSpace <= 0 {
+			continue
+		}
+
+		samples = append(samples, model.SpaceSample{
+			Instance:   client.Name,
+			CloudRoot:  root.Path,
+			TotalSpace: info.TotalSpace,
+			UsedSpace:  info.UsedSpace,
+			FreeSpace:  info.FreeSpace,
+			CreatedAt:  now,
+		})
+	}
+
+	if len(samples) > 0 {
+		if err := database.Ctx(ctx).Create(&samples).Error; err != nil {
+			return nil, err
+		}
+	}
+	return samples, nil
+}
+
+// evaluateQuota 根据最近的采样计算增长速度、剩余天数和告警
+func evaluateQuota(sample *model.SpaceSample) (*QuotaStatus, error) {
+	cfg := config.Get().Quota
+	st := &QuotaStatus{
+		Instance:   sample.Instance,
+		CloudRoot:  sample.CloudRoot,
+		TotalSpace: sample.TotalSpace,
+		UsedSpace:  sample.UsedSpace,
+		FreeSpace:  sample.FreeSpace,
+		Alerts:     []string{},
+		SampledAt:  sample.CreatedAt,
+	}
+	if sample.TotalSpace > 0 {
+		st.FreePercent = math.Round(float64(sample.FreeSpace)*10000/float64(sample.TotalSpace)) / 100
+	}
+
+	window := cfg.ForecastWindow
+	if window <= 0 {
+		window = 7
+	}
+	var history []model.SpaceSample
+	err := database.DB.Where("instance = ? AND cloud_root = ? AND created_at >= ? AND created_at <= ?",
+		sample.Instance, sample.CloudRoot, sample.CreatedAt.AddDate(0, 0, -window), sample.CreatedAt).
+		Order("created_at ASC").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if rate, ok := growthPerDay(history); ok {
+		st.GrowthPerDay = int64(rate)
+		if rate > 0 {
+			days := math.Round(float64(sample.FreeSpace)/rate*10) / 10
+			st.DaysLeft = &days
+		}
+	}
+
+	if cfg.MinFreePercent > 0 && st.FreePercent < cfg.MinFreePercent {
+		st.Alerts = append(st.Alerts, fmt.Sprintf("剩余空间 %.2f%% 低于 %.2f%%", st.FreePercent, cfg.MinFreePercent))
+		st.alertKinds = append(st.alertKinds, "percent")
+	}
+	if cfg.MinFreeGB > 0 && float64(sample.FreeSpace) < cfg.MinFreeGB*gb {
+		st.Alerts = append(st.Alerts, fmt.Sprintf("剩余空间 %.2fGB 低于 %.2fGB", float64(sample.FreeSpace)/gb, cfg.MinFreeGB))
+		st.alertKinds = append(st.alertKinds, "free")
+	}
+	if cfg.ForecastDays > 0 && st.DaysLeft != nil && *st.DaysLeft < float64(cfg.ForecastDays) {
+		st.Alerts = append(st.Alerts, fmt.Sprintf("按每天增长 %.2fGB 预计 %.1f 天内用尽", float64(st.GrowthPerDay)/gb, *st.DaysLeft))
+		st.alertKinds = append(st.alertKinds, "forecast")
+	}
+
+	return st, nil
+}
+
+// growthPerDay 用最小二乘法计算已用空间的日均增长，采样跨度不足1小时时不计算
+func growthPerDay(samples []model.SpaceSample) (float64, bool) {
+	if len(samples) < 2 || samples[len(samples)-1].CreatedAt.Sub(samples[0].CreatedAt) < time.Hour {
+		return 0, false
+	}
+
+	base := samples[0].CreatedAt
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.CreatedAt.Sub(base).Hours() / 24
+		y := float64(s.UsedSpace)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denom, true
+}
+
+// alertQuota 云盘进入告警状态或告警类型变化时发送通知，恢复时再通知一次
+func alertQuota(st *QuotaStatus) {
+	key := st.Instance + ":" + st.CloudRoot
+	current := strings.Join(st.alertKinds, ",")
+
+	quotaAlertsMu.Lock()
+	last := quotaAlerts[key]
+	if current == "" {
+		delete(quotaAlerts, key)
+	} else {
+		quotaAlerts[key] = current
+	}
+	quotaAlertsMu.Unlock()
+
+	switch {
+	case current == last:
+	case current == "":
+		notify.Send(notify.LevelInfo, "云盘空间已恢复", fmt.Sprintf("%s（%s）剩余空间 %.2fGB",
+			st.CloudRoot, st.Instance, float64(st.FreeSpace)/gb))
+	default:
+		notify.Send(notify.LevelWarn, "云盘空间不足", fmt.Sprintf("%s（%s）%s", st.CloudRoot, st.Instance, strings.Join(st.Alerts, "；")))
+	}
+}
+
+// pruneSpaceSamples 删除过期的空间采样
+func pruneSpaceSamples() {
+	days := config.Get().Quota.HistoryDays
+	if days <= 0 {
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	if err := database.DB.Where("created_at < ?", before).Delete(&model.SpaceSample{}).Error; err != nil {
+		logger.Error("清理空间采样失败", zap.Error(err))
+	}
+}
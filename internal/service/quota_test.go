@@ -0,0 +1,74 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"cinexus/internal/model"
+)
+
+func TestGrowthPerDay(t *testing.T) {
+	const gb = 1 << 30
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// samples 按 [距离 base 的小时数, 已用空间] 生成采样
+	samples := func(points ...[2]float64) []model.SpaceSample {
+		list := make([]model.SpaceSample, len(points))
+		for i, p := range points {
+			list[i] = model.SpaceSample{
+				CreatedAt: base.Add(time.Duration(p[0] * float64(time.Hour))),
+				UsedSpace: int64(p[1]),
+			}
+		}
+		return list
+	}
+
+	tests := []struct {
+		name    string
+		samples []model.SpaceSample
+		want    float64
+		ok      bool
+	}{
+		{name: "没有采样", samples: nil},
+		{name: "只有一个采样", samples: samples([2]float64{0, 100 * gb})},
+		{name: "跨度不足1小时", samples: samples([2]float64{0, 100 * gb}, [2]float64{0.5, 101 * gb})},
+		{
+			name:    "匀速增长",
+			samples: samples([2]float64{0, 100 * gb}, [2]float64{24, 110 * gb}, [2]float64{48, 120 * gb}, [2]float64{72, 130 * gb}),
+			want:    10 * gb,
+			ok:      true,
+		},
+		{
+			name:    "按小时采样",
+			samples: samples([2]float64{0, 100 * gb}, [2]float64{1, 100*gb + gb/24}, [2]float64{2, 100*gb + 2*gb/24}),
+			want:    gb,
+			ok:      true,
+		},
+		{
+			name:    "空间减少",
+			samples: samples([2]float64{0, 200 * gb}, [2]float64{24, 195 * gb}, [2]float64{48, 190 * gb}),
+			want:    -5 * gb,
+			ok:      true,
+		},
+		{
+			name:    "有波动时取拟合斜率",
+			samples: samples([2]float64{0, 100 * gb}, [2]float64{24, 112 * gb}, [2]float64{48, 118 * gb}, [2]float64{72, 130 * gb}),
+			want:    9.6 * gb,
+			ok:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := growthPerDay(tt.samples)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			// 采样值取整，允许每天1KB的误差
+			if math.Abs(got-tt.want) > 1024 {
+				t.Errorf("growthPerDay = %.0f, want %.0f", got, tt.want)
+			}
+		})
+	}
+}
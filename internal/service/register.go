@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cinexus/config"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/mail"
+)
+
+// 注册模式
+const (
+	RegisterModeOpen   = "open"
+	RegisterModeInvite = "invite"
+	RegisterModeClosed = "closed"
+)
+
+// 同一账号重新发送验证邮件的最小间隔
+const resendInterval = time.Minute
+
+// ErrVerificationMailFailed 注册成功但验证邮件发送失败
+var ErrVerificationMailFailed = errors.New("注册成功，但验证邮件发送失败，请稍后重新发送验证邮件")
+
+// RegisterSettings 注册设置
+type RegisterSettings struct {
+	Mode              string `json:"mode" binding:"required,oneof=open invite closed"`
+	EmailVerification bool   `json:"email_verification"`
+}
+
+// RegisterService 注册管理服务
+type RegisterService struct{}
+
+// CreateInviteCodeRequest 创建邀请码请求
+type CreateInviteCodeRequest struct {
+	Code      string     `json:"code" binding:"omitempty,min=6,max=64"`
+	MaxUses   int        `json:"max_uses" binding:"min=0"`
+	Role      string     `json:"role" binding:"omitempty,oneof=admin editor user"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Remark    string     `json:"remark" binding:"max=255"`
+}
+
+// ResendVerificationRequest 重新发送验证邮件请求
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// GetSettings 获取当前生效的注册设置
+func (s *RegisterService) GetSettings() RegisterSettings {
+	conf := config.Get().Register
+
+	mode := conf.Mode
+	if mode == "" {
+		mode = RegisterModeOpen
+	}
+	return RegisterSettings{
+		Mode:              mode,
+		EmailVerification: conf.EmailVerification,
+	}
+}
+
+// UpdateSettings 更新注册设置，保存为数据库中的配置项，重启后仍然有效
+func (s *RegisterService) UpdateSettings(ctx context.Context, req *RegisterSettings) error {
+	if req.EmailVerification && config.Get().SMTP.Host == "" {
+		return errors.New("未配置邮件服务，无法开启邮箱验证")
+	}
+
+	settingService := SettingService{}
+	_, err := settingService.set(ctx, map[string]any{
+		"register.mode":               req.Mode,
+		"register.email_verification": req.EmailVerification,
+	})
+	return err
+}
+
+// ListInviteCodes 获取邀请码列表
+func (s *RegisterService) ListInviteCodes(ctx context.Context, page *PageRequest) ([]model.InviteCode, int64, error) {
+	var codes []model.InviteCode
+	var total int64
+
+	page.Normalize()
+	if err := database.Ctx(ctx).Model(&model.InviteCode{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := database.Ctx(ctx).Order("id DESC").Offset(page.Offset()).Limit(page.PageSize).Find(&codes).Error
+	return codes, total, err
+}
+
+// CreateInviteCode 创建邀请码
+func (s *RegisterService) CreateInviteCode(ctx context.Context, creatorID uint, req *CreateInviteCodeRequest) (*model.InviteCode, error) {
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		var err error
+		if code, err = randomToken(8); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("过期时间不能早于当前时间")
+	}
+
+	var count int64
+	database.Ctx(ctx).Model(&model.InviteCode{}).Where("code = ?", code).Count(&count)
+	if count > 0 {
+		return nil, errors.New("邀请码已存在")
+	}
+
+	role := req.Role
+	if role == "" {
+		role = model.RoleUser
+	}
+
+	invite := model.InviteCode{
+		Code:      code,
+		MaxUses:   req.MaxUses,
+		Role:      role,
+		ExpiresAt: req.ExpiresAt,
+		Remark:    req.Remark,
+		CreatedBy: creatorID,
+	}
+	if err := database.Ctx(ctx).Create(&invite).Error; err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// DeleteInviteCode 删除邀请码
+func (s *RegisterService) DeleteInviteCode(ctx context.Context, id uint) error {
+	result := database.Ctx(ctx).Delete(&model.InviteCode{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("邀请码不存在")
+	}
+	return nil
+}
+
+// VerifyEmail 校验邮箱验证令牌并激活用户
+func (s *RegisterService) VerifyEmail(ctx context.Context, token string) error {
+	return database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		var verification model.EmailVerification
+		err := tx.Where("token = ?", token).First(&verification).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("验证链接无效")
+			}
+			return err
+		}
+
+		if verification.UsedAt != nil {
+			return errors.New("验证链接已使用")
+		}
+		if time.Now().After(verification.ExpiresAt) {
+			return errors.New("验证链接已过期")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&verification).Update("used_at", &now).Error; err != nil {
+			return err
+		}
+
+		// 只激活待验证的用户，避免重新启用被管理员禁用的账号
+		return tx.Model(&model.User{}).
+			Where("id = ? AND status = ?", verification.UserID, model.UserStatusPending).
+			Update("status", model.UserStatusActive).Error
+	})
+}
+
+// ResendVerification 重新发送验证邮件
+// 邮箱未注册或无需验证时同样返回成功，避免通过该接口探测账号；同一账号 resendInterval 内只发送一次
+func (s *RegisterService) ResendVerification(ctx context.Context, req *ResendVerificationRequest) error {
+	var user model.User
+	err := database.Ctx(ctx).Where("email = ?", req.Email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Status != model.UserStatusPending {
+		return nil
+	}
+
+	var verify *verificationMail
+	err = database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		var recent int64
+		if err := tx.Model(&model.EmailVerification{}).
+			Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-resendInterval)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return nil
+		}
+
+		// 作废之前未使用的验证链接
+		now := time.Now()
+		if err := tx.Model(&model.EmailVerification{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		var err error
+		verify, err = createVerification(tx, &user)
+		return err
+	})
+	if err != nil || verify == nil {
+		return err
+	}
+
+	// 在后台发送，响应时间和结果不因账号是否存在而不同
+	go func() {
+		if err := verify.send(); err != nil {
+			logger.Ctx(ctx).Warn("发送验证邮件失败", zap.Uint("user_id", user.ID), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// consumeInviteCode 在事务中校验并占用一次邀请码，返回邀请码对应的角色
+func consumeInviteCode(tx *gorm.DB, code string) (string, error) {
+	if code == "" {
+		return "", errors.New("需要邀请码才能注册")
+	}
+
+	var invite model.InviteCode
+	err := tx.Where("code = ?", code).First(&invite).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("邀请码无效")
+		}
+		return "", err
+	}
+
+	if !invite.Usable() {
+		return "", errors.New("邀请码已失效")
+	}
+
+	// 使用条件更新防止并发注册超出使用次数
+	result := tx.Model(&model.InviteCode{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", invite.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", errors.New("邀请码已失效")
+	}
+
+	return invite.Role, nil
+}
+
+// verificationMail 待发送的验证邮件，在保存验证令牌的事务提交后发送
+type verificationMail struct {
+	to       string
+	username string
+	link     string
+	expire   int
+}
+
+// createVerification 在事务中生成并保存验证令牌，返回需要发送的验证邮件
+func createVerification(tx *gorm.DB, user *model.User) (*verificationMail, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	expire := config.Get().Register.VerifyExpire
+	if expire <= 0 {
+		expire = 24
+	}
+
+	verification := model.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: time.Now().Add(time.Duration(expire) * time.Hour),
+	}
+	if err := tx.Create(&verification).Error; err != nil {
+		return nil, err
+	}
+
+	link := config.Get().Register.VerifyURL
+	if strings.Contains(link, "?") {
+		link += "&token=" + url.QueryEscape(token)
+	} else {
+		link += "?token=" + url.QueryEscape(token)
+	}
+	return &verificationMail{to: user.Email, username: user.Username, link: link, expire: expire}, nil
+}
+
+// send 发送验证邮件
+func (m *verificationMail) send() error {
+	body := fmt.Sprintf("%s，您好：\n\n请点击以下链接完成邮箱验证（%d 小时内有效）：\n%s\n\n如果这不是您本人的操作，请忽略此邮件。",
+		m.username, m.expire, m.link)
+	if err := mail.Send([]string{m.to}, "Cinexus 邮箱验证", body); err != nil {
+		return fmt.Errorf("发送验证邮件失败: %w", err)
+	}
+	return nil
+}
+
+// randomToken 生成指定字节长度的随机十六进制字符串
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+)
+
+// smtpMessage SMTP服务收到的邮件
+type smtpMessage struct {
+	to   []string
+	data string
+}
+
+// smtpStub 只实现发送邮件所需命令的本地SMTP服务
+type smtpStub struct {
+	ln       net.Listener
+	messages chan smtpMessage
+
+	mu     sync.Mutex
+	reject bool   // 收件人返回 550
+	onData func() // 收到邮件内容后、回复之前调用
+}
+
+func newSMTPStub() (*smtpStub, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &smtpStub{ln: ln, messages: make(chan smtpMessage, 10)}
+	go s.serve()
+	return s, nil
+}
+
+func (s *smtpStub) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) Close() error {
+	return s.ln.Close()
+}
+
+// set 设置下一次会话的行为并清空之前收到的邮件
+func (s *smtpStub) set(reject bool, onData func()) {
+	s.mu.Lock()
+	s.reject, s.onData = reject, onData
+	s.mu.Unlock()
+	for {
+		select {
+		case <-s.messages:
+		default:
+			return
+		}
+	}
+}
+
+// wait 等待收到一封邮件
+func (s *smtpStub) wait(t *testing.T) smtpMessage {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到邮件")
+		return smtpMessage{}
+	}
+}
+
+// expectNone 确认一段时间内没有收到邮件
+func (s *smtpStub) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		t.Fatalf("不应发送邮件，收到发往 %v 的邮件", msg.to)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	tc := textproto.NewConn(conn)
+	defer tc.Close()
+
+	s.mu.Lock()
+	reject, onData := s.reject, s.onData
+	s.mu.Unlock()
+
+	var to []string
+	tc.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tc.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"), cmd == "RSET", cmd == "NOOP":
+			tc.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if reject {
+				tc.PrintfLine("550 mailbox unavailable")
+				continue
+			}
+			to = append(to, strings.Trim(line[len("RCPT TO:"):], " <>"))
+			tc.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tc.PrintfLine("354 end with <CRLF>.<CRLF>")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			if onData != nil {
+				onData()
+			}
+			s.messages <- smtpMessage{to: to, data: string(data)}
+			tc.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// userExists 在另一个连接中查询用户是否已提交，数据库被锁定时视为不存在
+func userExists(username string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.User{}).Where("username = ?", username).Count(&count).Error
+	return err == nil && count == 1
+}
+
+func TestRegisterSendsVerificationAfterCommit(t *testing.T) {
+	committed := make(chan bool, 1)
+	testSMTP.set(false, func() { committed <- userExists("mail_commit") })
+	defer testSMTP.set(false, nil)
+
+	s := &UserService{}
+	user, err := s.Register(context.Background(), &RegisterRequest{
+		Username: "mail_commit",
+		Password: "secret123",
+		Email:    "commit@example.com",
+	})
+	if err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	if user.Status != model.UserStatusPending {
+		t.Errorf("status = %d，应为待验证", user.Status)
+	}
+
+	msg := testSMTP.wait(t)
+	if !<-committed {
+		t.Error("验证邮件在用户保存之前发送")
+	}
+	if len(msg.to) != 1 || msg.to[0] != "commit@example.com" {
+		t.Errorf("收件人 = %v", msg.to)
+	}
+
+	var verification model.EmailVerification
+	if err := database.DB.Where("user_id = ?", user.ID).First(&verification).Error; err != nil {
+		t.Fatalf("查询验证令牌失败: %v", err)
+	}
+	if !strings.Contains(msg.data, "http://localhost/verify?token="+verification.Token) {
+		t.Errorf("邮件中没有验证链接:\n%s", msg.data)
+	}
+}
+
+func TestRegisterKeepsPendingUserWhenMailFails(t *testing.T) {
+	testSMTP.set(true, nil)
+	defer testSMTP.set(false, nil)
+
+	s := &UserService{}
+	user, err := s.Register(context.Background(), &RegisterRequest{
+		Username: "mail_failed",
+		Password: "secret123",
+		Email:    "failed@example.com",
+	})
+	if !errors.Is(err, ErrVerificationMailFailed) {
+		t.Fatalf("err = %v，应为 ErrVerificationMailFailed", err)
+	}
+	if user == nil || user.ID == 0 {
+		t.Fatal("邮件发送失败时应保留已创建的用户")
+	}
+
+	var saved model.User
+	if err := database.DB.First(&saved, user.ID).Error; err != nil {
+		t.Fatalf("查询用户失败: %v", err)
+	}
+	if saved.Status != model.UserStatusPending {
+		t.Errorf("status = %d，应为待验证", saved.Status)
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	testSMTP.set(false, nil)
+
+	active := model.User{Username: "resend_active", Password: "secret123", Email: "active@example.com", Status: model.UserStatusActive}
+	pending := model.User{Username: "resend_pending", Password: "secret123", Email: "pending@example.com", Status: model.UserStatusPending}
+	for _, u := range []*model.User{&active, &pending} {
+		if err := database.DB.Create(u).Error; err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+
+	s := &RegisterService{}
+	ctx := context.Background()
+
+	// 未注册和无需验证的邮箱与待验证的邮箱返回相同的结果，但不发送邮件
+	for _, email := range []string{"nobody@example.com", "active@example.com"} {
+		if err := s.ResendVerification(ctx, &ResendVerificationRequest{Email: email}); err != nil {
+			t.Errorf("%s: err = %v，应返回成功", email, err)
+		}
+		testSMTP.expectNone(t)
+	}
+
+	if err := s.ResendVerification(ctx, &ResendVerificationRequest{Email: "pending@example.com"}); err != nil {
+		t.Fatalf("重新发送失败: %v", err)
+	}
+	if msg := testSMTP.wait(t); len(msg.to) != 1 || msg.to[0] != "pending@example.com" {
+		t.Errorf("收件人 = %v", msg.to)
+	}
+
+	// 间隔内再次请求不发送
+	if err := s.ResendVerification(ctx, &ResendVerificationRequest{Email: "pending@example.com"}); err != nil {
+		t.Errorf("err = %v，应返回成功", err)
+	}
+	testSMTP.expectNone(t)
+
+	var valid int64
+	database.DB.Model(&model.EmailVerification{}).
+		Where("user_id = ? AND used_at IS NULL AND expires_at > ?", pending.ID, time.Now()).
+		Count(&valid)
+	if valid != 1 {
+		t.Errorf("有效的验证令牌数 = %d，应为 1", valid)
+	}
+}
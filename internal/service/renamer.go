@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/parser"
+	"cinexus/pkg/pb"
+)
+
+// 每批提交给CD2的重命名数量
+const (
+	defaultRenameChunk = 50
+	maxRenameChunk     = 500
+)
+
+// templateVarRe 匹配模板变量，如 {title}、{season:02}、{1}
+var templateVarRe = regexp.MustCompile(`\{(\w+)(?::(\d+))?\}`)
+
+// RenamerService 批量重命名服务
+type RenamerService struct{}
+
+// RenamePreviewRequest 批量重命名预览请求
+type RenamePreviewRequest struct {
+	Instance      string `json:"instance"`
+	Path          string `json:"path" binding:"required"`
+	Pattern       string `json:"pattern"` // 正则表达式，只处理匹配的文件，捕获组可在模板中以 {1}、{name} 引用
+	Template      string `json:"template" binding:"required"`
+	DefaultSeason int    `json:"default_season" binding:"min=0"` // 文件名中没有季信息时使用的季号
+	EpisodeOffset int    `json:"episode_offset"`                 // 集数偏移，用于跨季连续编号的发布
+	IncludeDirs   bool   `json:"include_dirs"`
+}
+
+// RenameApplyRequest 批量重命名执行请求
+type RenameApplyRequest struct {
+	RenamePreviewRequest
+	ChunkSize int `json:"chunk_size" binding:"min=0"`
+}
+
+// RenamePreviewItem 预览中的单个文件
+type RenamePreviewItem struct {
+	Path    string            `json:"path"`
+	OldName string            `json:"old_name"`
+	NewName string            `json:"new_name,omitempty"`
+	Vars    map[string]string `json:"vars,omitempty"`
+	Skipped string            `json:"skipped,omitempty"` // 跳过原因
+}
+
+// RenamePreview 批量重命名预览结果
+type RenamePreview struct {
+	Items   []RenamePreviewItem `json:"items"`
+	Renamed int                 `json:"renamed"` // 将被重命名的文件数量
+}
+
+// RenameBatchQuery 批量重命名记录查询条件
+type RenameBatchQuery struct {
+	PageRequest
+	Instance string `form:"instance"`
+}
+
+// Preview 生成批量重命名的新旧名称对照
+func (s *RenamerService) Preview(ctx context.Context, req *RenamePreviewRequest) (*RenamePreview, error) {
+	var pattern *regexp.Regexp
+	if req.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(req.Pattern); err != nil {
+			return nil, fmt.Errorf("正则表达式错误: %w", err)
+		}
+	}
+
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cleanPath(req.Path)
+	files, err := listSubFiles(ctx, client, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	sortFiles(files, "name", "asc")
+
+	return planRename(files, pattern, req), nil
+}
+
+// planRename 按模板计算目录中每个文件的新名称，跳过无法渲染、未变化和冲突的文件
+func planRename(files []FileInfo, pattern *regexp.Regexp, req *RenamePreviewRequest) *RenamePreview {
+	preview := &RenamePreview{Items: make([]RenamePreviewItem, 0, len(files))}
+	existing := make(map[string]bool, len(files))
+	for _, f := range files {
+		existing[f.Name] = true
+	}
+
+	targets := make(map[string]int)
+	for _, f := range files {
+		item := RenamePreviewItem{Path: f.Path, OldName: f.Name}
+
+		switch {
+		case f.IsDir && !req.IncludeDirs:
+			item.Skipped = "目录"
+		case pattern != nil && !pattern.MatchString(f.Name):
+			item.Skipped = "不匹配正则表达式"
+		default:
+			item.Vars = renameVars(f.Name, pattern, req)
+			newName, err := renderTemplate(req.Template, item.Vars)
+			if err != nil {
+				item.Skipped = err.Error()
+			} else if err := validateName(newName); err != nil {
+				item.Skipped = err.Error()
+			} else if newName == f.Name {
+				item.Skipped = "名称未变化"
+			} else {
+				item.NewName = newName
+			}
+		}
+
+		if item.NewName != "" {
+			targets[item.NewName]++
+		}
+		preview.Items = append(preview.Items, item)
+	}
+
+	// 新名称之间重复、或与目录中已有文件重名时跳过，不支持文件之间互换名称
+	for i := range preview.Items {
+		item := &preview.Items[i]
+		if item.NewName == "" {
+			continue
+		}
+		switch {
+		case targets[item.NewName] > 1:
+			item.Skipped = "与其他文件的新名称重复"
+		case existing[item.NewName]:
+			item.Skipped = "目标名称已存在"
+		default:
+			preview.Renamed++
+			continue
+		}
+		item.NewName = ""
+	}
+
+	return preview
+}
+
+// Apply 按预览结果分批执行重命名，并保存撤销记录
+func (s *RenamerService) Apply(ctx context.Context, req *RenameApplyRequest) (*model.RenameBatch, error) {
+	preview, err := s.Preview(ctx, &req.RenamePreviewRequest)
+	if err != nil {
+		return nil, err
+	}
+	if preview.Renamed == 0 {
+		return nil, errors.New("没有需要重命名的文件")
+	}
+
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := model.RenameBatch{
+		UserID:   actor.FromContext(ctx).UserID,
+		Instance: client.Name,
+		Path:     cleanPath(req.Path),
+		Pattern:  req.Pattern,
+		Template: req.Template,
+		Total:    preview.Renamed,
+	}
+	for _, item := range preview.Items {
+		if item.NewName == "" {
+			continue
+		}
+		batch.Items = append(batch.Items, model.RenameRecord{
+			OldPath: item.Path,
+			NewPath: path.Join(path.Dir(item.Path), item.NewName),
+		})
+	}
+
+	// 先保存记录再执行，保证已经改名的文件一定可以撤销
+	batch.Status = model.RenameStatusPending
+	if err := database.Ctx(ctx).Create(&batch).Error; err != nil {
+		return nil, err
+	}
+
+	renameInChunks(ctx, client, batch.Items, req.ChunkSize, false)
+	batch.Succeeded, batch.Status = summarizeRename(batch.Items)
+
+	err = database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range batch.Items {
+			if err := tx.Model(&batch.Items[i]).Select("status", "error").Updates(&batch.Items[i]).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&batch).Select("succeeded", "status").Updates(&batch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// Undo 撤销一次批量重命名，将成功的文件改回原名称
+func (s *RenamerService) Undo(ctx context.Context, id uint) (*model.RenameBatch, error) {
+	batch, err := s.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == model.RenameStatusUndone {
+		return nil, errors.New("该批次已撤销")
+	}
+
+	client, err := cd2.Get(batch.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []model.RenameRecord
+	for _, r := range batch.Items {
+		if r.Status == model.RenameStatusApplied {
+			applied = append(applied, r)
+		}
+	}
+	if len(applied) == 0 {
+		return nil, errors.New("该批次没有可撤销的文件")
+	}
+
+	renameInChunks(ctx, client, applied, 0, true)
+
+	err = database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		undone := 0
+		for _, r := range applied {
+			if r.Status == model.RenameStatusUndone {
+				undone++
+			}
+			if err := tx.Model(&r).Select("status", "error").Updates(&r).Error; err != nil {
+				return err
+			}
+		}
+
+		status := model.RenameStatusUndone
+		if undone < len(applied) {
+			status = model.RenameStatusPartial
+		}
+		return tx.Model(batch).Updates(map[string]interface{}{
+			"status":    status,
+			"succeeded": batch.Succeeded - undone,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetBatch(ctx, id)
+}
+
+// ListBatches 分页查询批量重命名记录
+func (s *RenamerService) ListBatches(ctx context.Context, query *RenameBatchQuery) ([]model.RenameBatch, int64, error) {
+	var batches []model.RenameBatch
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.RenameBatch{})
+	if query.Instance != "" {
+		db = db.Where("instance = ?", query.Instance)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&batches).Error
+	return batches, total, err
+}
+
+// GetBatch 获取批量重命名记录及明细
+func (s *RenamerService) GetBatch(ctx context.Context, id uint) (*model.RenameBatch, error) {
+	var batch model.RenameBatch
+	err := database.Ctx(ctx).Preload("Items").First(&batch, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("重命名记录不存在")
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// renameInChunks 分批调用CD2重命名，结果写回每条记录，reverse 为 true 时从新名称改回原名称
+func renameInChunks(ctx context.Context, client *cd2.Client, records []model.RenameRecord, chunkSize int, reverse bool) {
+	if chunkSize <= 0 {
+		chunkSize = defaultRenameChunk
+	}
+	if chunkSize > maxRenameChunk {
+		chunkSize = maxRenameChunk
+	}
+
+	for start := 0; start < len(records); start += chunkSize {
+		end := start + chunkSize
+		if end > len(records) {
+			end = len(records)
+		}
+		chunk := records[start:end]
+
+		renames := make([]*pb.RenameFileRequest, 0, len(chunk))
+		for _, r := range chunk {
+			from, to := r.OldPath, r.NewPath
+			if reverse {
+				from, to = to, from
+			}
+			renames = append(renames, &pb.RenameFileRequest{TheFilePath: from, NewName: path.Base(to)})
+		}
+
+		reply, err := client.RenameFiles(ctx, &pb.RenameFilesRequest{RenameFiles: renames})
+		if err == nil {
+			err = checkResult("重命名", reply)
+		}
+
+		for i := range chunk {
+			switch {
+			case err != nil && reverse:
+				chunk[i].Error = truncate(err.Error(), 512)
+			case err != nil:
+				chunk[i].Status = model.RenameStatusFailed
+				chunk[i].Error = truncate(err.Error(), 512)
+			case reverse:
+				chunk[i].Status = model.RenameStatusUndone
+				chunk[i].Error = ""
+			default:
+				chunk[i].Status = model.RenameStatusApplied
+			}
+		}
+	}
+}
+
+// summarizeRename 统计重命名结果
+func summarizeRename(records []model.RenameRecord) (int, string) {
+	succeeded := 0
+	for _, r := range records {
+		if r.Status == model.RenameStatusApplied {
+			succeeded++
+		}
+	}
+
+	switch succeeded {
+	case len(records):
+		return succeeded, model.RenameStatusApplied
+	case 0:
+		return succeeded, model.RenameStatusFailed
+	default:
+		return succeeded, model.RenameStatusPartial
+	}
+}
+
+// renameVars 收集模板变量：文件名解析结果和正则捕获组
+func renameVars(name string, pattern *regexp.Regexp, req *RenamePreviewRequest) map[string]string {
+	info := parser.Parse(name)
+
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	vars := map[string]string{
+		"original":   name,
+		"name":       strings.TrimSuffix(name, path.Ext(name)),
+		"ext":        ext,
+		"title":      info.Title,
+		"resolution": info.Resolution,
+		"group":      info.Group,
+	}
+
+	season := info.Season
+	if season == 0 {
+		season = req.DefaultSeason
+	}
+	if season > 0 {
+		vars["season"] = strconv.Itoa(season)
+	}
+	if info.Episode > 0 && info.Episode+req.EpisodeOffset > 0 {
+		vars["episode"] = strconv.Itoa(info.Episode + req.EpisodeOffset)
+	}
+	if info.Year > 0 {
+		vars["year"] = strconv.Itoa(info.Year)
+	}
+
+	if pattern != nil {
+		m := pattern.FindStringSubmatch(name)
+		for i, v := range m {
+			if i > 0 {
+				vars[strconv.Itoa(i)] = v
+			}
+		}
+		for i, n := range pattern.SubexpNames() {
+			if n != "" && i < len(m) {
+				vars[n] = m[i]
+			}
+		}
+	}
+
+	return vars
+}
+
+// renderTemplate 渲染模板，{var:02} 表示数字补零到2位，缺少变量时返回错误
+func renderTemplate(tmpl string, vars map[string]string) (string, error) {
+	var missing []string
+
+	result := templateVarRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		sub := templateVarRe.FindStringSubmatch(m)
+		value, ok := vars[sub[1]]
+		if !ok || value == "" {
+			missing = append(missing, sub[1])
+			return m
+		}
+
+		if sub[2] != "" {
+			width, _ := strconv.Atoi(sub[2])
+			if n, err := strconv.Atoi(value); err == nil {
+				return fmt.Sprintf("%0*d", width, n)
+			}
+		}
+		return value
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("无法获取变量: %s", strings.Join(missing, ", "))
+	}
+	return strings.TrimSpace(result), nil
+}
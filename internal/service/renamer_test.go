@@ -0,0 +1,167 @@
+package service
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]string{
+		"title":   "Breaking Bad",
+		"season":  "5",
+		"episode": "14",
+		"ext":     "mkv",
+		"group":   "",
+		"1":       "abc",
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr string
+	}{
+		{name: "补零", tmpl: "{title} S{season:02}E{episode:02}.{ext}", want: "Breaking Bad S05E14.mkv"},
+		{name: "数字超过宽度", tmpl: "E{episode:1}", want: "E14"},
+		{name: "非数字不补零", tmpl: "{1:03}", want: "abc"},
+		{name: "捕获组编号", tmpl: "{1}.{ext}", want: "abc.mkv"},
+		{name: "去除首尾空格", tmpl: " {title} ", want: "Breaking Bad"},
+		{name: "不是变量的花括号保持原样", tmpl: "{title} {a-b}", want: "Breaking Bad {a-b}"},
+		{name: "缺少变量", tmpl: "{title} ({year})", wantErr: "无法获取变量: year"},
+		{name: "空值视为缺少", tmpl: "[{group}] {title}", wantErr: "无法获取变量: group"},
+		{name: "列出所有缺少的变量", tmpl: "{year} {group} {title}", wantErr: "无法获取变量: year, group"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate(tt.tmpl, vars)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v，应为 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderTemplate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderTemplate(%q) = %q，应为 %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanRename(t *testing.T) {
+	// result 预览中单个文件的期望结果，NewName 为空时 Skipped 为跳过原因
+	type result struct {
+		NewName string
+		Skipped string
+	}
+
+	tests := []struct {
+		name    string
+		files   []FileInfo
+		req     RenamePreviewRequest
+		pattern string
+		want    map[string]result
+		renamed int
+	}{
+		{
+			name: "冲突检测",
+			files: []FileInfo{
+				{Name: "Extras", IsDir: true},
+				{Name: "Show.S01E01.mkv"},
+				{Name: "Show S01E02.mkv"},
+				{Name: "Show.S01E03.720p.mkv"},
+				{Name: "Show.S01E03.1080p.mkv"},
+				{Name: "Show.S01E04.mkv"},
+				{Name: "Show S01E04.mkv"},
+				{Name: "notes.txt"},
+			},
+			req: RenamePreviewRequest{Template: "{title} S{season:02}E{episode:02}.{ext}"},
+			want: map[string]result{
+				"Extras":                {Skipped: "目录"},
+				"Show.S01E01.mkv":       {NewName: "Show S01E01.mkv"},
+				"Show S01E02.mkv":       {Skipped: "名称未变化"},
+				"Show.S01E03.720p.mkv":  {Skipped: "与其他文件的新名称重复"},
+				"Show.S01E03.1080p.mkv": {Skipped: "与其他文件的新名称重复"},
+				"Show.S01E04.mkv":       {Skipped: "目标名称已存在"},
+				"Show S01E04.mkv":       {Skipped: "名称未变化"},
+				"notes.txt":             {Skipped: "无法获取变量: season, episode"},
+			},
+			renamed: 1,
+		},
+		{
+			name: "包含目录",
+			files: []FileInfo{
+				{Name: "Season 1", IsDir: true},
+			},
+			req:     RenamePreviewRequest{Template: "S{season:02}", IncludeDirs: true},
+			want:    map[string]result{"Season 1": {NewName: "S01"}},
+			renamed: 1,
+		},
+		{
+			name: "正则捕获组和默认季号",
+			files: []FileInfo{
+				{Name: "Ep3.mkv"},
+				{Name: "readme.md"},
+			},
+			pattern: `^Ep(\d+)\.(?P<suffix>\w+)$`,
+			req:     RenamePreviewRequest{Template: "Bar S{season:02}E{1:02}.{suffix}", DefaultSeason: 1},
+			want: map[string]result{
+				"Ep3.mkv":   {NewName: "Bar S01E03.mkv"},
+				"readme.md": {Skipped: "不匹配正则表达式"},
+			},
+			renamed: 1,
+		},
+		{
+			name: "集数偏移",
+			files: []FileInfo{
+				{Name: "Show.S02E01.mkv"},
+			},
+			req:     RenamePreviewRequest{Template: "{title} E{episode:02}.{ext}", EpisodeOffset: 12},
+			want:    map[string]result{"Show.S02E01.mkv": {NewName: "Show E13.mkv"}},
+			renamed: 1,
+		},
+		{
+			name: "新名称不合法",
+			files: []FileInfo{
+				{Name: "a.mkv"},
+			},
+			pattern: `^(a)\.mkv$`,
+			req:     RenamePreviewRequest{Template: "{1}/b.mkv"},
+			want:    map[string]result{"a.mkv": {Skipped: "文件名不能包含路径分隔符"}},
+			renamed: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pattern *regexp.Regexp
+			if tt.pattern != "" {
+				pattern = regexp.MustCompile(tt.pattern)
+			}
+			for i := range tt.files {
+				tt.files[i].Path = "/115/tv/" + tt.files[i].Name
+			}
+
+			preview := planRename(tt.files, pattern, &tt.req)
+			if len(preview.Items) != len(tt.files) {
+				t.Fatalf("预览数量 = %d，应为 %d", len(preview.Items), len(tt.files))
+			}
+			for _, item := range preview.Items {
+				want, ok := tt.want[item.OldName]
+				if !ok {
+					t.Errorf("多余的文件 %s", item.OldName)
+					continue
+				}
+				if item.NewName != want.NewName || item.Skipped != want.Skipped {
+					t.Errorf("%s: NewName = %q, Skipped = %q，应为 %q, %q", item.OldName, item.NewName, item.Skipped, want.NewName, want.Skipped)
+				}
+			}
+			if preview.Renamed != tt.renamed {
+				t.Errorf("Renamed = %d，应为 %d", preview.Renamed, tt.renamed)
+			}
+		})
+	}
+}
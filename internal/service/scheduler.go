@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"cinexus/config"
+	"cinexus/pkg/logger"
+)
+
+// loopTask 按配置运行的后台循环任务，对应的配置节变化时重新启动
+type loopTask struct {
+	name    string // 心跳名称
+	section string
+	start   func(ctx context.Context)
+	cancel  context.CancelFunc
+}
+
+var (
+	schedulerMu sync.Mutex
+	loopTasks   []*loopTask
+)
+
+// StartScheduler 启动挂载点健康检查、备份状态采集、空间采集和回收站清理，
+// 对应的配置修改后以新配置重新启动，关闭的任务停止运行
+func StartScheduler(ctx context.Context) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+
+	loopTasks = []*loopTask{
+		{name: "mount_watchdog", section: "watchdog", start: func(ctx context.Context) {
+			if config.Get().Watchdog.Enabled {
+				StartMountWatchdog(ctx)
+			}
+		}},
+		{name: "backup_monitor", section: "backup", start: StartBackupMonitor},
+		{name: "quota_monitor", section: "quota", start: StartQuotaMonitor},
+		{name: "trash_purger", section: "trash", start: StartTrashPurger},
+	}
+
+	for _, t := range loopTasks {
+		task := t
+		task.run(ctx)
+		config.OnChange(task.section, func(_, _ *config.Config) {
+			schedulerMu.Lock()
+			defer schedulerMu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+
+			// 先取消再清除，heartbeat 会跳过已取消的旧循环
+			task.cancel()
+			clearHeartbeat(task.name)
+			task.run(ctx)
+			logger.Ctx(ctx).Info("后台任务已按新配置重新启动", zap.String("task", task.name))
+		})
+	}
+}
+
+func (t *loopTask) run(ctx context.Context) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	t.cancel = cancel
+	t.start(taskCtx)
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cinexus/config"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/actor"
+	"cinexus/pkg/logger"
+)
+
+// 配置项类型
+const (
+	SettingTypeString  = "string"
+	SettingTypeInt     = "int"
+	SettingTypeFloat   = "float"
+	SettingTypeBool    = "bool"
+	SettingTypeStrings = "strings" // 字符串列表
+	SettingTypeCD2     = "cd2"     // CD2实例列表，字段与配置文件中的 [[cd2]] 相同
+)
+
+// SettingSchema 可以在管理界面修改的配置项
+type SettingSchema struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Options     []string `json:"options,omitempty"` // 可选值，为空表示不限制
+}
+
+// settingSchemas 可以保存在数据库中的配置项，只包含修改后无需重启即可生效的配置
+// 数据库中的值覆盖配置文件和环境变量，删除后恢复为配置文件和环境变量中的值
+var settingSchemas = []SettingSchema{
+	{Key: "cd2", Type: SettingTypeCD2, Description: "CloudDrive2 实例，第一个为默认实例"},
+	{Key: "notify.webhook", Type: SettingTypeString, Description: "通知 Webhook 地址"},
+	{Key: "notify.emails", Type: SettingTypeStrings, Description: "接收通知的邮箱"},
+	{Key: "smtp.host", Type: SettingTypeString, Description: "SMTP 服务器"},
+	{Key: "smtp.port", Type: SettingTypeInt, Description: "SMTP 端口"},
+	{Key: "smtp.username", Type: SettingTypeString, Description: "SMTP 用户名"},
+	{Key: "smtp.password", Type: SettingTypeString, Description: "SMTP 密码"},
+	{Key: "smtp.from", Type: SettingTypeString, Description: "发件人"},
+	{Key: "smtp.ssl", Type: SettingTypeBool, Description: "使用隐式TLS连接"},
+	{Key: "register.mode", Type: SettingTypeString, Description: "注册模式", Options: []string{RegisterModeOpen, RegisterModeInvite, RegisterModeClosed}},
+	{Key: "register.email_verification", Type: SettingTypeBool, Description: "注册后需要验证邮箱"},
+	{Key: "register.verify_url", Type: SettingTypeString, Description: "邮箱验证链接地址"},
+	{Key: "register.verify_expire", Type: SettingTypeInt, Description: "验证链接有效期（小时）"},
+	{Key: "log.level", Type: SettingTypeString, Description: "日志级别", Options: []string{"debug", "info", "warn", "error"}},
+	{Key: "watchdog.enabled", Type: SettingTypeBool, Description: "挂载点健康检查"},
+	{Key: "watchdog.interval", Type: SettingTypeInt, Description: "挂载点检查间隔（秒）"},
+	{Key: "watchdog.auto_remount", Type: SettingTypeBool, Description: "挂载失效时自动重新挂载"},
+	{Key: "watchdog.max_remount_attempts", Type: SettingTypeInt, Description: "连续重新挂载失败多少次后停止尝试，0 表示不限制"},
+	{Key: "backup.monitor_interval", Type: SettingTypeInt, Description: "采集CD2备份状态的间隔（秒），0 表示不采集"},
+	{Key: "quota.interval", Type: SettingTypeInt, Description: "云盘空间采集间隔（秒），0 表示不采集"},
+	{Key: "quota.min_free_percent", Type: SettingTypeFloat, Description: "剩余空间低于该百分比时告警"},
+	{Key: "quota.min_free_gb", Type: SettingTypeFloat, Description: "剩余空间低于该值（GB）时告警"},
+	{Key: "quota.forecast_days", Type: SettingTypeInt, Description: "预计该天数内用尽时告警"},
+	{Key: "trash.enabled", Type: SettingTypeBool, Description: "删除时移入 Cinexus 回收站"},
+	{Key: "trash.retention_days", Type: SettingTypeInt, Description: "回收站保留天数，0 表示不自动清理"},
+}
+
+// settingMu 保证同一时间只有一次修改，数据库和配置快照保持一致
+var settingMu sync.Mutex
+
+// SettingService 配置项服务
+type SettingService struct{}
+
+// SettingItem 配置项及其当前值，敏感字段已脱敏
+type SettingItem struct {
+	SettingSchema
+	Value      any        `json:"value"`      // 当前生效的值
+	Default    any        `json:"default"`    // 配置文件和环境变量中的值
+	Overridden bool       `json:"overridden"` // 是否使用数据库中的值
+	UpdatedBy  uint       `json:"updated_by,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateSettingsRequest 修改配置项请求，值为 null 表示删除数据库中的值，恢复为配置文件和环境变量中的值
+type UpdateSettingsRequest struct {
+	Values map[string]json.RawMessage `json:"values" binding:"required"`
+}
+
+// SettingHistoryQuery 配置项修改记录查询条件
+type SettingHistoryQuery struct {
+	PageRequest
+	Key string `form:"key"`
+}
+
+// LoadSettings 启动时将数据库中的配置项应用到配置快照，需在数据库初始化后调用
+// 无法识别或无法解析的配置项跳过，合并后的配置校验失败时只使用配置文件和环境变量
+func LoadSettings() error {
+	var rows []model.Setting
+	if err := database.DB.Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	values := make(map[string]any, len(rows))
+	for _, row := range rows {
+		if findSchema(row.Key) == nil {
+			logger.Warn("跳过不支持在线修改的配置项", zap.String("key", row.Key))
+			continue
+		}
+		value, err := parseSettingValue(row.Key, []byte(row.Value))
+		if err != nil {
+			logger.Warn("跳过无法解析的配置项", zap.String("key", row.Key), zap.Error(err))
+			continue
+		}
+		values[row.Key] = value
+	}
+
+	changed, err := config.SetOverrides(values)
+	if err != nil {
+		return fmt.Errorf("数据库中的配置项无效，继续使用配置文件: %w", err)
+	}
+	logger.Info("已应用数据库中的配置项", zap.Int("count", len(values)), zap.Strings("sections", changed))
+	return nil
+}
+
+// List 获取所有可以修改的配置项及其当前值
+func (s *SettingService) List(ctx context.Context) ([]SettingItem, error) {
+	base, err := config.Base()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []model.Setting
+	if err := database.Ctx(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	stored := make(map[string]model.Setting, len(rows))
+	for _, row := range rows {
+		stored[row.Key] = row
+	}
+
+	conf := config.Get()
+	items := make([]SettingItem, 0, len(settingSchemas))
+	for _, schema := range settingSchemas {
+		item := SettingItem{
+			SettingSchema: schema,
+			Value:         conf.Value(schema.Key, true),
+			Default:       base.Value(schema.Key, true),
+		}
+		if row, ok := stored[schema.Key]; ok {
+			updatedAt := row.UpdatedAt
+			item.Overridden = true
+			item.UpdatedBy = row.UpdatedBy
+			item.UpdatedAt = &updatedAt
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Update 修改配置项，所有配置项合并校验通过后一起生效，返回发生变化的配置节
+func (s *SettingService) Update(ctx context.Context, req *UpdateSettingsRequest) ([]string, error) {
+	values := make(map[string]any, len(req.Values))
+	for key, raw := range req.Values {
+		if findSchema(key) == nil {
+			return nil, fmt.Errorf("不支持在线修改的配置项: %s", key)
+		}
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			values[key] = nil
+			continue
+		}
+		value, err := parseSettingValue(key, raw)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = config.RestoreRedacted(key, value)
+	}
+	return s.set(ctx, values)
+}
+
+// set 保存配置项并更新配置快照，值为nil表示删除
+func (s *SettingService) set(ctx context.Context, values map[string]any) ([]string, error) {
+	for key, value := range values {
+		if value == nil {
+			continue
+		}
+		if err := checkSettingValue(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	settingMu.Lock()
+	defer settingMu.Unlock()
+
+	var rows []model.Setting
+	if err := database.Ctx(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	prev := make(map[string]any, len(rows))
+	stored := make(map[string]model.Setting, len(rows))
+	for _, row := range rows {
+		stored[row.Key] = row
+		if findSchema(row.Key) == nil {
+			continue
+		}
+		if value, err := parseSettingValue(row.Key, []byte(row.Value)); err == nil {
+			prev[row.Key] = value
+		}
+	}
+
+	next := make(map[string]any, len(prev)+len(values))
+	for key, value := range prev {
+		next[key] = value
+	}
+	encoded := make(map[string]*string, len(values))
+	for key, value := range values {
+		if value == nil {
+			delete(next, key)
+			encoded[key] = nil
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		str := string(data)
+		next[key] = value
+		encoded[key] = &str
+	}
+
+	changed, err := config.SetOverrides(next)
+	if err != nil {
+		return nil, err
+	}
+
+	operator := actor.FromContext(ctx)
+	err = database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		for key, value := range encoded {
+			var old *string
+			if row, ok := stored[key]; ok {
+				old = &row.Value
+			}
+			if (old == nil && value == nil) || (old != nil && value != nil && *old == *value) {
+				continue
+			}
+
+			if value == nil {
+				if err := tx.Where(&model.Setting{Key: key}).Delete(&model.Setting{}).Error; err != nil {
+					return err
+				}
+			} else if row, ok := stored[key]; ok {
+				if err := tx.Model(&row).Updates(map[string]any{"value": *value, "updated_by": operator.UserID}).Error; err != nil {
+					return err
+				}
+			} else {
+				if err := tx.Create(&model.Setting{Key: key, Value: *value, UpdatedBy: operator.UserID}).Error; err != nil {
+					return err
+				}
+			}
+
+			history := model.SettingHistory{
+				Key:      key,
+				OldValue: old,
+				NewValue: value,
+				UserID:   operator.UserID,
+				Username: operator.Username,
+			}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// 保存失败时恢复原有配置，避免重启后配置与当前不一致
+		if _, restoreErr := config.SetOverrides(prev); restoreErr != nil {
+			logger.Ctx(ctx).Error("恢复配置项失败", zap.Error(restoreErr))
+		}
+		return nil, fmt.Errorf("保存配置项失败: %w", err)
+	}
+
+	logger.Ctx(ctx).Info("配置项已修改", zap.Strings("sections", changed), zap.Uint("user_id", operator.UserID))
+	return changed, nil
+}
+
+// Snapshot 获取指定配置项当前生效的值，敏感字段已脱敏，用于审计日志
+func (s *SettingService) Snapshot(keys []string) map[string]any {
+	conf := config.Get()
+	snapshot := make(map[string]any, len(keys))
+	for _, key := range keys {
+		snapshot[key] = conf.Value(key, true)
+	}
+	return snapshot
+}
+
+// History 查询配置项修改记录，敏感字段已脱敏
+func (s *SettingService) History(ctx context.Context, query *SettingHistoryQuery) ([]model.SettingHistory, int64, error) {
+	query.Normalize()
+
+	db := database.Ctx(ctx).Model(&model.SettingHistory{})
+	if query.Key != "" {
+		db = db.Where(&model.SettingHistory{Key: query.Key})
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []model.SettingHistory
+	if err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range records {
+		records[i].OldValue = redactSettingValue(records[i].Key, records[i].OldValue)
+		records[i].NewValue = redactSettingValue(records[i].Key, records[i].NewValue)
+	}
+	return records, total, nil
+}
+
+// findSchema 查找配置项定义
+func findSchema(key string) *SettingSchema {
+	for i := range settingSchemas {
+		if settingSchemas[i].Key == key {
+			return &settingSchemas[i]
+		}
+	}
+	return nil
+}
+
+// parseSettingValue 解析JSON格式的配置项值，数字保留为 json.Number 以便按配置项类型区分整数和小数
+func parseSettingValue(key string, data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%s 的值不是有效的JSON: %w", key, err)
+	}
+	return value, nil
+}
+
+// checkSettingValue 按配置项定义检查值的类型和可选值
+func checkSettingValue(key string, value any) error {
+	schema := findSchema(key)
+	if schema == nil {
+		return fmt.Errorf("不支持在线修改的配置项: %s", key)
+	}
+	if err := config.CheckValue(key, value); err != nil {
+		return err
+	}
+	if len(schema.Options) == 0 {
+		return nil
+	}
+
+	str, _ := value.(string)
+	for _, option := range schema.Options {
+		if str == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s 的值必须是以下之一: %s", key, strings.Join(schema.Options, ", "))
+}
+
+// redactSettingValue 隐藏修改记录中的敏感字段
+func redactSettingValue(key string, value *string) *string {
+	if value == nil {
+		return nil
+	}
+	parsed, err := parseSettingValue(key, []byte(*value))
+	if err != nil {
+		return value
+	}
+	data, err := json.Marshal(config.Redact(key, parsed))
+	if err != nil {
+		return nil
+	}
+	str := string(data)
+	return &str
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/internal/cd2"
+	"cinexus/pkg/pb"
+)
+
+// TransferService 传输任务服务，汇总所有CD2实例的上传、下载和复制任务
+type TransferService struct{}
+
+// TransferQuery 传输任务查询条件，不指定实例时汇总所有实例
+type TransferQuery struct {
+	PageRequest
+	Instance string `form:"instance"`
+	Status   string `form:"status"`  // 按状态过滤，不区分大小写
+	Keyword  string `form:"keyword"` // 按路径关键字过滤
+}
+
+// InstanceTransferStat 单个实例的传输统计
+type InstanceTransferStat struct {
+	Instance       string  `json:"instance"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	Error          string  `json:"error,omitempty"` // 该实例查询失败时的错误，不影响其他实例
+}
+
+// TransferList 传输任务分页列表
+type TransferList struct {
+	List      any                    `json:"list"`
+	Total     int64                  `json:"total"`
+	Page      int                    `json:"page"`
+	PageSize  int                    `json:"page_size"`
+	Instances []InstanceTransferStat `json:"instances"`
+}
+
+// UploadTask 上传任务
+type UploadTask struct {
+	Instance         string  `json:"instance"`
+	Key              string  `json:"key"`
+	DestPath         string  `json:"dest_path"`
+	Size             uint64  `json:"size"`
+	TransferredBytes uint64  `json:"transferred_bytes"`
+	Progress         float64 `json:"progress"` // 0-100
+	Status           string  `json:"status"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// DownloadTask 下载任务
+type DownloadTask struct {
+	Instance    string   `json:"instance"`
+	FilePath    string   `json:"file_path"`
+	FileLength  uint64   `json:"file_length"`
+	BufferUsed  uint64   `json:"buffer_used"`
+	ThreadCount uint32   `json:"thread_count"`
+	Process     []string `json:"process"`
+	Detail      string   `json:"detail"`
+	Status      string   `json:"status"` // downloading 或 error
+	Error       string   `json:"error,omitempty"`
+}
+
+// CopyTaskError 复制任务中的单个错误
+type CopyTaskError struct {
+	Time    *time.Time `json:"time"`
+	Message string     `json:"message"`
+}
+
+// CopyTaskInfo 复制/移动任务
+type CopyTaskInfo struct {
+	Instance       string          `json:"instance"`
+	Mode           string          `json:"mode"` // copy 或 move
+	SourcePath     string          `json:"source_path"`
+	DestPath       string          `json:"dest_path"`
+	Status         string          `json:"status"` // pending, scanning, scanned, completed, failed, paused
+	Paused         bool            `json:"paused"`
+	TotalFolders   uint64          `json:"total_folders"`
+	TotalFiles     uint64          `json:"total_files"`
+	FailedFolders  uint64          `json:"failed_folders"`
+	FailedFiles    uint64          `json:"failed_files"`
+	UploadedFiles  uint64          `json:"uploaded_files"`
+	CancelledFiles uint64          `json:"cancelled_files"`
+	SkippedFiles   uint64          `json:"skipped_files"`
+	TotalBytes     uint64          `json:"total_bytes"`
+	UploadedBytes  uint64          `json:"uploaded_bytes"`
+	Progress       float64         `json:"progress"` // 0-100
+	Errors         []CopyTaskError `json:"errors"`
+}
+
+// UploadActionRequest 上传任务操作请求，All 为 true 时操作实例的所有上传任务
+type UploadActionRequest struct {
+	Instance string   `json:"instance"`
+	Keys     []string `json:"keys"`
+	All      bool     `json:"all"`
+}
+
+// CopyTaskActionRequest 复制任务操作请求
+type CopyTaskActionRequest struct {
+	Instance   string `json:"instance"`
+	SourcePath string `json:"source_path" binding:"required"`
+	DestPath   string `json:"dest_path" binding:"required"`
+}
+
+// 上传任务操作
+const (
+	UploadActionPause  = "pause"
+	UploadActionResume = "resume"
+	UploadActionCancel = "cancel"
+)
+
+// TransferActionResult 单个实例的操作结果
+type TransferActionResult struct {
+	Instance string `json:"instance"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ListUploads 查询上传任务
+func (s *TransferService) ListUploads(ctx context.Context, query *TransferQuery) (*TransferList, error) {
+	var tasks []UploadTask
+	stats, err := eachTransferInstance(query.Instance, func(c *cd2.Client) (float64, error) {
+		result, err := c.GetUploadFileList(ctx, &pb.GetUploadFileListRequest{GetAll: true, Filter: query.Keyword})
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range result.UploadFiles {
+			tasks = append(tasks, UploadTask{
+				Instance:         c.Name,
+				Key:              f.Key,
+				DestPath:         f.DestPath,
+				Size:             f.Size,
+				TransferredBytes: f.TransferedBytes,
+				Progress:         percent(f.TransferedBytes, f.Size),
+				Status:           f.Status,
+				Error:            f.ErrorMessage,
+			})
+		}
+		return result.GlobalBytesPerSecond, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := tasks[:0]
+	for _, t := range tasks {
+		if matchTransfer(query, t.Status, t.DestPath) {
+			filtered = append(filtered, t)
+		}
+	}
+	return newTransferList(filtered, query, stats), nil
+}
+
+// ListDownloads 查询下载任务
+func (s *TransferService) ListDownloads(ctx context.Context, query *TransferQuery) (*TransferList, error) {
+	var tasks []DownloadTask
+	stats, err := eachTransferInstance(query.Instance, func(c *cd2.Client) (float64, error) {
+		result, err := c.GetDownloadFileList(ctx, &emptypb.Empty{})
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range result.DownloadFiles {
+			task := DownloadTask{
+				Instance:    c.Name,
+				FilePath:    f.FilePath,
+				FileLength:  f.FileLength,
+				BufferUsed:  f.TotalBufferUsed,
+				ThreadCount: f.DownloadThreadCount,
+				Process:     f.Process,
+				Detail:      f.DetailDownloadInfo,
+				Status:      "downloading",
+				Error:       f.GetLastDownloadError(),
+			}
+			if task.Error != "" {
+				task.Status = "error"
+			}
+			tasks = append(tasks, task)
+		}
+		return result.GlobalBytesPerSecond, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := tasks[:0]
+	for _, t := range tasks {
+		if matchTransfer(query, t.Status, t.FilePath) {
+			filtered = append(filtered, t)
+		}
+	}
+	return newTransferList(filtered, query, stats), nil
+}
+
+// ListCopyTasks 查询复制/移动任务
+func (s *TransferService) ListCopyTasks(ctx context.Context, query *TransferQuery) (*TransferList, error) {
+	var tasks []CopyTaskInfo
+	stats, err := eachTransferInstance(query.Instance, func(c *cd2.Client) (float64, error) {
+		result, err := c.GetCopyTasks(ctx, &emptypb.Empty{})
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range result.CopyTasks {
+			tasks = append(tasks, toCopyTaskInfo(c.Name, t))
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := tasks[:0]
+	for _, t := range tasks {
+		if matchTransfer(query, t.Status, t.SourcePath+"\n"+t.DestPath) {
+			filtered = append(filtered, t)
+		}
+	}
+	return newTransferList(filtered, query, stats), nil
+}
+
+// UploadAction 暂停、继续或取消上传任务，All 为 true 且未指定实例时操作所有实例
+func (s *TransferService) UploadAction(ctx context.Context, action string, req *UploadActionRequest) ([]TransferActionResult, error) {
+	if !req.All && len(req.Keys) == 0 {
+		return nil, errors.New("请指定要操作的任务或设置 all")
+	}
+
+	clients, err := transferClients(req.Instance)
+	if err != nil {
+		return nil, err
+	}
+	// 任务的 key 只在所属实例内有效，不允许跨实例按 key 操作
+	if !req.All && len(clients) > 1 {
+		return nil, errors.New("按任务操作时必须指定实例")
+	}
+
+	results := make([]TransferActionResult, 0, len(clients))
+	for _, c := range clients {
+		var err error
+		keys := &pb.MultpleUploadFileKeyRequest{Keys: req.Keys}
+		switch {
+		case action == UploadActionPause && req.All:
+			_, err = c.PauseAllUploadFiles(ctx, &emptypb.Empty{})
+		case action == UploadActionPause:
+			_, err = c.PauseUploadFiles(ctx, keys)
+		case action == UploadActionResume && req.All:
+			_, err = c.ResumeAllUploadFiles(ctx, &emptypb.Empty{})
+		case action == UploadActionResume:
+			_, err = c.ResumeUploadFiles(ctx, keys)
+		case action == UploadActionCancel && req.All:
+			_, err = c.CancelAllUploadFiles(ctx, &emptypb.Empty{})
+		case action == UploadActionCancel:
+			_, err = c.CancelUploadFiles(ctx, keys)
+		default:
+			return nil, errors.New("不支持的操作: " + action)
+		}
+		results = append(results, actionResult(c.Name, err))
+	}
+	return results, nil
+}
+
+// PauseCopyTask 暂停或继续复制任务
+func (s *TransferService) PauseCopyTask(ctx context.Context, req *CopyTaskActionRequest, pause bool) error {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PauseCopyTask(ctx, &pb.PauseCopyTaskRequest{
+		SourcePath: req.SourcePath,
+		DestPath:   req.DestPath,
+		Pause:      pause,
+	})
+	return err
+}
+
+// CancelCopyTask 取消复制任务
+func (s *TransferService) CancelCopyTask(ctx context.Context, req *CopyTaskActionRequest) error {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CancelCopyTask(ctx, &pb.CopyTaskRequest{SourcePath: req.SourcePath, DestPath: req.DestPath})
+	return err
+}
+
+// RestartCopyTask 重新开始复制任务
+func (s *TransferService) RestartCopyTask(ctx context.Context, req *CopyTaskActionRequest) error {
+	client, err := cd2.Get(req.Instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.RestartCopyTask(ctx, &pb.CopyTaskRequest{SourcePath: req.SourcePath, DestPath: req.DestPath})
+	return err
+}
+
+// RemoveCompletedCopyTasks 清除已完成的复制任务，未指定实例时清除所有实例
+func (s *TransferService) RemoveCompletedCopyTasks(ctx context.Context, instance string) ([]TransferActionResult, error) {
+	clients, err := transferClients(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TransferActionResult, 0, len(clients))
+	for _, c := range clients {
+		_, err := c.RemoveCompletedCopyTasks(ctx, &emptypb.Empty{})
+		results = append(results, actionResult(c.Name, err))
+	}
+	return results, nil
+}
+
+// toCopyTaskInfo 转换复制任务
+func toCopyTaskInfo(instance string, t *pb.CopyTask) CopyTaskInfo {
+	info := CopyTaskInfo{
+		Instance:       instance,
+		Mode:           strings.ToLower(t.TaskMode.String()),
+		SourcePath:     t.SourcePath,
+		DestPath:       t.DestPath,
+		Status:         strings.ToLower(t.Status.String()),
+		Paused:         t.Paused,
+		TotalFolders:   t.TotalFolders,
+		TotalFiles:     t.TotalFiles,
+		FailedFolders:  t.FailedFolders,
+		FailedFiles:    t.FailedFiles,
+		UploadedFiles:  t.UploadedFiles,
+		CancelledFiles: t.CancelledFiles,
+		SkippedFiles:   t.SkippedFiles,
+		TotalBytes:     t.TotalBytes,
+		UploadedBytes:  t.UploadedBytes,
+		Progress:       percent(t.UploadedBytes, t.TotalBytes),
+		Errors:         make([]CopyTaskError, 0, len(t.Errors)),
+	}
+	if t.Paused && t.Status != pb.CopyTask_Completed && t.Status != pb.CopyTask_Failed {
+		info.Status = "paused"
+	}
+
+	for _, e := range t.Errors {
+		item := CopyTaskError{Message: e.Message}
+		if e.Time != nil {
+			tm := e.Time.AsTime()
+			item.Time = &tm
+		}
+		info.Errors = append(info.Errors, item)
+	}
+	return info
+}
+
+// eachTransferInstance 依次查询实例，单个实例失败时记录错误并继续，只有指定的实例不存在时返回错误
+func eachTransferInstance(instance string, fn func(c *cd2.Client) (float64, error)) ([]InstanceTransferStat, error) {
+	clients, err := transferClients(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]InstanceTransferStat, 0, len(clients))
+	for _, c := range clients {
+		speed, err := fn(c)
+		stat := InstanceTransferStat{Instance: c.Name, BytesPerSecond: speed}
+		if err != nil {
+			stat.Error = err.Error()
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// transferClients 获取要操作的实例，未指定时返回所有实例
+func transferClients(instance string) ([]*cd2.Client, error) {
+	if instance == "" {
+		return cd2.All(), nil
+	}
+
+	client, err := cd2.Get(instance)
+	if err != nil {
+		return nil, err
+	}
+	return []*cd2.Client{client}, nil
+}
+
+// matchTransfer 按状态和关键字过滤
+func matchTransfer(query *TransferQuery, status, paths string) bool {
+	if query.Status != "" && !strings.EqualFold(query.Status, status) {
+		return false
+	}
+	if query.Keyword != "" && !strings.Contains(strings.ToLower(paths), strings.ToLower(query.Keyword)) {
+		return false
+	}
+	return true
+}
+
+// newTransferList 分页，任务按实例的配置顺序排列
+func newTransferList[T any](items []T, query *TransferQuery, stats []InstanceTransferStat) *TransferList {
+	return &TransferList{
+		List:      paginate(items, &query.PageRequest),
+		Total:     int64(len(items)),
+		Page:      query.Page,
+		PageSize:  query.PageSize,
+		Instances: stats,
+	}
+}
+
+// actionResult 转换实例操作结果
+func actionResult(instance string, err error) TransferActionResult {
+	result := TransferActionResult{Instance: instance, Success: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// percent 计算百分比，保留两位小数
+func percent(done, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(done)/float64(total)*10000) / 100
+}
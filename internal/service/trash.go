@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/internal/database"
+	"cinexus/internal/model"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/pb"
+)
+
+// trashDirName 每个云盘根目录下的回收站目录名
+const trashDirName = ".cinexus-trash"
+
+// ErrTrashItemNotFound 回收站记录不存在
+var ErrTrashItemNotFound error = &NotFoundError{Resource: "回收站记录"}
+
+// TrashService 回收站服务
+type TrashService struct{}
+
+// TrashQuery 回收站查询参数
+type TrashQuery struct {
+	PageRequest
+	Instance string `form:"instance"`
+	Status   string `form:"status"`
+	Source   string `form:"source"`
+	Keyword  string `form:"keyword"` // 按原路径模糊匹配
+}
+
+// List 获取回收站中的文件，默认只返回尚未恢复或删除的文件
+func (s *TrashService) List(ctx context.Context, query *TrashQuery) ([]model.TrashItem, int64, error) {
+	var items []model.TrashItem
+	var total int64
+
+	query.Normalize()
+	db := database.Ctx(ctx).Model(&model.TrashItem{})
+	if query.Instance != "" {
+		db = db.Where("instance = ?", query.Instance)
+	}
+	status := query.Status
+	if status == "" {
+		status = model.TrashStatusTrashed
+	}
+	if status != "all" {
+		db = db.Where("status = ?", status)
+	}
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+	}
+	if query.Keyword != "" {
+		db = db.Where("original_path LIKE ? ESCAPE '!'", "%"+escapeLike(query.Keyword)+"%")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("id DESC").Offset(query.Offset()).Limit(query.PageSize).Find(&items).Error
+	return items, total, err
+}
+
+// Restore 将文件移回原位置，原位置已有同名文件时不恢复
+func (s *TrashService) Restore(ctx context.Context, id uint) (*model.TrashItem, error) {
+	item, err := getTrashItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != model.TrashStatusTrashed {
+		return nil, errors.New("文件不在回收站中")
+	}
+
+	client, err := cd2.Get(item.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, exists, err := statFile(ctx, client, item.TrashPath); err != nil {
+		return nil, err
+	} else if !exists {
+		markTrashItem(item, model.TrashStatusMissing, "回收站中的文件已不存在")
+		return nil, &FileOperationError{Operation: "恢复", Message: "回收站中的文件已不存在"}
+	}
+	if _, exists, err := statFile(ctx, client, item.OriginalPath); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, &FileOperationError{Operation: "恢复", Message: "原位置已存在同名文件"}
+	}
+
+	parent := path.Dir(item.OriginalPath)
+	if err := mkdirAll(ctx, client, parent); err != nil {
+		return nil, err
+	}
+	reply, err := client.MoveFile(ctx, &pb.MoveFileRequest{TheFilePaths: []string{item.TrashPath}, DestPath: parent})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("恢复", reply); err != nil {
+		return nil, err
+	}
+
+	// 移入回收站时因重名被改名的文件，恢复后改回原来的名称
+	if name := path.Base(item.OriginalPath); path.Base(item.TrashPath) != name {
+		reply, err := client.RenameFile(ctx, &pb.RenameFileRequest{
+			TheFilePath: path.Join(parent, path.Base(item.TrashPath)),
+			NewName:     name,
+		})
+		if err == nil {
+			err = checkResult("恢复原文件名", reply)
+		}
+		if err != nil {
+			item.Error = truncate(err.Error(), 512)
+		}
+	}
+
+	now := time.Now()
+	item.Status = model.TrashStatusRestored
+	item.RestoredAt = &now
+	if err := database.Ctx(ctx).Model(item).Select("status", "restored_at", "error").Updates(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Purge 立即永久删除回收站中的文件
+func (s *TrashService) Purge(ctx context.Context, id uint) error {
+	item, err := getTrashItem(ctx, id)
+	if err != nil {
+		return err
+	}
+	if item.Status != model.TrashStatusTrashed {
+		return errors.New("文件不在回收站中")
+	}
+
+	client, err := cd2.Get(item.Instance)
+	if err != nil {
+		return err
+	}
+	if err := purgeTrashItem(ctx, client, item); err != nil {
+		return err
+	}
+	cleanTrashDirs(ctx, client, []string{path.Dir(item.TrashPath)})
+	return nil
+}
+
+// StartTrashPurger 定期永久删除超过保留期的文件
+func StartTrashPurger(ctx context.Context) {
+	cfg := config.Get().Trash
+	if !cfg.Enabled || cfg.RetentionDays <= 0 {
+		return
+	}
+	interval := time.Duration(cfg.PurgeInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		for {
+			heartbeat(ctx, "trash_purger", interval)
+			purgeExpiredTrash(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// moveToTrash 将文件移入所在云盘的回收站，未启用回收站时删除到云盘自带的回收站并返回 nil
+func moveToTrash(ctx context.Context, client *cd2.Client, p, source string, userID uint) (*model.TrashItem, error) {
+	conf := config.Get().Trash
+	if !conf.Enabled {
+		reply, err := client.DeleteFile(ctx, &pb.FileRequest{Path: p})
+		if err != nil {
+			return nil, err
+		}
+		return nil, checkResult("删除", reply)
+	}
+
+	if inTrash(p) {
+		return nil, &FileOperationError{Operation: "删除", Message: "文件已在回收站中，请使用回收站的永久删除"}
+	}
+	root, ok := trashRoot(p)
+	if !ok {
+		return nil, &FileOperationError{Operation: "删除", Message: "不能删除云盘根目录"}
+	}
+
+	file, exists, err := statFile(ctx, client, p)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &FileOperationError{Operation: "删除", Message: "文件不存在"}
+	}
+
+	now := time.Now()
+	dir := path.Join(root, now.Format("2006-01-02"))
+	if err := mkdirAll(ctx, client, dir); err != nil {
+		return nil, err
+	}
+
+	rename := pb.MoveFileRequest_Rename
+	reply, err := client.MoveFile(ctx, &pb.MoveFileRequest{TheFilePaths: []string{p}, DestPath: dir, ConflictPolicy: &rename})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResult("移入回收站", reply); err != nil {
+		return nil, err
+	}
+
+	item := &model.TrashItem{
+		Instance:     client.Name,
+		OriginalPath: p,
+		TrashPath:    path.Join(dir, path.Base(p)),
+		Name:         truncate(path.Base(p), 255),
+		IsDir:        file.IsDirectory,
+		Size:         file.Size,
+		Source:       source,
+		UserID:       userID,
+		Status:       model.TrashStatusTrashed,
+	}
+	// 同名冲突时CD2会改名，以返回的路径为准
+	if len(reply.ResultFilePaths) == 1 && strings.HasPrefix(reply.ResultFilePaths[0], dir+"/") {
+		item.TrashPath = reply.ResultFilePaths[0]
+	}
+	if days := conf.RetentionDays; days > 0 {
+		expire := now.AddDate(0, 0, days)
+		item.ExpiresAt = &expire
+	}
+
+	if err := database.Ctx(ctx).Create(item).Error; err != nil {
+		logger.Ctx(ctx).Error("保存回收站记录失败", zap.String("path", p), zap.String("trash_path", item.TrashPath), zap.Error(err))
+		return nil, err
+	}
+	return item, nil
+}
+
+// purgeExpiredTrash 永久删除所有过期的文件，并删除清空的日期目录
+func purgeExpiredTrash(ctx context.Context) {
+	start := time.Now()
+	var items []model.TrashItem
+	err := database.Ctx(ctx).Where("status = ? AND expires_at < ?", model.TrashStatusTrashed, time.Now()).
+		Order("id").Find(&items).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("查询过期的回收站文件失败", zap.Error(err))
+		metrics.ObserveJob("trash_purge", "failed", start)
+		return
+	}
+
+	dirs := make(map[string][]string)
+	purged, failed := 0, 0
+	for i := range items {
+		if ctx.Err() != nil {
+			return
+		}
+
+		item := &items[i]
+		client, err := cd2.Get(item.Instance)
+		if err != nil {
+			continue
+		}
+		if err := purgeTrashItem(ctx, client, item); err != nil {
+			logger.Ctx(ctx).Warn("清理回收站文件失败", zap.String("instance", item.Instance), zap.String("path", item.TrashPath), zap.Error(err))
+			failed++
+			continue
+		}
+		purged++
+		dirs[client.Name] = append(dirs[client.Name], path.Dir(item.TrashPath))
+	}
+
+	for instance, list := range dirs {
+		if client, err := cd2.Get(instance); err == nil {
+			cleanTrashDirs(ctx, client, list)
+		}
+	}
+	if purged > 0 {
+		logger.Ctx(ctx).Info("已清理过期的回收站文件", zap.Int("count", purged))
+	}
+	result := "completed"
+	if failed > 0 {
+		result = "partial"
+	}
+	metrics.ObserveJob("trash_purge", result, start)
+}
+
+// purgeTrashItem 永久删除回收站中的单个文件，文件已不存在时标记为丢失
+func purgeTrashItem(ctx context.Context, client *cd2.Client, item *model.TrashItem) error {
+	if _, exists, err := statFile(ctx, client, item.TrashPath); err != nil {
+		return err
+	} else if !exists {
+		markTrashItem(item, model.TrashStatusMissing, "回收站中的文件已不存在")
+		return nil
+	}
+
+	reply, err := client.DeleteFilePermanently(ctx, &pb.FileRequest{Path: item.TrashPath})
+	if err != nil {
+		return err
+	}
+	if err := checkResult("永久删除", reply); err != nil {
+		markTrashItem(item, model.TrashStatusTrashed, err.Error())
+		return err
+	}
+
+	now := time.Now()
+	item.Status = model.TrashStatusPurged
+	item.PurgedAt = &now
+	item.Error = ""
+	return database.Ctx(ctx).Model(item).Select("status", "purged_at", "error").Updates(item).Error
+}
+
+// cleanTrashDirs 删除已清空的日期目录
+func cleanTrashDirs(ctx context.Context, client *cd2.Client, dirs []string) {
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		if seen[dir] || !inTrash(dir) {
+			continue
+		}
+		seen[dir] = true
+
+		list, err := listSubFiles(ctx, client, dir, true)
+		if err != nil || len(list) > 0 {
+			continue
+		}
+		reply, err := client.DeleteFilePermanently(ctx, &pb.FileRequest{Path: dir})
+		if err == nil {
+			err = checkResult("删除回收站目录", reply)
+		}
+		if err != nil {
+			logger.Ctx(ctx).Warn("删除回收站目录失败", zap.String("path", dir), zap.Error(err))
+		}
+	}
+}
+
+// markTrashItem 更新回收站文件的状态和错误信息
+func markTrashItem(item *model.TrashItem, status, msg string) {
+	item.Status = status
+	item.Error = truncate(msg, 512)
+	database.DB.Model(item).Select("status", "error").Updates(item)
+}
+
+// getTrashItem 获取回收站记录
+func getTrashItem(ctx context.Context, id uint) (*model.TrashItem, error) {
+	var item model.TrashItem
+	if err := database.Ctx(ctx).First(&item, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// mkdirAll 逐级创建目录
+func mkdirAll(ctx context.Context, client *cd2.Client, dir string) error {
+	if dir == "/" {
+		return nil
+	}
+
+	_, exists, err := statFile(ctx, client, dir)
+	if err != nil || exists {
+		return err
+	}
+	if err := mkdirAll(ctx, client, path.Dir(dir)); err != nil {
+		return err
+	}
+
+	reply, err := client.CreateFolder(ctx, &pb.CreateFolderRequest{ParentPath: path.Dir(dir), FolderName: path.Base(dir)})
+	if err != nil {
+		return err
+	}
+	return checkResult("创建目录", reply.Result)
+}
+
+// trashRoot 返回路径所在云盘的回收站目录，云盘根目录本身不能移入回收站
+func trashRoot(p string) (string, bool) {
+	cloud, rest, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	if cloud == "" || rest == "" {
+		return "", false
+	}
+	return "/" + cloud + "/" + trashDirName, true
+}
+
+// inTrash 路径是否位于回收站目录中
+func inTrash(p string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 3)
+	return len(parts) >= 2 && parts[1] == trashDirName
+}
@@ -1,31 +1,37 @@
 package service
 
 import (
+	"context"
 	"errors"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"cinexus/internal/database"
 	"cinexus/internal/model"
 	"cinexus/pkg/jwt"
+	"cinexus/pkg/logger"
 )
 
 // UserService 用户服务
-type UserService struct{}
+type UserService struct {
+	registerService RegisterService
+}
 
 // LoginRequest 登录请求
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Password string `json:"password" binding:"required" redact:"true"`
 }
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6,max=50"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email" binding:"omitempty,email"`
-	Phone    string `json:"phone"`
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	Password   string `json:"password" binding:"required,min=6,max=50" redact:"true"`
+	Nickname   string `json:"nickname"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	Phone      string `json:"phone"`
+	InviteCode string `json:"invite_code"`
 }
 
 // UpdateUserRequest 更新用户请求
@@ -38,8 +44,20 @@ type UpdateUserRequest struct {
 
 // UpdatePasswordRequest 更新密码请求
 type UpdatePasswordRequest struct {
-	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6,max=50"`
+	OldPassword string `json:"old_password" binding:"required" redact:"true"`
+	NewPassword string `json:"new_password" binding:"required,min=6,max=50" redact:"true"`
+}
+
+// UserQuery 用户列表查询条件
+type UserQuery struct {
+	PageRequest
+	Role    string `form:"role"`
+	Keyword string `form:"keyword"` // 匹配用户名和邮箱
+}
+
+// SetRoleRequest 修改用户角色请求
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin editor user"`
 }
 
 // LoginResponse 登录响应
@@ -49,11 +67,11 @@ type LoginResponse struct {
 }
 
 // Login 用户登录
-func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
+func (s *UserService) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
 	var user model.User
 
 	// 查询用户
-	err := database.DB.Where("username = ?", req.Username).First(&user).Error
+	err := database.Ctx(ctx).Where("username = ?", req.Username).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -67,7 +85,11 @@ func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
 	}
 
 	// 检查用户状态
-	if user.Status != 1 {
+	switch user.Status {
+	case model.UserStatusActive:
+	case model.UserStatusPending:
+		return nil, errors.New("邮箱尚未验证")
+	default:
 		return nil, errors.New("用户已被禁用")
 	}
 
@@ -84,40 +106,86 @@ func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
 }
 
 // Register 用户注册
-func (s *UserService) Register(req *RegisterRequest) error {
+func (s *UserService) Register(ctx context.Context, req *RegisterRequest) (*model.User, error) {
+	settings := s.registerService.GetSettings()
+	if settings.Mode == RegisterModeClosed {
+		return nil, errors.New("系统已关闭注册")
+	}
+	if settings.EmailVerification && req.Email == "" {
+		return nil, errors.New("注册需要填写邮箱")
+	}
+
 	// 检查用户名是否已存在
 	var count int64
-	database.DB.Model(&model.User{}).Where("username = ?", req.Username).Count(&count)
+	database.Ctx(ctx).Model(&model.User{}).Where("username = ?", req.Username).Count(&count)
 	if count > 0 {
-		return errors.New("用户名已存在")
+		return nil, errors.New("用户名已存在")
 	}
 
 	// 检查邮箱是否已存在
 	if req.Email != "" {
-		database.DB.Model(&model.User{}).Where("email = ?", req.Email).Count(&count)
+		database.Ctx(ctx).Model(&model.User{}).Where("email = ?", req.Email).Count(&count)
 		if count > 0 {
-			return errors.New("邮箱已存在")
+			return nil, errors.New("邮箱已存在")
 		}
 	}
 
-	// 创建用户
-	user := model.User{
-		Username: req.Username,
-		Password: req.Password,
-		Nickname: req.Nickname,
-		Email:    req.Email,
-		Phone:    req.Phone,
-		Role:     "user",
-		Status:   1,
+	var user model.User
+	var verify *verificationMail
+	err := database.Ctx(ctx).Transaction(func(tx *gorm.DB) error {
+		role := model.RoleUser
+		if settings.Mode == RegisterModeInvite {
+			var err error
+			if role, err = consumeInviteCode(tx, req.InviteCode); err != nil {
+				return err
+			}
+		}
+
+		status := model.UserStatusActive
+		if settings.EmailVerification {
+			status = model.UserStatusPending
+		}
+
+		// 创建用户
+		user = model.User{
+			Username: req.Username,
+			Password: req.Password,
+			Nickname: req.Nickname,
+			Email:    req.Email,
+			Phone:    req.Phone,
+			Role:     role,
+			Status:   status,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		if settings.EmailVerification {
+			var err error
+			verify, err = createVerification(tx, &user)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再发送邮件，发送失败时账号保持待验证状态，可以重新发送验证邮件
+	if verify != nil {
+		if err := verify.send(); err != nil {
+			logger.Ctx(ctx).Warn("发送验证邮件失败", zap.Uint("user_id", user.ID), zap.Error(err))
+			return &user, ErrVerificationMailFailed
+		}
 	}
 
-	return database.DB.Create(&user).Error
+	return &user, nil
 }
 
 // GetUserByID 根据ID获取用户
-func (s *UserService) GetUserByID(id uint) (*model.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	err := database.DB.First(&user, id).Error
+	err := database.Ctx(ctx).First(&user, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -128,18 +196,18 @@ func (s *UserService) GetUserByID(id uint) (*model.User, error) {
 }
 
 // UpdateUser 更新用户信息
-func (s *UserService) UpdateUser(id uint, req *UpdateUserRequest) error {
+func (s *UserService) UpdateUser(ctx context.Context, id uint, req *UpdateUserRequest) error {
 	// 检查邮箱是否已存在
 	if req.Email != "" {
 		var count int64
-		database.DB.Model(&model.User{}).Where("email = ? AND id != ?", req.Email, id).Count(&count)
+		database.Ctx(ctx).Model(&model.User{}).Where("email = ? AND id != ?", req.Email, id).Count(&count)
 		if count > 0 {
 			return errors.New("邮箱已存在")
 		}
 	}
 
 	// 更新用户
-	return database.DB.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+	return database.Ctx(ctx).Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
 		"nickname": req.Nickname,
 		"email":    req.Email,
 		"phone":    req.Phone,
@@ -148,11 +216,11 @@ func (s *UserService) UpdateUser(id uint, req *UpdateUserRequest) error {
 }
 
 // UpdatePassword 更新用户密码
-func (s *UserService) UpdatePassword(id uint, req *UpdatePasswordRequest) error {
+func (s *UserService) UpdatePassword(ctx context.Context, id uint, req *UpdatePasswordRequest) error {
 	var user model.User
 
 	// 查询用户
-	err := database.DB.First(&user, id).Error
+	err := database.Ctx(ctx).First(&user, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("用户不存在")
@@ -167,5 +235,40 @@ func (s *UserService) UpdatePassword(id uint, req *UpdatePasswordRequest) error
 
 	// 更新密码
 	user.Password = req.NewPassword
-	return database.DB.Save(&user).Error
+	return database.Ctx(ctx).Save(&user).Error
+}
+
+// ListUsers 获取用户列表
+func (s *UserService) ListUsers(ctx context.Context, query *UserQuery) ([]model.User, int64, error) {
+	query.Normalize()
+
+	db := database.Ctx(ctx).Model(&model.User{})
+	if query.Role != "" {
+		db = db.Where("role = ?", query.Role)
+	}
+	if query.Keyword != "" {
+		like := "%" + query.Keyword + "%"
+		db = db.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	err := db.Order("id").Offset(query.Offset()).Limit(query.PageSize).Find(&users).Error
+	return users, total, err
+}
+
+// SetRole 修改用户角色，用户重新登录后生效；管理员不能修改自己的角色
+func (s *UserService) SetRole(ctx context.Context, operatorID, id uint, role string) error {
+	if operatorID == id {
+		return errors.New("不能修改自己的角色")
+	}
+	if _, err := s.GetUserByID(ctx, id); err != nil {
+		return err
+	}
+
+	return database.Ctx(ctx).Model(&model.User{}).Where("id = ?", id).Update("role", role).Error
 }
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"cinexus/config"
+	"cinexus/internal/cd2"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/metrics"
+	"cinexus/pkg/notify"
+)
+
+// MountHealth 挂载点健康状态
+type MountHealth struct {
+	Instance        string     `json:"instance"`
+	MountPoint      string     `json:"mount_point"`
+	ProbePath       string     `json:"probe_path"`
+	Declared        bool       `json:"declared"`
+	Mounted         bool       `json:"mounted"`       // CD2 报告的挂载状态
+	ProbeSkipped    bool       `json:"probe_skipped"` // 本地无法访问该路径时跳过目录探测
+	Healthy         bool       `json:"healthy"`
+	Error           string     `json:"error,omitempty"`
+	FailCount       int        `json:"fail_count"`
+	RemountAttempts int        `json:"remount_attempts"`
+	LastCheck       time.Time  `json:"last_check"`
+	LastHealthy     *time.Time `json:"last_healthy,omitempty"`
+}
+
+// MountWatchdog 定期检查挂载点，失效时通知并自动重新挂载
+type MountWatchdog struct {
+	mountService MountService
+
+	mu      sync.RWMutex
+	states  map[string]*MountHealth
+	probing map[string]bool // 仍未返回的本地探测，失效的FUSE挂载上 readdir 可能一直阻塞
+}
+
+var watchdog = &MountWatchdog{
+	states:  make(map[string]*MountHealth),
+	probing: make(map[string]bool),
+}
+
+// StartMountWatchdog 启动挂载点健康检查
+func StartMountWatchdog(ctx context.Context) {
+	go func() {
+		for {
+			interval := time.Duration(config.Get().Watchdog.Interval) * time.Second
+			if interval <= 0 {
+				interval = time.Minute
+			}
+
+			heartbeat(ctx, "mount_watchdog", interval)
+			watchdog.CheckAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// MountHealthList 获取所有挂载点的健康状态
+func MountHealthList() []MountHealth {
+	watchdog.mu.RLock()
+	defer watchdog.mu.RUnlock()
+
+	list := make([]MountHealth, 0, len(watchdog.states))
+	for _, s := range watchdog.states {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Instance != list[j].Instance {
+			return list[i].Instance < list[j].Instance
+		}
+		return list[i].MountPoint < list[j].MountPoint
+	})
+	return list
+}
+
+// CheckAll 检查所有实例的挂载点
+func (w *MountWatchdog) CheckAll(ctx context.Context) {
+	start := time.Now()
+	for _, c := range cd2.All() {
+		w.checkInstance(ctx, c)
+	}
+	metrics.ObserveJob("mount_check", "completed", start)
+}
+
+// checkInstance 检查单个实例的挂载点
+func (w *MountWatchdog) checkInstance(ctx context.Context, client *cd2.Client) {
+	now := time.Now()
+
+	result, err := client.GetMountPoints(ctx, &emptypb.Empty{})
+	if err != nil {
+		// CD2 不可用时挂载点也无法提供服务，将已知的挂载点全部标记为失效
+		w.mu.Lock()
+		var failed []*MountHealth
+		for _, s := range w.states {
+			if s.Instance == client.Name {
+				s.LastCheck = now
+				failed = append(failed, w.markFailed(s, "获取CD2挂载状态失败: "+err.Error()))
+			}
+		}
+		w.mu.Unlock()
+		w.notifyFailed(failed)
+		return
+	}
+
+	declared := make(map[string]config.MountConfig)
+	for _, m := range client.Config().Mounts {
+		declared[m.MountPoint] = m
+	}
+
+	seen := make(map[string]bool)
+	missingDeclared := false
+	for _, m := range result.MountPoints {
+		seen[m.MountPoint] = true
+
+		conf, isDeclared := declared[m.MountPoint]
+		probePath := conf.ProbePath
+		if probePath == "" {
+			probePath = m.MountPoint
+		}
+
+		var probeErr error
+		skipped := false
+		if m.IsMounted {
+			skipped, probeErr = w.probe(client.Name+"|"+m.MountPoint, probePath)
+		}
+
+		key := client.Name + "|" + m.MountPoint
+		w.mu.Lock()
+		s, ok := w.states[key]
+		if !ok {
+			s = &MountHealth{Instance: client.Name, MountPoint: m.MountPoint, Healthy: true}
+			w.states[key] = s
+		}
+		s.ProbePath = probePath
+		s.Declared = isDeclared
+		s.Mounted = m.IsMounted
+		s.ProbeSkipped = skipped
+		s.LastCheck = now
+
+		var failed, recovered *MountHealth
+		switch {
+		case !m.IsMounted:
+			reason := "未挂载"
+			if m.FailReason != "" {
+				reason += ": " + m.FailReason
+			}
+			failed = w.markFailed(s, reason)
+		case probeErr != nil:
+			failed = w.markFailed(s, probeErr.Error())
+		default:
+			recovered = w.markHealthy(s, now)
+		}
+
+		// 只有声明的或CD2中设置为自动挂载的挂载点才自动重新挂载，手动卸载的保持原样
+		remount := !s.Healthy && (isDeclared || m.AutoMount) && w.shouldRemount(s)
+		if remount {
+			s.RemountAttempts++
+		}
+		attempts := s.RemountAttempts
+		w.mu.Unlock()
+
+		w.notifyFailed([]*MountHealth{failed})
+		if recovered != nil {
+			notify.Send(notify.LevelInfo, "挂载点已恢复",
+				fmt.Sprintf("CD2实例 %s 的挂载点 %s 已恢复", recovered.Instance, recovered.MountPoint))
+		}
+		if remount {
+			w.remount(ctx, client.Name, m.MountPoint, m.IsMounted, attempts)
+		}
+	}
+
+	w.mu.Lock()
+	for key, s := range w.states {
+		if s.Instance != client.Name || seen[s.MountPoint] {
+			continue
+		}
+		if _, ok := declared[s.MountPoint]; !ok {
+			delete(w.states, key)
+		}
+	}
+	var failed []*MountHealth
+	for mp, conf := range declared {
+		if seen[mp] {
+			continue
+		}
+		missingDeclared = true
+
+		key := client.Name + "|" + mp
+		s, ok := w.states[key]
+		if !ok {
+			s = &MountHealth{Instance: client.Name, MountPoint: mp, ProbePath: conf.ProbePath, Declared: true, Healthy: true}
+			w.states[key] = s
+		}
+		s.Mounted = false
+		s.LastCheck = now
+		failed = append(failed, w.markFailed(s, "CD2中不存在该挂载点"))
+	}
+	w.mu.Unlock()
+	w.notifyFailed(failed)
+
+	// 声明的挂载点被删除时交给调整逻辑重新创建
+	if missingDeclared && config.Get().Watchdog.AutoRemount {
+		logReconcile(w.mountService.Reconcile(ctx, client.Name))
+	}
+}
+
+// probe 在超时时间内读取挂载目录，本地不存在该路径时跳过探测
+func (w *MountWatchdog) probe(key, probePath string) (bool, error) {
+	w.mu.Lock()
+	if w.probing[key] {
+		w.mu.Unlock()
+		return false, errors.New("上次目录探测仍未返回，挂载可能已失效")
+	}
+	w.probing[key] = true
+	w.mu.Unlock()
+
+	type probeResult struct {
+		skipped bool
+		err     error
+	}
+	done := make(chan probeResult, 1)
+
+	go func() {
+		defer func() {
+			w.mu.Lock()
+			delete(w.probing, key)
+			w.mu.Unlock()
+		}()
+
+		if _, err := os.Stat(probePath); err != nil {
+			if os.IsNotExist(err) {
+				done <- probeResult{skipped: true}
+				return
+			}
+			done <- probeResult{err: fmt.Errorf("访问挂载目录失败: %w", err)}
+			return
+		}
+
+		f, err := os.Open(probePath)
+		if err != nil {
+			done <- probeResult{err: fmt.Errorf("打开挂载目录失败: %w", err)}
+			return
+		}
+		defer f.Close()
+
+		// 失效的挂载常表现为空目录，Emby 会据此删除媒体库条目，所以默认把空目录视为失效
+		_, err = f.Readdirnames(1)
+		switch {
+		case err == nil, errors.Is(err, io.EOF) && config.Get().Watchdog.AllowEmpty:
+			done <- probeResult{}
+		case errors.Is(err, io.EOF):
+			done <- probeResult{err: errors.New("挂载目录为空")}
+		default:
+			done <- probeResult{err: fmt.Errorf("读取挂载目录失败: %w", err)}
+		}
+	}()
+
+	timeout := time.Duration(config.Get().Watchdog.ProbeTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	select {
+	case r := <-done:
+		return r.skipped, r.err
+	case <-time.After(timeout):
+		return false, fmt.Errorf("读取挂载目录超时（%s）", timeout)
+	}
+}
+
+// remount 先卸载再挂载
+func (w *MountWatchdog) remount(ctx context.Context, instance, mountPoint string, mounted bool, attempt int) {
+	logger.Ctx(ctx).Warn("挂载点失效，尝试重新挂载",
+		zap.String("instance", instance),
+		zap.String("mount_point", mountPoint),
+		zap.Int("attempt", attempt),
+	)
+
+	if mounted {
+		if err := w.mountService.Unmount(ctx, instance, mountPoint); err != nil {
+			logger.Ctx(ctx).Warn("卸载挂载点失败", zap.String("instance", instance), zap.String("mount_point", mountPoint), zap.Error(err))
+		}
+	}
+
+	if err := w.mountService.Mount(ctx, instance, mountPoint); err != nil {
+		logger.Ctx(ctx).Error("重新挂载失败", zap.String("instance", instance), zap.String("mount_point", mountPoint), zap.Error(err))
+
+		if limit := config.Get().Watchdog.MaxRemountAttempts; limit > 0 && attempt >= limit {
+			notify.Send(notify.LevelError, "挂载点重新挂载失败",
+				fmt.Sprintf("CD2实例 %s 的挂载点 %s 已连续 %d 次重新挂载失败，停止自动重试，请手动处理。最后一次错误: %s",
+					instance, mountPoint, attempt, err.Error()))
+		}
+	}
+}
+
+// shouldRemount 是否还需要自动重新挂载，调用时需持有锁
+func (w *MountWatchdog) shouldRemount(s *MountHealth) bool {
+	conf := config.Get().Watchdog
+	if !conf.AutoRemount {
+		return false
+	}
+	return conf.MaxRemountAttempts <= 0 || s.RemountAttempts < conf.MaxRemountAttempts
+}
+
+// markFailed 标记挂载点失效，首次失效时返回需要通知的状态，调用时需持有锁
+func (w *MountWatchdog) markFailed(s *MountHealth, reason string) *MountHealth {
+	wasHealthy := s.Healthy
+	s.Healthy = false
+	s.Error = reason
+	s.FailCount++
+
+	if !wasHealthy {
+		return nil
+	}
+	copied := *s
+	return &copied
+}
+
+// markHealthy 标记挂载点正常，从失效中恢复时返回需要通知的状态，调用时需持有锁
+func (w *MountWatchdog) markHealthy(s *MountHealth, now time.Time) *MountHealth {
+	wasHealthy := s.Healthy
+	s.Healthy = true
+	s.Error = ""
+	s.FailCount = 0
+	s.RemountAttempts = 0
+	s.LastHealthy = &now
+
+	if wasHealthy {
+		return nil
+	}
+	copied := *s
+	return &copied
+}
+
+// notifyFailed 发送挂载点失效通知
+func (w *MountWatchdog) notifyFailed(list []*MountHealth) {
+	for _, s := range list {
+		if s == nil {
+			continue
+		}
+		notify.Send(notify.LevelWarn, "挂载点失效",
+			fmt.Sprintf("CD2实例 %s 的挂载点 %s 不可用: %s", s.Instance, s.MountPoint, s.Error))
+	}
+}
@@ -0,0 +1,26 @@
+package actor
+
+import "context"
+
+// Actor 操作者信息，由JWT中间件写入请求上下文，传递到服务层和CD2调用
+type Actor struct {
+	UserID   uint
+	Username string
+	IP       string
+}
+
+type actorKey struct{}
+
+// With 将操作者信息写入上下文
+func With(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// FromContext 从上下文中读取操作者信息，不存在时视为系统操作
+func FromContext(ctx context.Context) Actor {
+	if ctx == nil {
+		return Actor{}
+	}
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
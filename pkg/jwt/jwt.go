@@ -27,16 +27,18 @@ type CustomClaims struct {
 
 // GenerateToken 生成JWT令牌
 func GenerateToken(userID uint, username, role string) (string, error) {
+	conf := config.Get().JWT
+
 	// 设置JWT声明
 	claims := CustomClaims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(config.Conf.JWT.ExpireTime) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(conf.ExpireTime) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    config.Conf.JWT.Issuer,
+			Issuer:    conf.Issuer,
 		},
 	}
 
@@ -44,14 +46,14 @@ func GenerateToken(userID uint, username, role string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// 签名令牌
-	return token.SignedString([]byte(config.Conf.JWT.Secret))
+	return token.SignedString([]byte(conf.Secret))
 }
 
 // ParseToken 解析JWT令牌
 func ParseToken(tokenString string) (*CustomClaims, error) {
 	// 解析令牌
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.Conf.JWT.Secret), nil
+		return []byte(config.Get().JWT.Secret), nil
 	})
 
 	if err != nil {
@@ -84,7 +86,7 @@ func RefreshToken(tokenString string) (string, error) {
 		if errors.Is(err, ErrTokenExpired) {
 			// 解析过期的令牌，忽略过期错误
 			token, _ := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (any, error) {
-				return []byte(config.Conf.JWT.Secret), nil
+				return []byte(config.Get().JWT.Secret), nil
 			}, jwt.WithoutClaimsValidation())
 
 			if claims, ok := token.Claims.(*CustomClaims); ok {
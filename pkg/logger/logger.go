@@ -1,11 +1,15 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -15,28 +19,25 @@ import (
 
 var logger *zap.Logger
 
+// atomicLevel 所有输出共用的日志级别
+var atomicLevel = zap.NewAtomicLevel()
+
 // Init 初始化日志
 func Init() error {
+	conf := config.Get().Log
+
 	// 确保日志目录存在
-	logDir := filepath.Dir(config.Conf.Log.Filename)
+	logDir := filepath.Dir(conf.Filename)
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return err
 	}
 
-	// 设置日志级别
-	var level zapcore.Level
-	switch config.Conf.Log.Level {
-	case "debug":
-		level = zapcore.DebugLevel
-	case "info":
-		level = zapcore.InfoLevel
-	case "warn":
-		level = zapcore.WarnLevel
-	case "error":
-		level = zapcore.ErrorLevel
-	default:
+	// 设置日志级别，运行中可以通过 SetLevel 修改
+	level, err := parseLevel(conf.Level)
+	if err != nil {
 		level = zapcore.InfoLevel
 	}
+	atomicLevel.SetLevel(level)
 
 	// 配置编码器
 	encoderConfig := zapcore.EncoderConfig{
@@ -66,19 +67,19 @@ func Init() error {
 	today := time.Now().Format("2006-01-02")
 
 	// 构建日志文件名
-	logFilename := config.Conf.Log.Filename
+	logFilename := conf.Filename
 	ext := filepath.Ext(logFilename)
 	logFileNameOnly := logFilename[:len(logFilename)-len(ext)]
 	dailyLogFile := logFileNameOnly + "." + today + ext
 
 	// 配置lumberjack
 	fileOutput := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   dailyLogFile,               // 按日期命名的日志文件
-		MaxSize:    config.Conf.Log.MaxSize,    // 每个日志文件的最大大小（MB）
-		MaxBackups: config.Conf.Log.MaxBackups, // 保留的旧日志文件最大数量
-		MaxAge:     config.Conf.Log.MaxAge,     // 保留的旧日志文件最大天数
-		Compress:   config.Conf.Log.Compress,   // 是否压缩
-		LocalTime:  true,                       // 使用本地时间
+		Filename:   dailyLogFile,    // 按日期命名的日志文件
+		MaxSize:    conf.MaxSize,    // 每个日志文件的最大大小（MB）
+		MaxBackups: conf.MaxBackups, // 保留的旧日志文件最大数量
+		MaxAge:     conf.MaxAge,     // 保留的旧日志文件最大天数
+		Compress:   conf.Compress,   // 是否压缩
+		LocalTime:  true,            // 使用本地时间
 	})
 
 	// 创建一个软链接指向最新的日志文件
@@ -89,13 +90,64 @@ func Init() error {
 
 	// 创建核心
 	core := zapcore.NewTee(
-		zapcore.NewCore(consoleEncoder, consoleOutput, level),
-		zapcore.NewCore(fileEncoder, fileOutput, level),
+		zapcore.NewCore(consoleEncoder, consoleOutput, atomicLevel),
+		zapcore.NewCore(fileEncoder, fileOutput, atomicLevel),
+		&streamCore{LevelEnabler: atomicLevel, enc: zapcore.NewJSONEncoder(encoderConfig)},
 	)
 
 	// 创建日志记录器
 	logger = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
 
+	config.OnChange("log", onConfigChange)
+
+	return nil
+}
+
+// onConfigChange 配置文件中的日志级别变化时立即生效，日志文件相关的配置需要重启
+func onConfigChange(old, new *config.Config) {
+	if new.Log.Level != old.Log.Level {
+		if err := SetLevel(new.Log.Level); err != nil {
+			Warn("日志级别无效，保持当前级别", zap.Error(err))
+		} else {
+			Info("日志级别已修改", zap.String("level", Level()))
+		}
+	}
+
+	oldFile, newFile := old.Log, new.Log
+	oldFile.Level, newFile.Level = "", ""
+	oldFile.Access, newFile.Access = config.AccessLogConfig{}, config.AccessLogConfig{}
+	if !reflect.DeepEqual(oldFile, newFile) {
+		Warn("日志文件配置修改后需要重启才能生效")
+	}
+}
+
+// parseLevel 解析日志级别，只支持 debug、info、warn、error
+func parseLevel(s string) (zapcore.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	return zapcore.InfoLevel, fmt.Errorf("不支持的日志级别: %s", s)
+}
+
+// Level 返回当前的日志级别
+func Level() string {
+	return atomicLevel.Level().String()
+}
+
+// SetLevel 运行中修改日志级别，重启后恢复为配置文件中的级别
+func SetLevel(s string) error {
+	level, err := parseLevel(s)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(level)
 	return nil
 }
 
@@ -149,3 +201,37 @@ func Fatal(msg string, fields ...zap.Field) {
 func With(fields ...zap.Field) *zap.Logger {
 	return logger.With(fields...)
 }
+
+// requestIDKey 请求ID在上下文中的键
+type requestIDKey struct{}
+
+// WithRequestID 将请求ID写入上下文
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 从上下文中读取请求ID，不存在时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx 返回附加了上下文中请求ID（request_id）和链路信息（trace_id、span_id）的日志记录器
+func Ctx(ctx context.Context) *zap.Logger {
+	l := logger.WithOptions(zap.AddCallerSkip(-1))
+	if ctx == nil {
+		return l
+	}
+
+	var fields []zap.Field
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+	}
+	return l.With(fields...)
+}
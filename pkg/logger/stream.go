@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   = make(map[chan []byte]struct{})
+	subscriberNum atomic.Int32
+)
+
+// Subscribe 订阅新写入的日志，每条为与日志文件相同格式的JSON，处理不及时的日志会被丢弃
+// 返回的函数用于取消订阅
+func Subscribe(buffer int) (<-chan []byte, func()) {
+	ch := make(chan []byte, buffer)
+
+	subscribersMu.Lock()
+	subscribers[ch] = struct{}{}
+	subscriberNum.Add(1)
+	subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			subscribersMu.Lock()
+			delete(subscribers, ch)
+			subscriberNum.Add(-1)
+			subscribersMu.Unlock()
+		})
+	}
+}
+
+// streamCore 将日志转发给订阅者，没有订阅者时不编码
+type streamCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+}
+
+func (c *streamCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &streamCore{LevelEnabler: c.LevelEnabler, enc: enc}
+}
+
+func (c *streamCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if subscriberNum.Load() > 0 && c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *streamCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	line := append([]byte(nil), buf.Bytes()...)
+	buf.Free()
+
+	subscribersMu.RLock()
+	for ch := range subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+	subscribersMu.RUnlock()
+	return nil
+}
+
+func (c *streamCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cinexus/config"
+)
+
+// ErrNotConfigured 邮件服务未配置
+var ErrNotConfigured = errors.New("邮件服务未配置")
+
+// Send 发送纯文本邮件
+func Send(to []string, subject, body string) error {
+	conf := config.Get().SMTP
+	if conf.Host == "" || conf.From == "" {
+		return ErrNotConfigured
+	}
+
+	from, err := mail.ParseAddress(conf.From)
+	if err != nil {
+		return fmt.Errorf("发件人地址错误: %w", err)
+	}
+
+	addr := net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port))
+	msg := buildMessage(conf.From, to, subject, body)
+
+	var auth smtp.Auth
+	if conf.Username != "" {
+		auth = smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)
+	}
+
+	// 非隐式TLS直接交给标准库处理，服务器支持时会自动升级STARTTLS
+	if !conf.SSL {
+		return smtp.SendMail(addr, auth, from.Address, to, msg)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: conf.Host})
+	if err != nil {
+		return fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, conf.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("邮件服务器认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// buildMessage 构建邮件内容
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: " + from + "\r\n")
+	sb.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	sb.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
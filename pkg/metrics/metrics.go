@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "cinexus"
+
+// registry 独立的注册表，避免引入第三方库注册到默认注册表的指标
+var registry = prometheus.NewRegistry()
+
+var (
+	dbMu        sync.Mutex
+	dbCollector prometheus.Collector
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP请求数",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "正在处理的HTTP请求数",
+	})
+
+	cd2Calls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cd2_rpc_calls_total",
+		Help:      "CD2 gRPC调用数，method 为不含服务名的方法名，code 为gRPC状态码",
+	}, []string{"instance", "method", "code"})
+
+	cd2Duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cd2_rpc_duration_seconds",
+		Help:      "CD2 gRPC调用耗时",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"instance", "method"})
+
+	cd2Errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cd2_rpc_errors_total",
+		Help:      "CD2 gRPC调用失败数",
+	}, []string{"instance", "method"})
+
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "后台任务执行次数，status 为执行结果",
+	}, []string{"job", "status"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "后台任务耗时",
+		Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 4 * 3600, 12 * 3600},
+	}, []string{"job"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		cd2Calls, cd2Duration, cd2Errors,
+		jobRuns, jobDuration,
+	)
+}
+
+// Handler 返回输出所有指标的HTTP处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB 注册数据库连接池指标，重新连接数据库时替换之前的连接池
+func RegisterDB(db *sql.DB, name string) error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	if dbCollector != nil {
+		registry.Unregister(dbCollector)
+	}
+	dbCollector = collectors.NewDBStatsCollector(db, name)
+	return registry.Register(dbCollector)
+}
+
+// HTTPStart 记录开始处理HTTP请求，返回请求结束时调用的函数
+func HTTPStart() func(method, route string, code int) {
+	start := time.Now()
+	httpInFlight.Inc()
+	return func(method, route string, code int) {
+		httpInFlight.Dec()
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CD2UnaryInterceptor 创建记录CD2调用次数、耗时和错误的拦截器
+func CD2UnaryInterceptor(instance string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		observeCD2(instance, method, err, start)
+		return err
+	}
+}
+
+// CD2StreamInterceptor 创建记录CD2流式调用的拦截器，耗时计算到流结束为止
+func CD2StreamInterceptor(instance string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			observeCD2(instance, method, err, start)
+			return nil, err
+		}
+		s := &observedStream{ClientStream: stream, done: func(err error) {
+			observeCD2(instance, method, err, start)
+		}}
+		go s.watch(ctx)
+		return s, nil
+	}
+}
+
+// observedStream 在流结束、出错或调用方的上下文结束时记录一次调用
+type observedStream struct {
+	grpc.ClientStream
+	once     sync.Once
+	done     func(error)
+	received atomic.Bool // 是否成功接收过消息
+}
+
+// RecvMsg 接收消息，读到流末尾视为调用成功
+func (s *observedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		result := err
+		if errors.Is(err, io.EOF) {
+			result = nil
+		}
+		s.once.Do(func() { s.done(result) })
+		return err
+	}
+	s.received.Store(true)
+	return nil
+}
+
+// watch 等待流结束，调用方读到需要的消息后不再读取（如扫码登录收到 CLOSE）并取消上下文时，
+// RecvMsg 不会再返回错误，在这里记录：已收到过消息视为成功，否则按上下文的错误记录
+// 流正常结束或出错时上下文由 gRPC 取消，此时由 RecvMsg 记录
+func (s *observedStream) watch(ctx context.Context) {
+	<-s.Context().Done()
+	if ctx.Err() == nil {
+		return
+	}
+
+	var result error
+	if !s.received.Load() {
+		result = status.FromContextError(ctx.Err()).Err()
+	}
+	s.once.Do(func() { s.done(result) })
+}
+
+// observeCD2 记录单次CD2调用，method 只保留方法名
+func observeCD2(instance, method string, err error, start time.Time) {
+	method = path.Base(method)
+	cd2Calls.WithLabelValues(instance, method, status.Code(err).String()).Inc()
+	cd2Duration.WithLabelValues(instance, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		cd2Errors.WithLabelValues(instance, method).Inc()
+	}
+}
+
+// ObserveJob 记录后台任务的执行结果和耗时
+func ObserveJob(job, result string, start time.Time) {
+	jobRuns.WithLabelValues(job, result).Inc()
+	jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+}
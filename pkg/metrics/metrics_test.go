@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+// fakeStream 依次返回 msgs 条消息，eof 为真时随后返回 io.EOF 并结束流，否则阻塞到上下文结束
+type fakeStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	finish context.CancelFunc
+	msgs   int
+	eof    bool
+}
+
+func (f *fakeStream) Context() context.Context { return f.ctx }
+
+func (f *fakeStream) RecvMsg(any) error {
+	if f.msgs > 0 {
+		f.msgs--
+		return nil
+	}
+	if f.eof {
+		f.finish()
+		return io.EOF
+	}
+	<-f.ctx.Done()
+	return f.ctx.Err()
+}
+
+func TestCD2StreamInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		msgs   int
+		eof    bool
+		recv   int // 调用方读取的次数
+		code   string
+	}{
+		{name: "读到流末尾", method: "/pb.Srv/ReadToEOF", msgs: 2, eof: true, recv: 3, code: "OK"},
+		{name: "收到消息后取消", method: "/pb.Srv/StopAfterClose", msgs: 2, recv: 2, code: "OK"},
+		{name: "没有收到消息就取消", method: "/pb.Srv/CancelEarly", recv: 0, code: "Canceled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+				streamCtx, finish := context.WithCancel(ctx)
+				return &fakeStream{ctx: streamCtx, finish: finish, msgs: tt.msgs, eof: tt.eof}, nil
+			}
+
+			stream, err := CD2StreamInterceptor("test")(ctx, nil, nil, tt.method, streamer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.recv; i++ {
+				stream.RecvMsg(nil)
+			}
+			cancel()
+
+			method := path.Base(tt.method)
+			calls := cd2Calls.WithLabelValues("test", method, tt.code)
+			deadline := time.Now().Add(time.Second)
+			for testutil.ToFloat64(calls) < 1 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			// 等待可能的重复记录
+			time.Sleep(20 * time.Millisecond)
+
+			total := testutil.ToFloat64(cd2Calls.WithLabelValues("test", method, "OK")) +
+				testutil.ToFloat64(cd2Calls.WithLabelValues("test", method, "Canceled"))
+			if got := testutil.ToFloat64(calls); got != 1 || total != 1 {
+				t.Errorf("code=%s 的调用数 = %v，总调用数 = %v，应各为 1", tt.code, got, total)
+			}
+		})
+	}
+}
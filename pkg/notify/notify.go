@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+
+	"cinexus/config"
+	"cinexus/pkg/logger"
+	"cinexus/pkg/mail"
+)
+
+// 通知级别
+const (
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Message 通知内容
+type Message struct {
+	Level   string    `json:"level"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// Send 写入日志并异步发送到已配置的通知渠道
+func Send(level, title, content string) {
+	msg := Message{Level: level, Title: title, Content: content, Time: time.Now()}
+
+	fields := []zap.Field{zap.String("title", title), zap.String("content", content)}
+	switch level {
+	case LevelError:
+		logger.Error("通知", fields...)
+	case LevelWarn:
+		logger.Warn("通知", fields...)
+	default:
+		logger.Info("通知", fields...)
+	}
+
+	conf := config.Get().Notify
+	if conf.Webhook != "" {
+		go func() {
+			if err := sendWebhook(conf.Webhook, &msg); err != nil {
+				logger.Error("发送Webhook通知失败", zap.String("title", title), zap.Error(err))
+			}
+		}()
+	}
+	if len(conf.Emails) > 0 {
+		go func() {
+			if err := mail.Send(conf.Emails, "[Cinexus] "+title, content); err != nil {
+				logger.Error("发送邮件通知失败", zap.String("title", title), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// sendWebhook 以JSON POST通知内容
+func sendWebhook(url string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpClient.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo 从文件名中解析出的媒体信息，未解析到的数字字段为0
+type MediaInfo struct {
+	Title      string `json:"title"`
+	Year       int    `json:"year"`
+	Season     int    `json:"season"`
+	Episode    int    `json:"episode"`
+	Resolution string `json:"resolution"`
+	Group      string `json:"group"` // 字幕组或发布组
+	Ext        string `json:"ext"`   // 扩展名，不含点
+}
+
+// 常见的媒体扩展名，只有这些扩展名会从文件名中剥离
+var mediaExts = map[string]bool{
+	"mkv": true, "mp4": true, "avi": true, "ts": true, "m2ts": true, "rmvb": true, "wmv": true, "mov": true, "flv": true, "webm": true, "iso": true, "strm": true,
+	"ass": true, "ssa": true, "srt": true, "sup": true, "vtt": true, "idx": true, "sub": true,
+	"nfo": true, "jpg": true, "png": true,
+}
+
+var (
+	groupRe      = regexp.MustCompile(`^\s*[\[【]([^\]】]+)[\]】]`)
+	seasonEpRe   = regexp.MustCompile(`(?i)\bS(\d{1,2})[ ._-]?E(\d{1,4})\b`)
+	crossEpRe    = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	cnEpisodeRe  = regexp.MustCompile(`第\s*(\d{1,4})\s*[集话話]`)
+	cnSeasonRe   = regexp.MustCompile(`第\s*([\d一二三四五六七八九十]{1,3})\s*[季部]`)
+	seasonRe     = regexp.MustCompile(`(?i)\b(?:Season\s*(\d{1,2})|S(\d{1,2})|(\d)(?:st|nd|rd|th)\s+Season)\b`)
+	episodeRe    = regexp.MustCompile(`(?i)\b(?:EP?)(\d{1,4})(?:v\d)?\b`)
+	bracketEpRe  = regexp.MustCompile(`[\[【](\d{1,4})(?:v\d)?(?:END)?[\]】]`)
+	dashEpRe     = regexp.MustCompile(`\s-\s(\d{1,4})(?:v\d)?(?:\s|$|[\[(])`)
+	resolutionRe = regexp.MustCompile(`(?i)\b(\d{3,4}[pi]|4K|8K)\b`)
+	yearRe       = regexp.MustCompile(`(?:^|[\s.\[(（_-])((?:19|20)\d{2})\b`)
+	tagRe        = regexp.MustCompile(`[\[【(（][^\]】)）]*[\]】)）]`)
+	spaceRe      = regexp.MustCompile(`\s+`)
+)
+
+// Parse 解析文件名中的标题、季、集等信息
+func Parse(filename string) *MediaInfo {
+	info := &MediaInfo{}
+
+	name := path.Base(filename)
+	if ext := strings.TrimPrefix(path.Ext(name), "."); mediaExts[strings.ToLower(ext)] {
+		info.Ext = ext
+		name = strings.TrimSuffix(name, "."+ext)
+	}
+
+	// cut 记录标题结束的位置，季、集、年份等信息出现的最早位置之前视为标题
+	cut := len(name)
+	mark := func(loc []int) {
+		if loc != nil && loc[0] < cut {
+			cut = loc[0]
+		}
+	}
+
+	start := 0
+	if m := groupRe.FindStringSubmatchIndex(name); m != nil {
+		info.Group = strings.TrimSpace(name[m[2]:m[3]])
+		start = m[1]
+	}
+
+	body := name[start:]
+	offset := func(loc []int) []int {
+		if loc == nil {
+			return nil
+		}
+		shifted := make([]int, len(loc))
+		for i, v := range loc {
+			if v >= 0 {
+				v += start
+			}
+			shifted[i] = v
+		}
+		return shifted
+	}
+
+	switch {
+	case seasonEpRe.MatchString(body):
+		m := offset(seasonEpRe.FindStringSubmatchIndex(body))
+		info.Season = atoi(name[m[2]:m[3]])
+		info.Episode = atoi(name[m[4]:m[5]])
+		mark(m)
+	case crossEpRe.MatchString(body):
+		m := offset(crossEpRe.FindStringSubmatchIndex(body))
+		info.Season = atoi(name[m[2]:m[3]])
+		info.Episode = atoi(name[m[4]:m[5]])
+		mark(m)
+	default:
+		for _, re := range []*regexp.Regexp{cnEpisodeRe, episodeRe, bracketEpRe, dashEpRe} {
+			if m := offset(re.FindStringSubmatchIndex(body)); m != nil {
+				info.Episode = atoi(name[m[2]:m[3]])
+				mark(m)
+				break
+			}
+		}
+		if m := offset(cnSeasonRe.FindStringSubmatchIndex(body)); m != nil {
+			info.Season = cnAtoi(name[m[2]:m[3]])
+			mark(m)
+		} else if m := offset(seasonRe.FindStringSubmatchIndex(body)); m != nil {
+			for i := 2; i < len(m); i += 2 {
+				if m[i] >= 0 {
+					info.Season = atoi(name[m[i]:m[i+1]])
+					break
+				}
+			}
+			mark(m)
+		}
+	}
+
+	if m := offset(resolutionRe.FindStringSubmatchIndex(body)); m != nil {
+		info.Resolution = strings.ToLower(name[m[2]:m[3]])
+		mark(m)
+	}
+
+	// 年份不能出现在开头，否则像《2012》这样的标题会被误判
+	for _, m := range yearRe.FindAllStringSubmatchIndex(body, -1) {
+		if m = offset(m); m[2] > start {
+			info.Year = atoi(name[m[2]:m[3]])
+			mark([]int{m[2], m[3]})
+			break
+		}
+	}
+
+	if cut < start {
+		cut = start
+	}
+	info.Title = cleanTitle(name[start:cut])
+
+	// 标题整体被方括号包裹时（如 [字幕组][标题][01]），取第一个非数字的标签作为标题
+	if info.Title == "" {
+		for _, tag := range tagRe.FindAllString(name[start:cut], -1) {
+			if t := cleanTitle(strings.Trim(tag, "[]【】()（）")); t != "" && atoi(t) == 0 {
+				info.Title = t
+				break
+			}
+		}
+	}
+
+	return info
+}
+
+// cleanTitle 去除标题中的标签和分隔符
+func cleanTitle(s string) string {
+	s = tagRe.ReplaceAllString(s, " ")
+	s = strings.NewReplacer(".", " ", "_", " ").Replace(s)
+	s = spaceRe.ReplaceAllString(s, " ")
+	return strings.Trim(s, " -[]【】()（）")
+}
+
+// cnAtoi 转换阿拉伯数字或不超过99的中文数字
+func cnAtoi(s string) int {
+	if n := atoi(s); n > 0 {
+		return n
+	}
+
+	digits := map[rune]int{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	n, cur := 0, 0
+	for _, r := range s {
+		if r == '十' {
+			if cur == 0 {
+				cur = 1
+			}
+			n += cur * 10
+			cur = 0
+			continue
+		}
+		cur = digits[r]
+	}
+	return n + cur
+}
+
+// atoi 转换数字，失败时返回0
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,138 @@
+package parser
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want MediaInfo
+	}{
+		{
+			name: "SxxEyy",
+			file: "Breaking.Bad.S05E14.1080p.BluRay.x264.mkv",
+			want: MediaInfo{Title: "Breaking Bad", Season: 5, Episode: 14, Resolution: "1080p", Ext: "mkv"},
+		},
+		{
+			name: "SxxEyy 带年份",
+			file: "The.Office.2005.S02E01.720p.mp4",
+			want: MediaInfo{Title: "The Office", Year: 2005, Season: 2, Episode: 1, Resolution: "720p", Ext: "mp4"},
+		},
+		{
+			name: "季集之间有分隔符",
+			file: "Friends S01 E03.mkv",
+			want: MediaInfo{Title: "Friends", Season: 1, Episode: 3, Ext: "mkv"},
+		},
+		{
+			name: "1x02",
+			file: "Doctor Who 3x07.avi",
+			want: MediaInfo{Title: "Doctor Who", Season: 3, Episode: 7, Ext: "avi"},
+		},
+		{
+			name: "字幕组 - 集数",
+			file: "[Lilith-Raws] Sousou no Frieren - 12 [Baha][WEB-DL][1080p][AVC AAC][CHT][MP4].mp4",
+			want: MediaInfo{Title: "Sousou no Frieren", Episode: 12, Resolution: "1080p", Group: "Lilith-Raws", Ext: "mp4"},
+		},
+		{
+			name: "字幕组方括号集数",
+			file: "[Nekomoe kissaten][Kimetsu no Yaiba][05][1080p][CHS].mp4",
+			want: MediaInfo{Title: "Kimetsu no Yaiba", Episode: 5, Resolution: "1080p", Group: "Nekomoe kissaten", Ext: "mp4"},
+		},
+		{
+			name: "方括号集数带版本和完结",
+			file: "[VCB-Studio] Mushishi [26v2END][1080p].mkv",
+			want: MediaInfo{Title: "Mushishi", Episode: 26, Resolution: "1080p", Group: "VCB-Studio", Ext: "mkv"},
+		},
+		{
+			name: "全角括号",
+			file: "【喵萌奶茶屋】葬送的芙莉莲【03】【1080p】.mkv",
+			want: MediaInfo{Title: "葬送的芙莉莲", Episode: 3, Resolution: "1080p", Group: "喵萌奶茶屋", Ext: "mkv"},
+		},
+		{
+			name: "中文集数和季数",
+			file: "庆余年 第二季 第05集 4K.mp4",
+			want: MediaInfo{Title: "庆余年", Season: 2, Episode: 5, Resolution: "4k", Ext: "mp4"},
+		},
+		{
+			name: "中文十位季数",
+			file: "名侦探柯南 第十二季 第3话.mkv",
+			want: MediaInfo{Title: "名侦探柯南", Season: 12, Episode: 3, Ext: "mkv"},
+		},
+		{
+			name: "EP 集数",
+			file: "Attack on Titan EP25 720p.mkv",
+			want: MediaInfo{Title: "Attack on Titan", Episode: 25, Resolution: "720p", Ext: "mkv"},
+		},
+		{
+			name: "Season 与 E 集数",
+			file: "Mob Psycho 100 Season 2 E03.mkv",
+			want: MediaInfo{Title: "Mob Psycho 100", Season: 2, Episode: 3, Ext: "mkv"},
+		},
+		{
+			name: "序数季",
+			file: "[Group] Shingeki no Kyojin 3rd Season - 05 [1080p].mkv",
+			want: MediaInfo{Title: "Shingeki no Kyojin", Season: 3, Episode: 5, Resolution: "1080p", Group: "Group", Ext: "mkv"},
+		},
+		{
+			name: "电影",
+			file: "Inception.2010.2160p.UHD.BluRay.mkv",
+			want: MediaInfo{Title: "Inception", Year: 2010, Resolution: "2160p", Ext: "mkv"},
+		},
+		{
+			name: "括号中的年份",
+			file: "Spirited Away (2001) [1080p].mp4",
+			want: MediaInfo{Title: "Spirited Away", Year: 2001, Resolution: "1080p", Ext: "mp4"},
+		},
+		{
+			name: "标题为年份",
+			file: "2012.2009.1080p.mkv",
+			want: MediaInfo{Title: "2012", Year: 2009, Resolution: "1080p", Ext: "mkv"},
+		},
+		{
+			name: "字幕文件",
+			file: "Breaking.Bad.S01E01.chs.ass",
+			want: MediaInfo{Title: "Breaking Bad", Season: 1, Episode: 1, Ext: "ass"},
+		},
+		{
+			name: "带目录的路径",
+			file: "/115/动画/Frieren/[SubsPlease] Frieren - 01 (1080p).mkv",
+			want: MediaInfo{Title: "Frieren", Episode: 1, Resolution: "1080p", Group: "SubsPlease", Ext: "mkv"},
+		},
+		{
+			name: "非媒体扩展名保留在名称中",
+			file: "Some Show S01E02.part",
+			want: MediaInfo{Title: "Some Show", Season: 1, Episode: 2},
+		},
+		{
+			name: "没有可识别的信息",
+			file: "README.txt",
+			want: MediaInfo{Title: "README txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.file); *got != tt.want {
+				t.Errorf("Parse(%q)\n got  %+v\n want %+v", tt.file, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCnAtoi(t *testing.T) {
+	tests := map[string]int{
+		"3":   3,
+		"一":   1,
+		"九":   9,
+		"十":   10,
+		"十二":  12,
+		"二十":  20,
+		"二十三": 23,
+		"x":   0,
+	}
+	for s, want := range tests {
+		if got := cnAtoi(s); got != want {
+			t.Errorf("cnAtoi(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
@@ -80,6 +80,17 @@ func NotFound(c *gin.Context, msg string) {
 	})
 }
 
+// Conflict 返回409错误响应
+func Conflict(c *gin.Context, msg string) {
+	if msg == "" {
+		msg = "资源冲突"
+	}
+	c.JSON(http.StatusConflict, Response{
+		Code: 409,
+		Msg:  msg,
+	})
+}
+
 // ServerError 返回500错误响应
 func ServerError(c *gin.Context, msg string) {
 	if msg == "" {
@@ -90,3 +101,36 @@ func ServerError(c *gin.Context, msg string) {
 		Msg:  msg,
 	})
 }
+
+// BadGateway 返回502错误响应，用于上游服务（如CD2）调用失败
+func BadGateway(c *gin.Context, msg string) {
+	if msg == "" {
+		msg = "上游服务不可用"
+	}
+	c.JSON(http.StatusBadGateway, Response{
+		Code: 502,
+		Msg:  msg,
+	})
+}
+
+// PageData 分页数据
+type PageData struct {
+	List     any   `json:"list"`
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+}
+
+// SuccessWithPage 返回分页成功响应
+func SuccessWithPage(c *gin.Context, list any, total int64, page, pageSize int) {
+	c.JSON(http.StatusOK, Response{
+		Code: 200,
+		Msg:  "成功",
+		Data: PageData{
+			List:     list,
+			Total:    total,
+			Page:     page,
+			PageSize: pageSize,
+		},
+	})
+}